@@ -7,12 +7,15 @@ package fed
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/moov-io/base"
@@ -31,6 +34,10 @@ var (
 )
 
 // ACHDictionary of Participant records
+//
+// A ACHDictionary is populated by Read and is not modified by any of its search or filter methods, so it
+// can be searched from multiple goroutines once Read returns. Participants returned by searches are shared
+// and should not be modified.
 type ACHDictionary struct {
 	// Participants is a list of Participant structs
 	ACHParticipants []*ACHParticipant
@@ -38,10 +45,11 @@ type ACHDictionary struct {
 	IndexACHRoutingNumber map[string]*ACHParticipant
 	// IndexACHCustomerName creates an index of ACHParticipants keyed by ACHParticipant.CustomerName
 	IndexACHCustomerName map[string][]*ACHParticipant
-	// errors holds each error encountered when attempting to parse the file
-	errors base.ErrorList
 	// validator is composed for data validation
 	validator
+
+	// sourceChecksum is the SourceChecksum of the data read
+	sourceChecksum string
 }
 
 // NewACHDictionary creates a ACHDictionary
@@ -58,16 +66,18 @@ type ACHParticipant struct {
 	// RoutingNumber The institution's routing number
 	RoutingNumber string `json:"routingNumber"`
 	// OfficeCode Main/Head Office or Branch. O=main B=branch
-	OfficeCode string `json:"officeCode"`
+	OfficeCode OfficeCode `json:"officeCode"`
 	// ServicingFRBNumber Servicing Fed's main office routing number
 	ServicingFRBNumber string `json:"servicingFRBNumber"`
 	// RecordTypeCode The code indicating the ABA number to be used to route or send ACH items to the RDFI
 	// 0 = Institution is a Federal Reserve Bank
 	// 1 = Send items to customer routing number
 	// 2 = Send items to customer using new routing number field
-	RecordTypeCode string `json:"recordTypeCode"`
-	// Revised Date of last revision: YYYYMMDD, or blank
+	RecordTypeCode RecordTypeCode `json:"recordTypeCode"`
+	// Revised Date of last revision: MMDDYY, or blank
 	Revised string `json:"revised"`
+	// RevisedAt is the parsed value of Revised, or nil if Revised is blank or invalid
+	RevisedAt *time.Time `json:"revisedAt,omitempty"`
 	// NewRoutingNumber Institution's new routing number resulting from a merger or renumber
 	NewRoutingNumber string `json:"newRoutingNumber"`
 	// CustomerName (36): FEDERAL RESERVE BANK
@@ -78,21 +88,15 @@ type ACHParticipant struct {
 	PhoneNumber string `json:"phoneNumber"`
 	// StatusCode Code is based on the customers receiver code
 	// 1 = Receives Gov/Comm
-	StatusCode string `json:"statusCode"`
+	StatusCode StatusCode `json:"statusCode"`
 	// ViewCode is current view
 	// 1 = Current view
-	ViewCode string `json:"viewCode"`
+	ViewCode ViewCode `json:"viewCode"`
 
 	// CleanName is our cleaned up value of CustomerName
 	CleanName string `json:"cleanName"`
 }
 
-type achParticipantResult struct {
-	*ACHParticipant
-
-	highestMatch float64
-}
-
 // ACHLocation is the institution's delivery address
 type ACHLocation struct {
 	// Address
@@ -117,6 +121,7 @@ func (f *ACHDictionary) Read(r io.Reader) error {
 	if err != nil {
 		return err
 	}
+	f.sourceChecksum = SourceChecksum(bs)
 
 	// Try validating the file as JSON and if that fails read as plaintext
 	if json.Valid(bs) {
@@ -138,23 +143,23 @@ type jsonACHDictionary struct {
 			Code int `json:"code"`
 		} `json:"response"`
 		FedACHParticipants []struct {
-			RoutingNumber         string `json:"routingNumber"`
-			OfficeCode            string `json:"officeCode"`
-			ServicingFRBNumber    string `json:"servicingFRBNumber"`
-			RecordTypeCode        string `json:"recordTypeCode"`
-			ChangeDate            string `json:"changeDate"`
-			NewRoutingNumber      string `json:"newRoutingNumber"`
-			CustomerName          string `json:"customerName"`
-			CustomerAddress       string `json:"customerAddress"`
-			CustomerCity          string `json:"customerCity"`
-			CustomerState         string `json:"customerState"`
-			CustomerZip           string `json:"customerZip"`
-			CustomerZipExt        string `json:"customerZipExt"`
-			CustomerAreaCode      string `json:"customerAreaCode"`
-			CustomerPhonePrefix   string `json:"customerPhonePrefix"`
-			CustomerPhoneSuffix   string `json:"customerPhoneSuffix"`
-			InstitutionStatusCode string `json:"institutionStatusCode"`
-			DataViewCode          string `json:"dataViewCode"`
+			RoutingNumber         string         `json:"routingNumber"`
+			OfficeCode            OfficeCode     `json:"officeCode"`
+			ServicingFRBNumber    string         `json:"servicingFRBNumber"`
+			RecordTypeCode        RecordTypeCode `json:"recordTypeCode"`
+			ChangeDate            string         `json:"changeDate"`
+			NewRoutingNumber      string         `json:"newRoutingNumber"`
+			CustomerName          string         `json:"customerName"`
+			CustomerAddress       string         `json:"customerAddress"`
+			CustomerCity          string         `json:"customerCity"`
+			CustomerState         string         `json:"customerState"`
+			CustomerZip           string         `json:"customerZip"`
+			CustomerZipExt        string         `json:"customerZipExt"`
+			CustomerAreaCode      string         `json:"customerAreaCode"`
+			CustomerPhonePrefix   string         `json:"customerPhonePrefix"`
+			CustomerPhoneSuffix   string         `json:"customerPhoneSuffix"`
+			InstitutionStatusCode StatusCode     `json:"institutionStatusCode"`
+			DataViewCode          ViewCode       `json:"dataViewCode"`
 		} `json:"fedACHParticipants"`
 	} `json:"fedACHParticipants"`
 }
@@ -168,27 +173,30 @@ func (f *ACHDictionary) readJSON(r io.Reader) error {
 	for i := range ps {
 		p := &ACHParticipant{
 			RoutingNumber:      ps[i].RoutingNumber,
-			OfficeCode:         ps[i].OfficeCode,
-			ServicingFRBNumber: ps[i].ServicingFRBNumber,
-			RecordTypeCode:     ps[i].RecordTypeCode,
-			Revised:            ps[i].ChangeDate,
-			NewRoutingNumber:   ps[i].NewRoutingNumber,
+			OfficeCode:         intern(ps[i].OfficeCode),
+			ServicingFRBNumber: intern(ps[i].ServicingFRBNumber),
+			RecordTypeCode:     intern(ps[i].RecordTypeCode),
+			Revised:            intern(ps[i].ChangeDate),
+			RevisedAt:          revisedAt(ps[i].ChangeDate),
+			NewRoutingNumber:   intern(ps[i].NewRoutingNumber),
 			CustomerName:       ps[i].CustomerName,
 			ACHLocation: ACHLocation{
 				Address:             ps[i].CustomerAddress,
-				City:                ps[i].CustomerCity,
-				State:               ps[i].CustomerState,
-				PostalCode:          ps[i].CustomerZip,
-				PostalCodeExtension: ps[i].CustomerZipExt,
+				City:                intern(ps[i].CustomerCity),
+				State:               intern(ps[i].CustomerState),
+				PostalCode:          intern(ps[i].CustomerZip),
+				PostalCodeExtension: intern(ps[i].CustomerZipExt),
 			},
 			PhoneNumber: fmt.Sprintf("%s%s%s", ps[i].CustomerAreaCode, ps[i].CustomerPhonePrefix, ps[i].CustomerPhoneSuffix),
-			StatusCode:  ps[i].InstitutionStatusCode,
-			ViewCode:    ps[i].DataViewCode,
+			StatusCode:  intern(ps[i].InstitutionStatusCode),
+			ViewCode:    intern(ps[i].DataViewCode),
 
 			// Our Custom Fields
 			CleanName: Normalize(ps[i].CustomerName),
 		}
-		f.IndexACHRoutingNumber[ps[i].RoutingNumber] = p
+		internRecord(&p.RoutingNumber, &p.CustomerName, &p.Address, &p.PhoneNumber, &p.CleanName)
+
+		f.IndexACHRoutingNumber[p.RoutingNumber] = p
 		f.ACHParticipants = append(f.ACHParticipants, p)
 	}
 	f.createIndexACHCustomerName()
@@ -206,13 +214,11 @@ func (f *ACHDictionary) readPlaintext(r io.Reader) error {
 		line = s.Text()
 
 		if utf8.RuneCountInString(line) != ACHLineLength {
-			f.errors.Add(NewRecordWrongLengthErr(ACHLineLength, len(line)))
 			// Return with error if the record length is incorrect as this file is a FED file
-			return f.errors
+			return base.ErrorList{NewRecordWrongLengthErr(ACHLineLength, len(line))}
 		}
 		if err := f.parseACHParticipant(line); err != nil {
-			f.errors.Add(err)
-			return f.errors
+			return base.ErrorList{err}
 		}
 	}
 	f.createIndexACHCustomerName()
@@ -226,47 +232,111 @@ func (f *ACHDictionary) parseACHParticipant(line string) error {
 	//RoutingNumber (9): 011000015
 	p.RoutingNumber = line[:9]
 	// OfficeCode (1): O
-	p.OfficeCode = line[9:10]
+	p.OfficeCode = intern(OfficeCode(line[9:10]))
 	// ServicingFrbNumber (9): 011000015
-	p.ServicingFRBNumber = line[10:19]
+	p.ServicingFRBNumber = intern(line[10:19])
 	// RecordTypeCode (1): 0
-	p.RecordTypeCode = line[19:20]
+	p.RecordTypeCode = intern(RecordTypeCode(line[19:20]))
 	// ChangeDate (6): 122415
-	p.Revised = line[20:26]
+	p.Revised = intern(line[20:26])
+	p.RevisedAt = revisedAt(p.Revised)
 	// NewRoutingNumber (9): 000000000
-	p.NewRoutingNumber = line[26:35]
+	p.NewRoutingNumber = intern(line[26:35])
 	// CustomerName (36): FEDERAL RESERVE BANK
 	p.CustomerName = strings.Trim(line[35:71], " ")
 	// Address (36): 1000 PEACHTREE ST N.E.
 	p.Address = strings.Trim(line[71:107], " ")
 	// City (20): ATLANTA
-	p.City = strings.Trim(line[107:127], " ")
+	p.City = intern(strings.Trim(line[107:127], " "))
 	// State (2): GA
-	p.State = line[127:129]
+	p.State = intern(line[127:129])
 	// PostalCode (5): 30309
-	p.PostalCode = line[129:134]
+	p.PostalCode = intern(line[129:134])
 	// PostalCodeExtension (4): 4470
-	p.PostalCodeExtension = line[134:138]
+	p.PostalCodeExtension = intern(line[134:138])
 	// PhoneNumber(10): 8773722457
 	p.PhoneNumber = line[138:148]
 	// StatusCode (1): 1
-	p.StatusCode = line[148:149]
+	p.StatusCode = intern(StatusCode(line[148:149]))
 	// ViewCode (1): 1
-	p.ViewCode = line[149:150]
+	p.ViewCode = intern(ViewCode(line[149:150]))
 
 	// Our custom fields
 	p.CleanName = Normalize(p.CustomerName)
 
+	internRecord(&p.RoutingNumber, &p.CustomerName, &p.Address, &p.PhoneNumber, &p.CleanName)
+
 	f.ACHParticipants = append(f.ACHParticipants, p)
 	f.IndexACHRoutingNumber[p.RoutingNumber] = p
 	return nil
 }
 
+// internFields interns the values of a participant decoded from a snapshot, as parseACHParticipant does
+func (p *ACHParticipant) internFields() (routingNumber, customerName string) {
+	p.OfficeCode = intern(p.OfficeCode)
+	p.ServicingFRBNumber = intern(p.ServicingFRBNumber)
+	p.RecordTypeCode = intern(p.RecordTypeCode)
+	p.Revised = intern(p.Revised)
+	p.NewRoutingNumber = intern(p.NewRoutingNumber)
+	p.City = intern(p.City)
+	p.State = intern(p.State)
+	p.PostalCode = intern(p.PostalCode)
+	p.PostalCodeExtension = intern(p.PostalCodeExtension)
+	p.StatusCode = intern(p.StatusCode)
+	p.ViewCode = intern(p.ViewCode)
+
+	internRecord(&p.RoutingNumber, &p.CustomerName, &p.Address, &p.PhoneNumber, &p.CleanName)
+	return p.RoutingNumber, p.CustomerName
+}
+
 // createIndexACHCustomerName creates an index of Financial Institutions keyed by ACHParticipant.CustomerName
 func (f *ACHDictionary) createIndexACHCustomerName() {
+	// Count each name first so every entry in the index can share one backing array
+	counts := make(map[string]int)
 	for _, achP := range f.ACHParticipants {
-		f.IndexACHCustomerName[achP.CustomerName] = append(f.IndexACHCustomerName[achP.CustomerName], achP)
+		counts[achP.CustomerName]++
 	}
+	clear(f.IndexACHCustomerName)
+
+	backing, next := make([]*ACHParticipant, len(f.ACHParticipants)), 0
+	for _, achP := range f.ACHParticipants {
+		ps, ok := f.IndexACHCustomerName[achP.CustomerName]
+		if !ok {
+			n := counts[achP.CustomerName]
+			ps, next = backing[next:next:next+n], next+n
+		}
+		f.IndexACHCustomerName[achP.CustomerName] = append(ps, achP)
+	}
+}
+
+// Compact reduces the memory held by the dictionary. Participants are moved into a single allocation and
+// the values of each mostly unique field (routing numbers, names, addresses and phone numbers) are packed
+// into one buffer per field. Fields, indexes and search results are otherwise unchanged.
+//
+// Compact replaces every participant, so call it after Read and before searching or holding on to any.
+func (f *ACHDictionary) Compact() {
+	f.ACHParticipants = compactParticipants(f.ACHParticipants, f.IndexACHRoutingNumber, func(p *ACHParticipant) ([]*string, **time.Time) {
+		return []*string{&p.RoutingNumber, &p.CustomerName, &p.CleanName, &p.Address, &p.PhoneNumber}, &p.RevisedAt
+	})
+	f.createIndexACHCustomerName()
+}
+
+// SourceChecksum returns the SHA-256 of the data last passed to Read, or of the data a loaded snapshot was
+// created from. It's empty for a dictionary which hasn't been read.
+func (f *ACHDictionary) SourceChecksum() string {
+	return f.sourceChecksum
+}
+
+// WriteSnapshot writes the participants and indexes to w in a versioned binary format with a checksum.
+// Loading a snapshot with LoadSnapshot is much faster than parsing the directory with Read.
+func (f *ACHDictionary) WriteSnapshot(w io.Writer) error {
+	return saveSnapshot(w, "ach", f.sourceChecksum, f.ACHParticipants, f.IndexACHRoutingNumber, f.IndexACHCustomerName)
+}
+
+// LoadSnapshot replaces the participants and indexes with those from a snapshot written by WriteSnapshot.
+// The dictionary is unchanged if the snapshot is from another version, fails its checksum or is corrupt.
+func (f *ACHDictionary) LoadSnapshot(r io.Reader) error {
+	return loadSnapshot(r, "ach", (*ACHParticipant).internFields, &f.ACHParticipants, &f.IndexACHRoutingNumber, &f.IndexACHCustomerName, &f.sourceChecksum)
 }
 
 // CustomerNameLabel returns a formatted string Title for displaying ACHParticipant.CustomerName
@@ -275,6 +345,16 @@ func (p *ACHParticipant) CustomerNameLabel() string {
 	return s
 }
 
+// IsMainOffice returns true if the participant is a main (head) office rather than a branch
+func (p *ACHParticipant) IsMainOffice() bool {
+	return p.OfficeCode.IsMainOffice()
+}
+
+// IsRedirect returns true if ACH items for the participant are sent to its NewRoutingNumber
+func (p *ACHParticipant) IsRedirect() bool {
+	return p.RecordTypeCode.IsRedirect()
+}
+
 // RoutingNumberSearchSingle returns a FEDACH participant based on a ACHParticipant.RoutingNumber.  Routing Number
 // validation is only that it exists in IndexParticipant.  Expecting a valid 9 digit routing number.
 func (f *ACHDictionary) RoutingNumberSearchSingle(s string) *ACHParticipant {
@@ -286,8 +366,9 @@ func (f *ACHDictionary) RoutingNumberSearchSingle(s string) *ACHParticipant {
 
 // FinancialInstitutionSearchSingle returns FEDACH participants based on a ACHParticipant.CustomerName
 func (f *ACHDictionary) FinancialInstitutionSearchSingle(s string) []*ACHParticipant {
-	if _, ok := f.IndexACHCustomerName[s]; ok {
-		return f.IndexACHCustomerName[s]
+	if ps, ok := f.IndexACHCustomerName[s]; ok {
+		// Copy the index so callers can't modify it
+		return slices.Clone(ps)
 	}
 	return nil
 }
@@ -296,51 +377,46 @@ func (f *ACHDictionary) FinancialInstitutionSearchSingle(s string) []*ACHPartici
 // The first 2 digits of the routing number are required.
 // Based on https://www.frbservices.org/EPaymentsDirectory/search.html
 func (f *ACHDictionary) RoutingNumberSearch(s string, limit int) ([]*ACHParticipant, error) {
+	out, _, err := f.RoutingNumberSearchContext(context.Background(), s, limit)
+	return out, err
+}
+
+// RoutingNumberSearchContext is RoutingNumberSearch but also returns SearchStats, and stops scoring participants
+// once ctx is done. The best matches found so far are returned along with ctx.Err() in that case.
+func (f *ACHDictionary) RoutingNumberSearchContext(ctx context.Context, s string, limit int) ([]*ACHParticipant, SearchStats, error) {
 	s = strings.TrimSpace(s)
 
-	if utf8.RuneCountInString(s) < MinimumRoutingNumberDigits {
-		// The first 2 digits (characters) are required
-		f.errors.Add(NewRecordWrongLengthErr(2, len(s)))
-		return nil, f.errors
-	}
-	if utf8.RuneCountInString(s) > MaximumRoutingNumberDigits {
-		f.errors.Add(NewRecordWrongLengthErr(9, len(s)))
-		// Routing Number cannot be greater than 10 digits (characters)
-		return nil, f.errors
-	}
-	if err := f.isNumeric(s); err != nil {
-		// Routing Number is not numeric
-		f.errors.Add(ErrRoutingNumberNumeric)
-		return nil, f.errors
+	if err := f.validateRoutingNumberSearch(s); err != nil {
+		return nil, SearchStats{}, err
 	}
 	exactMatch := len(s) == 9
 
-	out := make([]*achParticipantResult, 0)
-	for _, achP := range f.ACHParticipants {
+	return scoreParticipants(ctx, f.ACHParticipants, limit, func(achP *ACHParticipant) (float64, bool) {
 		if exactMatch {
-			if achP.RoutingNumber == s {
-				out = append(out, &achParticipantResult{
-					ACHParticipant: achP,
-					highestMatch:   1.0,
-				})
-			}
-		} else {
-			out = append(out, &achParticipantResult{
-				ACHParticipant: achP,
-				highestMatch:   strcmp.JaroWinkler(achP.RoutingNumber, s),
-			})
+			return 1.0, achP.RoutingNumber == s
 		}
-	}
-	return reduceACHResults(out, limit), nil
+		return strcmp.JaroWinkler(achP.RoutingNumber, s), true
+	})
 }
 
 // FinancialInstitutionSearch returns a FEDACH participant based on a ACHParticipant.CustomerName
 func (f *ACHDictionary) FinancialInstitutionSearch(s string, limit int) []*ACHParticipant {
-	s = strings.ToLower(s)
+	out, _, _ := f.FinancialInstitutionSearchContext(context.Background(), s, limit)
+	return out
+}
 
-	out := make([]*achParticipantResult, 0)
+// FinancialInstitutionSearchContext is FinancialInstitutionSearch but also returns SearchStats, and stops scoring
+// participants once ctx is done. The best matches found so far are returned along with ctx.Err() in that case.
+func (f *ACHDictionary) FinancialInstitutionSearchContext(ctx context.Context, s string, limit int) ([]*ACHParticipant, SearchStats, error) {
+	return f.FinancialInstitutionSearchIn(ctx, f.ACHParticipants, s, limit)
+}
 
-	for _, achP := range f.ACHParticipants {
+// FinancialInstitutionSearchIn is FinancialInstitutionSearchContext over participants, such as those kept by the
+// ACHParticipant*Filter methods, rather than every participant in the dictionary.
+func (f *ACHDictionary) FinancialInstitutionSearchIn(ctx context.Context, participants []*ACHParticipant, s string, limit int) ([]*ACHParticipant, SearchStats, error) {
+	s = strings.ToLower(s)
+
+	return scoreParticipants(ctx, participants, limit, func(achP *ACHParticipant) (float64, bool) {
 		// JaroWinkler is a more accurate version of the Jaro algorithm. It works by boosting the
 		// score of exact matches at the beginning of the strings. By doing this, Winkler says that
 		// typos are less common to happen at the beginning.
@@ -351,14 +427,10 @@ func (f *ACHDictionary) FinancialInstitutionSearch(s string, limit int) []*ACHPa
 		levenScore := strcmp.Levenshtein(strings.ToLower(achP.CleanName), s)
 
 		if jaroScore > ACHJaroWinklerSimilarity || levenScore > ACHLevenshteinSimilarity {
-			out = append(out, &achParticipantResult{
-				ACHParticipant: achP,
-				highestMatch:   math.Max(jaroScore, levenScore),
-			})
+			return math.Max(jaroScore, levenScore), true
 		}
-	}
-
-	return reduceACHResults(out, limit)
+		return 0, false
+	})
 }
 
 // ACHParticipantStateFilter filters ACHParticipant by State.
@@ -400,8 +472,7 @@ func (f *ACHDictionary) ACHParticipantRoutingNumberFilter(achParticipants []*ACH
 
 	if len(s) < MinimumRoutingNumberDigits {
 		// The first 2 digits (characters) are required
-		f.errors.Add(NewRecordWrongLengthErr(2, len(s)))
-		return nil, f.errors
+		return nil, NewRecordWrongLengthErr(MinimumRoutingNumberDigits, len(s))
 	}
 	nsl := make([]*ACHParticipant, 0)
 	for _, achP := range achParticipants {
@@ -412,6 +483,32 @@ func (f *ACHDictionary) ACHParticipantRoutingNumberFilter(achParticipants []*ACH
 	return nsl, nil
 }
 
+// ACHParticipantRevisedFilter filters ACHParticipant by revision date. Participants revised on or after
+// after and before before are kept. A zero after or before is not checked, participants without a
+// revision date are removed.
+func (f *ACHDictionary) ACHParticipantRevisedFilter(achParticipants []*ACHParticipant, after, before time.Time) []*ACHParticipant {
+	nsl := make([]*ACHParticipant, 0)
+	for _, achP := range achParticipants {
+		if revisedBetween(achP.RevisedAt, after, before) {
+			nsl = append(nsl, achP)
+		}
+	}
+	return nsl
+}
+
+// RevisedSince returns ACHDictionary.ACHParticipant revised on or after since, sorted by revision date
+// and then routing number.
+func (f *ACHDictionary) RevisedSince(since time.Time) []*ACHParticipant {
+	nsl := f.ACHParticipantRevisedFilter(f.ACHParticipants, since, time.Time{})
+	sort.SliceStable(nsl, func(i, j int) bool {
+		if !nsl[i].RevisedAt.Equal(*nsl[j].RevisedAt) {
+			return nsl[i].RevisedAt.Before(*nsl[j].RevisedAt)
+		}
+		return nsl[i].RoutingNumber < nsl[j].RoutingNumber
+	})
+	return nsl
+}
+
 // StateFilter filters ACHDictionary.ACHParticipant by state
 func (f *ACHDictionary) StateFilter(s string) []*ACHParticipant {
 	nsl := make([]*ACHParticipant, 0)
@@ -445,13 +542,3 @@ func (f *ACHDictionary) PostalCodeFilter(s string) []*ACHParticipant {
 	}
 	return nsl
 }
-
-func reduceACHResults(in []*achParticipantResult, limit int) []*ACHParticipant {
-	sort.SliceStable(in, func(i, j int) bool { return in[i].highestMatch > in[j].highestMatch })
-
-	out := make([]*ACHParticipant, 0)
-	for i := 0; i < limit && i < len(in); i++ {
-		out = append(out, in[i].ACHParticipant)
-	}
-	return out
-}
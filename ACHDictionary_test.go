@@ -5,18 +5,22 @@
 package fed
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/moov-io/base"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func loadTestACHFiles(t *testing.T) (*ACHDictionary, *ACHDictionary) {
+func loadTestACHFiles(t testing.TB) (*ACHDictionary, *ACHDictionary) {
 	t.Helper()
 
 	open := func(path string) *ACHDictionary {
@@ -258,13 +262,13 @@ func TestACHRoutingNumberSearchMinimumLength(t *testing.T) {
 	jsonDict, plainDict := loadTestACHFiles(t)
 
 	if _, err := jsonDict.RoutingNumberSearch("0", 10); err != nil {
-		if !base.Has(err, NewRecordWrongLengthErr(2, 1)) {
+		if !errors.Is(err, NewRecordWrongLengthErr(2, 1)) {
 			t.Errorf("%T: %s", err, err)
 		}
 	}
 
 	if _, err := plainDict.RoutingNumberSearch("0", 10); err != nil {
-		if !base.Has(err, NewRecordWrongLengthErr(2, 1)) {
+		if !errors.Is(err, NewRecordWrongLengthErr(2, 1)) {
 			t.Errorf("%T: %s", err, err)
 		}
 	}
@@ -296,12 +300,12 @@ func TestACHRoutingNumberSearchMaximumLength(t *testing.T) {
 	jsonDict, plainDict := loadTestACHFiles(t)
 
 	if _, err := jsonDict.RoutingNumberSearch("1234567890", 10); err != nil {
-		if !base.Has(err, NewRecordWrongLengthErr(9, 10)) {
+		if !errors.Is(err, NewRecordWrongLengthErr(9, 10)) {
 			t.Errorf("%T: %s", err, err)
 		}
 	}
 	if _, err := plainDict.RoutingNumberSearch("1234567890", 10); err != nil {
-		if !base.Has(err, NewRecordWrongLengthErr(9, 10)) {
+		if !errors.Is(err, NewRecordWrongLengthErr(9, 10)) {
 			t.Errorf("%T: %s", err, err)
 		}
 	}
@@ -313,12 +317,12 @@ func TestACHRoutingNumberNumeric(t *testing.T) {
 	jsonDict, plainDict := loadTestACHFiles(t)
 
 	if _, err := jsonDict.RoutingNumberSearch("1  S5", 10); err != nil {
-		if !base.Has(err, ErrRoutingNumberNumeric) {
+		if !errors.Is(err, ErrRoutingNumberNumeric) {
 			t.Errorf("%T: %s", err, err)
 		}
 	}
 	if _, err := plainDict.RoutingNumberSearch("1  S5", 10); err != nil {
-		if !base.Has(err, ErrRoutingNumberNumeric) {
+		if !errors.Is(err, ErrRoutingNumberNumeric) {
 			t.Errorf("%T: %s", err, err)
 		}
 	}
@@ -552,3 +556,75 @@ func TestACHDictionary_EmptyRead(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, dict.ACHParticipants)
 }
+
+// TestACHDictionary_ConcurrentQueries verifies queries are side-effect free and return
+// their own error for each call, even when run from many goroutines.
+func TestACHDictionary_ConcurrentQueries(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			input := strings.Repeat("1", 10+i%5)
+			_, err := dict.RoutingNumberSearch(input, 10)
+			assert.ErrorIs(t, err, NewRecordWrongLengthErr(MaximumRoutingNumberDigits, len(input)))
+
+			_, err = dict.ACHParticipantRoutingNumberFilter(dict.ACHParticipants, "1")
+			assert.ErrorIs(t, err, NewRecordWrongLengthErr(MinimumRoutingNumberDigits, 1))
+
+			_, err = dict.RoutingNumberSearch("1  S5", 10)
+			assert.ErrorIs(t, err, ErrRoutingNumberNumeric)
+
+			found, err := dict.RoutingNumberSearch("044112187", 10)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, found)
+
+			assert.NotEmpty(t, dict.FinancialInstitutionSearch("FARMERS", 10))
+			assert.NotEmpty(t, dict.StateFilter("IA"))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestACHDictionary_SearchContext(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	found, _, err := dict.FinancialInstitutionSearchContext(context.Background(), "FARMERS", 10)
+	require.NoError(t, err)
+	require.Equal(t, dict.FinancialInstitutionSearch("FARMERS", 10), found)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	found, _, err = dict.FinancialInstitutionSearchContext(ctx, "FARMERS", 10)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Empty(t, found)
+
+	found, _, err = dict.RoutingNumberSearchContext(ctx, "0441", 10)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Empty(t, found)
+
+	// Invalid input is reported before checking ctx
+	_, _, err = dict.RoutingNumberSearchContext(ctx, "0", 10)
+	require.ErrorIs(t, err, NewRecordWrongLengthErr(MinimumRoutingNumberDigits, 1))
+}
+
+func TestACHDictionary_FinancialInstitutionSearchIn(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	// Only the given participants are scored
+	iowa := dict.StateFilter("IA")
+	found, _, err := dict.FinancialInstitutionSearchIn(context.Background(), iowa, "FARMERS", 10)
+	require.NoError(t, err)
+	require.Len(t, found, 10)
+	for _, p := range found {
+		require.Equal(t, "IA", p.State)
+	}
+
+	found, _, err = dict.FinancialInstitutionSearchIn(context.Background(), nil, "FARMERS", 10)
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
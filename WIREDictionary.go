@@ -7,11 +7,14 @@ package fed
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"math"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/moov-io/base"
@@ -28,6 +31,10 @@ var (
 )
 
 // WIREDictionary of Participant records
+//
+// A WIREDictionary is populated by Read and is not modified by any of its search or filter methods, so it
+// can be searched from multiple goroutines once Read returns. Participants returned by searches are shared
+// and should not be modified.
 type WIREDictionary struct {
 	// Participants is a list of Participant structs
 	WIREParticipants []*WIREParticipant
@@ -35,10 +42,11 @@ type WIREDictionary struct {
 	IndexWIRERoutingNumber map[string]*WIREParticipant
 	// IndexWIRECustomerName creates an index of WIREParticipants keyed by WIREParticipant.CustomerName
 	IndexWIRECustomerName map[string][]*WIREParticipant
-	// errors holds each error encountered when attempting to parse the file
-	errors base.ErrorList
 	// validator is composed for data validation
 	validator
+
+	// sourceChecksum is the SourceChecksum of the data read
+	sourceChecksum string
 }
 
 // NewWIREDictionary creates a WIREDictionary
@@ -63,14 +71,18 @@ type WIREParticipant struct {
 	// FundsTransferStatus designates funds transfer status
 	// Y - Eligible
 	// N - Ineligible
-	FundsTransferStatus string `json:"fundsTransferStatus"`
+	FundsTransferStatus FundsTransferStatus `json:"fundsTransferStatus"`
 	// FundsSettlementOnlyStatus designates funds settlement only status
 	// S - Settlement-Only
-	FundsSettlementOnlyStatus string `json:"fundsSettlementOnlyStatus"`
+	FundsSettlementOnlyStatus FundsSettlementOnlyStatus `json:"fundsSettlementOnlyStatus"`
 	// BookEntrySecuritiesTransferStatus designates book entry securities transfer status
-	BookEntrySecuritiesTransferStatus string `json:"bookEntrySecuritiesTransferStatus"`
+	// Y - Eligible
+	// N - Ineligible
+	BookEntrySecuritiesTransferStatus BookEntrySecuritiesTransferStatus `json:"bookEntrySecuritiesTransferStatus"`
 	// Date of last revision: YYYYMMDD, or blank
 	Date string `json:"date"`
+	// RevisedAt is the parsed value of Date, or nil if Date is blank or invalid
+	RevisedAt *time.Time `json:"revisedAt,omitempty"`
 
 	// CleanName is our cleaned up value of CustomerName
 	CleanName string `json:"cleanName"`
@@ -92,15 +104,15 @@ type jsonWIREDictionary struct {
 			Code int `json:"code"`
 		} `json:"response"`
 		FedwireParticipants []struct {
-			RoutingNumber             string `json:"routingNumber"`
-			TelegraphicName           string `json:"telegraphicName"`
-			CustomerName              string `json:"customerName"`
-			CustomerState             string `json:"customerState"`
-			CustomerCity              string `json:"customerCity"`
-			FundsEligibility          string `json:"fundsEligibility"`
-			FundsSettlementOnlyStatus string `json:"fundsSettlementOnlyStatus"`
-			SecuritiesEligibility     string `json:"securitiesEligibility"`
-			ChangeDate                string `json:"changeDate"`
+			RoutingNumber             string                            `json:"routingNumber"`
+			TelegraphicName           string                            `json:"telegraphicName"`
+			CustomerName              string                            `json:"customerName"`
+			CustomerState             string                            `json:"customerState"`
+			CustomerCity              string                            `json:"customerCity"`
+			FundsEligibility          FundsTransferStatus               `json:"fundsEligibility"`
+			FundsSettlementOnlyStatus FundsSettlementOnlyStatus         `json:"fundsSettlementOnlyStatus"`
+			SecuritiesEligibility     BookEntrySecuritiesTransferStatus `json:"securitiesEligibility"`
+			ChangeDate                string                            `json:"changeDate"`
 		} `json:"fedwireParticipants"`
 	} `json:"fedwireParticipants"`
 }
@@ -115,6 +127,7 @@ func (f *WIREDictionary) Read(r io.Reader) error {
 	if err != nil {
 		return err
 	}
+	f.sourceChecksum = SourceChecksum(bs)
 
 	// Try validating the file as JSON and if that fails read as plaintext
 	if json.Valid(bs) {
@@ -140,17 +153,20 @@ func (f *WIREDictionary) readJSON(r io.Reader) error {
 			TelegraphicName: ps[i].TelegraphicName,
 			CustomerName:    ps[i].CustomerName,
 			WIRELocation: WIRELocation{
-				City:  ps[i].CustomerCity,
-				State: ps[i].CustomerState,
+				City:  intern(ps[i].CustomerCity),
+				State: intern(ps[i].CustomerState),
 			},
-			FundsTransferStatus:               ps[i].FundsEligibility,
-			FundsSettlementOnlyStatus:         ps[i].FundsSettlementOnlyStatus,
-			BookEntrySecuritiesTransferStatus: ps[i].SecuritiesEligibility,
-			Date:                              ps[i].ChangeDate,
+			FundsTransferStatus:               intern(ps[i].FundsEligibility),
+			FundsSettlementOnlyStatus:         intern(ps[i].FundsSettlementOnlyStatus),
+			BookEntrySecuritiesTransferStatus: intern(ps[i].SecuritiesEligibility),
+			Date:                              intern(ps[i].ChangeDate),
+			RevisedAt:                         revisedAt(ps[i].ChangeDate),
 
 			// Our Custom Fields
 			CleanName: Normalize(ps[i].CustomerName),
 		}
+		internRecord(&p.RoutingNumber, &p.TelegraphicName, &p.CustomerName, &p.CleanName)
+
 		f.WIREParticipants = append(f.WIREParticipants, p)
 		f.IndexWIRERoutingNumber[p.RoutingNumber] = p
 	}
@@ -168,13 +184,11 @@ func (f *WIREDictionary) readPlaintext(r io.Reader) error {
 	for s.Scan() {
 		line = s.Text()
 		if utf8.RuneCountInString(line) != WIRELineLength {
-			f.errors.Add(NewRecordWrongLengthErr(WIRELineLength, len(line)))
 			// Return with error if the record length is incorrect as this file is a FED file
-			return f.errors
+			return base.ErrorList{NewRecordWrongLengthErr(WIRELineLength, len(line))}
 		}
 		if err := f.parseWIREParticipant(line); err != nil {
-			f.errors.Add(err)
-			return f.errors
+			return base.ErrorList{err}
 		}
 	}
 	f.createIndexWIRECustomerName()
@@ -193,32 +207,101 @@ func (f *WIREDictionary) parseWIREParticipant(line string) error {
 	p.CustomerName = strings.Trim(line[27:63], " ")
 	p.WIRELocation = WIRELocation{
 		// State (2): GA
-		State: line[63:65],
+		State: intern(line[63:65]),
 		// City (25): ATLANTA
-		City: strings.Trim(line[65:90], " "),
+		City: intern(strings.Trim(line[65:90], " ")),
 	}
 	// FundsTransferStatus (1): Y or N
-	p.FundsTransferStatus = line[90:91]
+	p.FundsTransferStatus = intern(FundsTransferStatus(line[90:91]))
 	// FundsSettlementOnlyStatus (1): " " or S - Settlement-Only
-	p.FundsSettlementOnlyStatus = line[91:92]
+	p.FundsSettlementOnlyStatus = intern(FundsSettlementOnlyStatus(line[91:92]))
 	// BookEntrySecuritiesTransferStatus (1): Y or N
-	p.BookEntrySecuritiesTransferStatus = line[92:93]
+	p.BookEntrySecuritiesTransferStatus = intern(BookEntrySecuritiesTransferStatus(line[92:93]))
 	// Date YYYYMMDD (8): 122415
-	p.Date = line[93:101]
+	p.Date = intern(line[93:101])
+	p.RevisedAt = revisedAt(p.Date)
 
 	// Our custom fields
 	p.CleanName = Normalize(p.CustomerName)
 
+	internRecord(&p.RoutingNumber, &p.TelegraphicName, &p.CustomerName, &p.CleanName)
+
 	f.WIREParticipants = append(f.WIREParticipants, p)
 	f.IndexWIRERoutingNumber[p.RoutingNumber] = p
 	return nil
 }
 
+// internFields interns the values of a participant decoded from a snapshot, as parseWIREParticipant does
+func (p *WIREParticipant) internFields() (routingNumber, customerName string) {
+	p.State = intern(p.State)
+	p.City = intern(p.City)
+	p.FundsTransferStatus = intern(p.FundsTransferStatus)
+	p.FundsSettlementOnlyStatus = intern(p.FundsSettlementOnlyStatus)
+	p.BookEntrySecuritiesTransferStatus = intern(p.BookEntrySecuritiesTransferStatus)
+	p.Date = intern(p.Date)
+
+	internRecord(&p.RoutingNumber, &p.TelegraphicName, &p.CustomerName, &p.CleanName)
+	return p.RoutingNumber, p.CustomerName
+}
+
 // createIndexWIRECustomerName creates an index of Financial Institutions keyed by WIREParticipant.CustomerName
 func (f *WIREDictionary) createIndexWIRECustomerName() {
+	// Count each name first so every entry in the index can share one backing array
+	counts := make(map[string]int)
 	for _, wireP := range f.WIREParticipants {
-		f.IndexWIRECustomerName[wireP.CustomerName] = append(f.IndexWIRECustomerName[wireP.CustomerName], wireP)
+		counts[wireP.CustomerName]++
 	}
+	clear(f.IndexWIRECustomerName)
+
+	backing, next := make([]*WIREParticipant, len(f.WIREParticipants)), 0
+	for _, wireP := range f.WIREParticipants {
+		ps, ok := f.IndexWIRECustomerName[wireP.CustomerName]
+		if !ok {
+			n := counts[wireP.CustomerName]
+			ps, next = backing[next:next:next+n], next+n
+		}
+		f.IndexWIRECustomerName[wireP.CustomerName] = append(ps, wireP)
+	}
+}
+
+// Compact reduces the memory held by the dictionary. Participants are moved into a single allocation and
+// the values of each mostly unique field (routing numbers and names) are packed into one buffer per field.
+// Fields, indexes and search results are otherwise unchanged.
+//
+// Compact replaces every participant, so call it after Read and before searching or holding on to any.
+func (f *WIREDictionary) Compact() {
+	f.WIREParticipants = compactParticipants(f.WIREParticipants, f.IndexWIRERoutingNumber, func(p *WIREParticipant) ([]*string, **time.Time) {
+		return []*string{&p.RoutingNumber, &p.TelegraphicName, &p.CustomerName, &p.CleanName}, &p.RevisedAt
+	})
+	f.createIndexWIRECustomerName()
+}
+
+// SourceChecksum returns the SHA-256 of the data last passed to Read, or of the data a loaded snapshot was
+// created from. It's empty for a dictionary which hasn't been read.
+func (f *WIREDictionary) SourceChecksum() string {
+	return f.sourceChecksum
+}
+
+// WriteSnapshot writes the participants and indexes to w in a versioned binary format with a checksum.
+// Loading a snapshot with LoadSnapshot is much faster than parsing the directory with Read.
+func (f *WIREDictionary) WriteSnapshot(w io.Writer) error {
+	return saveSnapshot(w, "wire", f.sourceChecksum, f.WIREParticipants, f.IndexWIRERoutingNumber, f.IndexWIRECustomerName)
+}
+
+// LoadSnapshot replaces the participants and indexes with those from a snapshot written by WriteSnapshot.
+// The dictionary is unchanged if the snapshot is from another version, fails its checksum or is corrupt.
+func (f *WIREDictionary) LoadSnapshot(r io.Reader) error {
+	return loadSnapshot(r, "wire", (*WIREParticipant).internFields, &f.WIREParticipants, &f.IndexWIRERoutingNumber, &f.IndexWIRECustomerName, &f.sourceChecksum)
+}
+
+// IsWireEligible returns true if the participant is eligible for Fedwire funds transfers
+func (p *WIREParticipant) IsWireEligible() bool {
+	return p.FundsTransferStatus.IsWireEligible()
+}
+
+// IsSettlementOnly returns true if the participant is settlement-only
+func (p *WIREParticipant) IsSettlementOnly() bool {
+	return p.FundsSettlementOnlyStatus.IsSettlementOnly()
 }
 
 // RoutingNumberSearchSingle returns a FEDWIRE participant based on a WIREParticipant.RoutingNumber.  Routing Number
@@ -232,8 +315,9 @@ func (f *WIREDictionary) RoutingNumberSearchSingle(s string) *WIREParticipant {
 
 // FinancialInstitutionSearchSingle returns a FEDWIRE participant based on a WIREParticipant.CustomerName
 func (f *WIREDictionary) FinancialInstitutionSearchSingle(s string) []*WIREParticipant {
-	if _, ok := f.IndexWIRECustomerName[s]; ok {
-		return f.IndexWIRECustomerName[s]
+	if ps, ok := f.IndexWIRECustomerName[s]; ok {
+		// Copy the index so callers can't modify it
+		return slices.Clone(ps)
 	}
 	return nil
 }
@@ -242,51 +326,46 @@ func (f *WIREDictionary) FinancialInstitutionSearchSingle(s string) []*WIREParti
 // The first 2 digits of the routing number are required.
 // Based on https://www.frbservices.org/EPaymentsDirectory/search.html
 func (f *WIREDictionary) RoutingNumberSearch(s string, limit int) ([]*WIREParticipant, error) {
+	out, _, err := f.RoutingNumberSearchContext(context.Background(), s, limit)
+	return out, err
+}
+
+// RoutingNumberSearchContext is RoutingNumberSearch but also returns SearchStats, and stops scoring participants
+// once ctx is done. The best matches found so far are returned along with ctx.Err() in that case.
+func (f *WIREDictionary) RoutingNumberSearchContext(ctx context.Context, s string, limit int) ([]*WIREParticipant, SearchStats, error) {
 	s = strings.TrimSpace(s)
 
-	if utf8.RuneCountInString(s) < MinimumRoutingNumberDigits {
-		// The first 2 digits (characters) are required
-		f.errors.Add(NewRecordWrongLengthErr(2, len(s)))
-		return nil, f.errors
-	}
-	if utf8.RuneCountInString(s) > MaximumRoutingNumberDigits {
-		f.errors.Add(NewRecordWrongLengthErr(9, len(s)))
-		// Routing Number cannot be greater than 10 digits (characters)
-		return nil, f.errors
-	}
-	if err := f.isNumeric(s); err != nil {
-		// Routing Number is not numeric
-		f.errors.Add(ErrRoutingNumberNumeric)
-		return nil, f.errors
+	if err := f.validateRoutingNumberSearch(s); err != nil {
+		return nil, SearchStats{}, err
 	}
 	exactMatch := len(s) == 9
 
-	out := make([]*wireParticipantResult, 0)
-	for _, wireP := range f.WIREParticipants {
+	return scoreParticipants(ctx, f.WIREParticipants, limit, func(wireP *WIREParticipant) (float64, bool) {
 		if exactMatch {
-			if wireP.RoutingNumber == s {
-				out = append(out, &wireParticipantResult{
-					WIREParticipant: wireP,
-					highestMatch:    1.0,
-				})
-			}
-		} else {
-			out = append(out, &wireParticipantResult{
-				WIREParticipant: wireP,
-				highestMatch:    strcmp.JaroWinkler(wireP.RoutingNumber, s),
-			})
+			return 1.0, wireP.RoutingNumber == s
 		}
-	}
-	return reduceWIREResults(out, limit), nil
+		return strcmp.JaroWinkler(wireP.RoutingNumber, s), true
+	})
 }
 
 // FinancialInstitutionSearch returns a FEDWIRE participant based on a WIREParticipant.CustomerName
 func (f *WIREDictionary) FinancialInstitutionSearch(s string, limit int) []*WIREParticipant {
-	s = strings.ToLower(s)
+	out, _, _ := f.FinancialInstitutionSearchContext(context.Background(), s, limit)
+	return out
+}
 
-	out := make([]*wireParticipantResult, 0)
+// FinancialInstitutionSearchContext is FinancialInstitutionSearch but also returns SearchStats, and stops scoring
+// participants once ctx is done. The best matches found so far are returned along with ctx.Err() in that case.
+func (f *WIREDictionary) FinancialInstitutionSearchContext(ctx context.Context, s string, limit int) ([]*WIREParticipant, SearchStats, error) {
+	return f.FinancialInstitutionSearchIn(ctx, f.WIREParticipants, s, limit)
+}
 
-	for _, wireP := range f.WIREParticipants {
+// FinancialInstitutionSearchIn is FinancialInstitutionSearchContext over participants, such as those kept by the
+// WIREParticipant*Filter methods, rather than every participant in the dictionary.
+func (f *WIREDictionary) FinancialInstitutionSearchIn(ctx context.Context, participants []*WIREParticipant, s string, limit int) ([]*WIREParticipant, SearchStats, error) {
+	s = strings.ToLower(s)
+
+	return scoreParticipants(ctx, participants, limit, func(wireP *WIREParticipant) (float64, bool) {
 		// JaroWinkler is a more accurate version of the Jaro algorithm. It works by boosting the
 		// score of exact matches at the beginning of the strings. By doing this, Winkler says that
 		// typos are less common to happen at the beginning.
@@ -297,14 +376,10 @@ func (f *WIREDictionary) FinancialInstitutionSearch(s string, limit int) []*WIRE
 		levenScore := strcmp.Levenshtein(strings.ToLower(wireP.CleanName), s)
 
 		if jaroScore > ACHJaroWinklerSimilarity || levenScore > ACHLevenshteinSimilarity {
-			out = append(out, &wireParticipantResult{
-				WIREParticipant: wireP,
-				highestMatch:    math.Max(jaroScore, levenScore),
-			})
+			return math.Max(jaroScore, levenScore), true
 		}
-	}
-
-	return reduceWIREResults(out, limit)
+		return 0, false
+	})
 }
 
 // WIREParticipantRoutingNumberFilter filters WIREParticipant by Routing Number
@@ -313,8 +388,7 @@ func (f *WIREDictionary) WIREParticipantRoutingNumberFilter(wireParticipants []*
 
 	if len(s) < MinimumRoutingNumberDigits {
 		// The first 2 digits (characters) are required
-		f.errors.Add(NewRecordWrongLengthErr(2, len(s)))
-		return nil, f.errors
+		return nil, NewRecordWrongLengthErr(MinimumRoutingNumberDigits, len(s))
 	}
 	nsl := make([]*WIREParticipant, 0)
 	for _, wireP := range wireParticipants {
@@ -347,6 +421,32 @@ func (f *WIREDictionary) WIREParticipantCityFilter(wireParticipants []*WIREParti
 	return nsl
 }
 
+// WIREParticipantRevisedFilter filters WIREParticipant by revision date. Participants revised on or after
+// after and before before are kept. A zero after or before is not checked, participants without a
+// revision date are removed.
+func (f *WIREDictionary) WIREParticipantRevisedFilter(wireParticipants []*WIREParticipant, after, before time.Time) []*WIREParticipant {
+	nsl := make([]*WIREParticipant, 0)
+	for _, wireP := range wireParticipants {
+		if revisedBetween(wireP.RevisedAt, after, before) {
+			nsl = append(nsl, wireP)
+		}
+	}
+	return nsl
+}
+
+// RevisedSince returns WIREDictionary.WIREParticipant revised on or after since, sorted by revision date
+// and then routing number.
+func (f *WIREDictionary) RevisedSince(since time.Time) []*WIREParticipant {
+	nsl := f.WIREParticipantRevisedFilter(f.WIREParticipants, since, time.Time{})
+	sort.SliceStable(nsl, func(i, j int) bool {
+		if !nsl[i].RevisedAt.Equal(*nsl[j].RevisedAt) {
+			return nsl[i].RevisedAt.Before(*nsl[j].RevisedAt)
+		}
+		return nsl[i].RoutingNumber < nsl[j].RoutingNumber
+	})
+	return nsl
+}
+
 // StateFilter filters WIREDictionary.WIREParticipant by state
 func (f *WIREDictionary) StateFilter(s string) []*WIREParticipant {
 	nsl := make([]*WIREParticipant, 0)
@@ -368,19 +468,3 @@ func (f *WIREDictionary) CityFilter(s string) []*WIREParticipant {
 	}
 	return nsl
 }
-
-type wireParticipantResult struct {
-	*WIREParticipant
-
-	highestMatch float64
-}
-
-func reduceWIREResults(in []*wireParticipantResult, limit int) []*WIREParticipant {
-	sort.SliceStable(in, func(i, j int) bool { return in[i].highestMatch > in[j].highestMatch })
-
-	out := make([]*WIREParticipant, 0)
-	for i := 0; i < limit && i < len(in); i++ {
-		out = append(out, in[i].WIREParticipant)
-	}
-	return out
-}
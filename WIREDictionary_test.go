@@ -5,19 +5,24 @@
 package fed
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/moov-io/base"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 // loadTestWireFiles returns two WIREDictionary, one from the JSON source file
 // and other from the plaintext source file.
-func loadTestWireFiles(t *testing.T) (*WIREDictionary, *WIREDictionary) {
+func loadTestWireFiles(t testing.TB) (*WIREDictionary, *WIREDictionary) {
 	t.Helper()
 
 	open := func(path string) *WIREDictionary {
@@ -227,7 +232,7 @@ func TestWIRERoutingNumberSearchMinimumLength(t *testing.T) {
 
 	check := func(t *testing.T, kind string, dict *WIREDictionary) {
 		if _, err := dict.RoutingNumberSearch("0", 1); err != nil {
-			if !base.Has(err, NewRecordWrongLengthErr(2, 1)) {
+			if !errors.Is(err, NewRecordWrongLengthErr(2, 1)) {
 				t.Errorf("%T: %s", err, err)
 			}
 		}
@@ -262,7 +267,7 @@ func TestWIRERoutingNumberSearchMaximumLength(t *testing.T) {
 
 	check := func(t *testing.T, kind string, dict *WIREDictionary) {
 		if _, err := dict.RoutingNumberSearch("1234567890", 1); err != nil {
-			if !base.Has(err, NewRecordWrongLengthErr(9, 10)) {
+			if !errors.Is(err, NewRecordWrongLengthErr(9, 10)) {
 				t.Errorf("%T: %s", err, err)
 			}
 		}
@@ -280,7 +285,7 @@ func TestWIRERoutingNumberNumeric(t *testing.T) {
 
 	check := func(t *testing.T, kind string, dict *WIREDictionary) {
 		if _, err := dict.RoutingNumberSearch("1  S5", 1); err != nil {
-			if !base.Has(err, ErrRoutingNumberNumeric) {
+			if !errors.Is(err, ErrRoutingNumberNumeric) {
 				t.Errorf("%T: %s", err, err)
 			}
 		}
@@ -483,3 +488,67 @@ func TestWireDictionary_EmptyRead(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, dict.WIREParticipants)
 }
+
+// TestWIREDictionary_ConcurrentQueries verifies queries are side-effect free and return
+// their own error for each call, even when run from many goroutines.
+func TestWIREDictionary_ConcurrentQueries(t *testing.T) {
+	_, dict := loadTestWireFiles(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			input := strings.Repeat("1", 10+i%5)
+			_, err := dict.RoutingNumberSearch(input, 10)
+			assert.ErrorIs(t, err, NewRecordWrongLengthErr(MaximumRoutingNumberDigits, len(input)))
+
+			_, err = dict.WIREParticipantRoutingNumberFilter(dict.WIREParticipants, "1")
+			assert.ErrorIs(t, err, NewRecordWrongLengthErr(MinimumRoutingNumberDigits, 1))
+
+			_, err = dict.RoutingNumberSearch("1  S5", 10)
+			assert.ErrorIs(t, err, ErrRoutingNumberNumeric)
+
+			found, err := dict.RoutingNumberSearch("325", 10)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, found)
+
+			assert.NotEmpty(t, dict.FinancialInstitutionSearch("FARMERS", 10))
+			assert.NotEmpty(t, dict.StateFilter("IA"))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestWIREDictionary_SearchContext(t *testing.T) {
+	_, dict := loadTestWireFiles(t)
+
+	found, _, err := dict.FinancialInstitutionSearchContext(context.Background(), "MIDWEST", 10)
+	require.NoError(t, err)
+	require.Equal(t, dict.FinancialInstitutionSearch("MIDWEST", 10), found)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	found, _, err = dict.FinancialInstitutionSearchContext(ctx, "MIDWEST", 10)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Empty(t, found)
+
+	found, _, err = dict.RoutingNumberSearchContext(ctx, "325", 10)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Empty(t, found)
+}
+
+func TestWIREDictionary_FinancialInstitutionSearchIn(t *testing.T) {
+	_, dict := loadTestWireFiles(t)
+
+	// Only the given participants are scored
+	iowa := dict.StateFilter("IA")
+	found, _, err := dict.FinancialInstitutionSearchIn(context.Background(), iowa, "MIDWEST", 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, found)
+	for _, p := range found {
+		require.Equal(t, "IA", p.State)
+	}
+}
@@ -25,6 +25,114 @@ var (
 // FEDApiService FEDApi service
 type FEDApiService service
 
+// AchChangesOpts Optional parameters for the method 'AchChanges'
+type AchChangesOpts struct {
+	XRequestID optional.String
+	PageToken  optional.String
+	Limit      optional.Int32
+}
+
+/*
+AchChanges List FEDACH participants revised since a date
+Participants are sorted by revision date and then routing number. Follow nextPageToken to read every page.
+  - @param ctx _context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
+  - @param since Return participants revised on or after this date (YYYY-MM-DD or RFC 3339)
+  - @param optional nil or *AchChangesOpts - Optional Parameters:
+  - @param "XRequestID" (optional.String) -  Optional Request ID allows application developer to trace requests through the systems logs
+  - @param "PageToken" (optional.String) -  nextPageToken from the previous page
+  - @param "Limit" (optional.Int32) -  Maximum results returned per page
+
+@return AchChanges
+*/
+func (a *FEDApiService) AchChanges(ctx _context.Context, since string, localVarOptionals *AchChangesOpts) (AchChanges, *_nethttp.Response, error) {
+	var (
+		localVarHTTPMethod   = _nethttp.MethodGet
+		localVarPostBody     interface{}
+		localVarFormFileName string
+		localVarFileName     string
+		localVarFileBytes    []byte
+		localVarReturnValue  AchChanges
+	)
+
+	// create path and map variables
+	localVarPath := a.client.cfg.BasePath + "/fed/ach/changes"
+	localVarHeaderParams := make(map[string]string)
+	localVarQueryParams := _neturl.Values{}
+	localVarFormParams := _neturl.Values{}
+
+	localVarQueryParams.Add("since", parameterToString(since, ""))
+	if localVarOptionals != nil && localVarOptionals.PageToken.IsSet() {
+		localVarQueryParams.Add("pageToken", parameterToString(localVarOptionals.PageToken.Value(), ""))
+	}
+	if localVarOptionals != nil && localVarOptionals.Limit.IsSet() {
+		localVarQueryParams.Add("limit", parameterToString(localVarOptionals.Limit.Value(), ""))
+	}
+	// to determine the Content-Type header
+	localVarHTTPContentTypes := []string{}
+
+	// set Content-Type header
+	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
+	if localVarHTTPContentType != "" {
+		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
+	}
+
+	// to determine the Accept header
+	localVarHTTPHeaderAccepts := []string{"application/json"}
+
+	// set Accept header
+	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
+	if localVarHTTPHeaderAccept != "" {
+		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
+	}
+	if localVarOptionals != nil && localVarOptionals.XRequestID.IsSet() {
+		localVarHeaderParams["X-Request-ID"] = parameterToString(localVarOptionals.XRequestID.Value(), "")
+	}
+	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFormFileName, localVarFileName, localVarFileBytes)
+	if err != nil {
+		return localVarReturnValue, nil, err
+	}
+
+	localVarHTTPResponse, err := a.client.callAPI(r)
+	if err != nil || localVarHTTPResponse == nil {
+		return localVarReturnValue, localVarHTTPResponse, err
+	}
+
+	localVarBody, err := _ioutil.ReadAll(localVarHTTPResponse.Body)
+	localVarHTTPResponse.Body.Close()
+	if err != nil {
+		return localVarReturnValue, localVarHTTPResponse, err
+	}
+
+	if localVarHTTPResponse.StatusCode >= 300 {
+		newErr := GenericOpenAPIError{
+			body:  localVarBody,
+			error: localVarHTTPResponse.Status,
+		}
+		if localVarHTTPResponse.StatusCode == 400 {
+			var v Error
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
+		return localVarReturnValue, localVarHTTPResponse, newErr
+	}
+
+	err = a.client.decode(&localVarReturnValue, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+	if err != nil {
+		newErr := GenericOpenAPIError{
+			body:  localVarBody,
+			error: err.Error(),
+		}
+		return localVarReturnValue, localVarHTTPResponse, newErr
+	}
+
+	return localVarReturnValue, localVarHTTPResponse, nil
+}
+
 /*
 Ping Ping the FED service to check if running
   - @param ctx _context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
@@ -97,6 +205,8 @@ type SearchFEDACHOpts struct {
 	State         optional.String
 	City          optional.String
 	PostalCode    optional.String
+	RevisedAfter  optional.String
+	RevisedBefore optional.String
 	Limit         optional.Int32
 }
 
@@ -111,6 +221,8 @@ SearchFEDACH Search FEDACH names and metadata
   - @param "State" (optional.String) -  FEDACH Financial Institution State
   - @param "City" (optional.String) -  FEDACH Financial Institution City
   - @param "PostalCode" (optional.String) -  FEDACH Financial Institution Postal Code
+  - @param "RevisedAfter" (optional.String) -  Only return participants revised on or after this date (YYYY-MM-DD or RFC 3339)
+  - @param "RevisedBefore" (optional.String) -  Only return participants revised before this date (YYYY-MM-DD or RFC 3339)
   - @param "Limit" (optional.Int32) -  Maximum results returned by a search
 
 @return AchDictionary
@@ -146,6 +258,12 @@ func (a *FEDApiService) SearchFEDACH(ctx _context.Context, localVarOptionals *Se
 	if localVarOptionals != nil && localVarOptionals.PostalCode.IsSet() {
 		localVarQueryParams.Add("postalCode", parameterToString(localVarOptionals.PostalCode.Value(), ""))
 	}
+	if localVarOptionals != nil && localVarOptionals.RevisedAfter.IsSet() {
+		localVarQueryParams.Add("revisedAfter", parameterToString(localVarOptionals.RevisedAfter.Value(), ""))
+	}
+	if localVarOptionals != nil && localVarOptionals.RevisedBefore.IsSet() {
+		localVarQueryParams.Add("revisedBefore", parameterToString(localVarOptionals.RevisedBefore.Value(), ""))
+	}
 	if localVarOptionals != nil && localVarOptionals.Limit.IsSet() {
 		localVarQueryParams.Add("limit", parameterToString(localVarOptionals.Limit.Value(), ""))
 	}
@@ -203,6 +321,16 @@ func (a *FEDApiService) SearchFEDACH(ctx _context.Context, localVarOptionals *Se
 			newErr.model = v
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
+		if localVarHTTPResponse.StatusCode == 503 {
+			var v Error
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		return localVarReturnValue, localVarHTTPResponse, newErr
 	}
 
@@ -226,6 +354,8 @@ type SearchFEDWIREOpts struct {
 	RoutingNumber optional.String
 	State         optional.String
 	City          optional.String
+	RevisedAfter  optional.String
+	RevisedBefore optional.String
 	Limit         optional.Int32
 }
 
@@ -239,6 +369,8 @@ SearchFEDWIRE Search FEDWIRE names and metadata
   - @param "RoutingNumber" (optional.String) -  FEDWIRE Routing Number for a Financial Institution
   - @param "State" (optional.String) -  FEDWIRE Financial Institution State
   - @param "City" (optional.String) -  FEDWIRE Financial Institution City
+  - @param "RevisedAfter" (optional.String) -  Only return participants revised on or after this date (YYYY-MM-DD or RFC 3339)
+  - @param "RevisedBefore" (optional.String) -  Only return participants revised before this date (YYYY-MM-DD or RFC 3339)
   - @param "Limit" (optional.Int32) -  Maximum results returned by a search
 
 @return WireDictionary
@@ -271,6 +403,12 @@ func (a *FEDApiService) SearchFEDWIRE(ctx _context.Context, localVarOptionals *S
 	if localVarOptionals != nil && localVarOptionals.City.IsSet() {
 		localVarQueryParams.Add("city", parameterToString(localVarOptionals.City.Value(), ""))
 	}
+	if localVarOptionals != nil && localVarOptionals.RevisedAfter.IsSet() {
+		localVarQueryParams.Add("revisedAfter", parameterToString(localVarOptionals.RevisedAfter.Value(), ""))
+	}
+	if localVarOptionals != nil && localVarOptionals.RevisedBefore.IsSet() {
+		localVarQueryParams.Add("revisedBefore", parameterToString(localVarOptionals.RevisedBefore.Value(), ""))
+	}
 	if localVarOptionals != nil && localVarOptionals.Limit.IsSet() {
 		localVarQueryParams.Add("limit", parameterToString(localVarOptionals.Limit.Value(), ""))
 	}
@@ -313,6 +451,124 @@ func (a *FEDApiService) SearchFEDWIRE(ctx _context.Context, localVarOptionals *S
 		return localVarReturnValue, localVarHTTPResponse, err
 	}
 
+	if localVarHTTPResponse.StatusCode >= 300 {
+		newErr := GenericOpenAPIError{
+			body:  localVarBody,
+			error: localVarHTTPResponse.Status,
+		}
+		if localVarHTTPResponse.StatusCode == 400 {
+			var v Error
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
+		if localVarHTTPResponse.StatusCode == 503 {
+			var v Error
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
+		return localVarReturnValue, localVarHTTPResponse, newErr
+	}
+
+	err = a.client.decode(&localVarReturnValue, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+	if err != nil {
+		newErr := GenericOpenAPIError{
+			body:  localVarBody,
+			error: err.Error(),
+		}
+		return localVarReturnValue, localVarHTTPResponse, newErr
+	}
+
+	return localVarReturnValue, localVarHTTPResponse, nil
+}
+
+// WireChangesOpts Optional parameters for the method 'WireChanges'
+type WireChangesOpts struct {
+	XRequestID optional.String
+	PageToken  optional.String
+	Limit      optional.Int32
+}
+
+/*
+WireChanges List FEDWIRE participants revised since a date
+Participants are sorted by revision date and then routing number. Follow nextPageToken to read every page.
+  - @param ctx _context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
+  - @param since Return participants revised on or after this date (YYYY-MM-DD or RFC 3339)
+  - @param optional nil or *WireChangesOpts - Optional Parameters:
+  - @param "XRequestID" (optional.String) -  Optional Request ID allows application developer to trace requests through the systems logs
+  - @param "PageToken" (optional.String) -  nextPageToken from the previous page
+  - @param "Limit" (optional.Int32) -  Maximum results returned per page
+
+@return WireChanges
+*/
+func (a *FEDApiService) WireChanges(ctx _context.Context, since string, localVarOptionals *WireChangesOpts) (WireChanges, *_nethttp.Response, error) {
+	var (
+		localVarHTTPMethod   = _nethttp.MethodGet
+		localVarPostBody     interface{}
+		localVarFormFileName string
+		localVarFileName     string
+		localVarFileBytes    []byte
+		localVarReturnValue  WireChanges
+	)
+
+	// create path and map variables
+	localVarPath := a.client.cfg.BasePath + "/fed/wire/changes"
+	localVarHeaderParams := make(map[string]string)
+	localVarQueryParams := _neturl.Values{}
+	localVarFormParams := _neturl.Values{}
+
+	localVarQueryParams.Add("since", parameterToString(since, ""))
+	if localVarOptionals != nil && localVarOptionals.PageToken.IsSet() {
+		localVarQueryParams.Add("pageToken", parameterToString(localVarOptionals.PageToken.Value(), ""))
+	}
+	if localVarOptionals != nil && localVarOptionals.Limit.IsSet() {
+		localVarQueryParams.Add("limit", parameterToString(localVarOptionals.Limit.Value(), ""))
+	}
+	// to determine the Content-Type header
+	localVarHTTPContentTypes := []string{}
+
+	// set Content-Type header
+	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
+	if localVarHTTPContentType != "" {
+		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
+	}
+
+	// to determine the Accept header
+	localVarHTTPHeaderAccepts := []string{"application/json"}
+
+	// set Accept header
+	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
+	if localVarHTTPHeaderAccept != "" {
+		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
+	}
+	if localVarOptionals != nil && localVarOptionals.XRequestID.IsSet() {
+		localVarHeaderParams["X-Request-ID"] = parameterToString(localVarOptionals.XRequestID.Value(), "")
+	}
+	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFormFileName, localVarFileName, localVarFileBytes)
+	if err != nil {
+		return localVarReturnValue, nil, err
+	}
+
+	localVarHTTPResponse, err := a.client.callAPI(r)
+	if err != nil || localVarHTTPResponse == nil {
+		return localVarReturnValue, localVarHTTPResponse, err
+	}
+
+	localVarBody, err := _ioutil.ReadAll(localVarHTTPResponse.Body)
+	localVarHTTPResponse.Body.Close()
+	if err != nil {
+		return localVarReturnValue, localVarHTTPResponse, err
+	}
+
 	if localVarHTTPResponse.StatusCode >= 300 {
 		newErr := GenericOpenAPIError{
 			body:  localVarBody,
@@ -9,10 +9,9 @@
 
 package client
 
-// Logo Company logo of the Fed ACH or Wire participant
-type Logo struct {
-	// Company name
-	Name string `json:"name,omitempty"`
-	// URL to the company logo
-	Url string `json:"url,omitempty"`
+// AchChanges A page of ACH participants revised since a date
+type AchChanges struct {
+	ACHParticipants []AchParticipant `json:"ACHParticipants,omitempty"`
+	// Passed as pageToken to read the next page, omitted on the last page
+	NextPageToken string `json:"nextPageToken,omitempty"`
 }
@@ -12,4 +12,6 @@ package client
 // AchDictionary Search results containing ACHDictionary of Participants
 type AchDictionary struct {
 	ACHParticipants []AchParticipant `json:"ACHParticipants,omitempty"`
+	// True when the search timed out and only the results found so far are returned
+	Partial bool `json:"partial,omitempty"`
 }
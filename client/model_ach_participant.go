@@ -9,6 +9,10 @@
 
 package client
 
+import (
+	"time"
+)
+
 // AchParticipant ACHParticipant holds a FedACH dir routing record as defined by Fed ACH Format.  https://www.frbservices.org/EPaymentsDirectory/achFormat.html
 type AchParticipant struct {
 	// The institution's routing number
@@ -19,8 +23,10 @@ type AchParticipant struct {
 	ServicingFRBNumber string `json:"servicingFRBNumber,omitempty"`
 	// The code indicating the ABA number to be used to route or send ACH items to the RDFI  * `0` - Institution is a Federal Reserve Bank * `1` - Send items to customer routing number * `2` - Send items to customer using new routing number field
 	RecordTypeCode string `json:"recordTypeCode,omitempty"`
-	// Date of last revision  * YYYYMMDD * Blank
+	// Date of last revision  * MMDDYY * Blank
 	Revised string `json:"revised,omitempty"`
+	// Parsed value of revised in RFC 3339, omitted when revised is blank
+	RevisedAt time.Time `json:"revisedAt,omitempty"`
 	// Financial Institution's new routing number resulting from a merger or renumber
 	NewRoutingNumber string `json:"newRoutingNumber,omitempty"`
 	// Financial Institution Name
@@ -34,5 +40,4 @@ type AchParticipant struct {
 	ViewCode string `json:"viewCode,omitempty"`
 	// Normalized name of ACH participant
 	CleanName string `json:"cleanName,omitempty"`
-	Logo      Logo   `json:"logo,omitempty"`
 }
@@ -12,4 +12,6 @@ package client
 // WireDictionary Search results containing WIREDictionary of Participants
 type WireDictionary struct {
 	WIREParticipants []WireParticipant `json:"WIREParticipants,omitempty"`
+	// True when the search timed out and only the results found so far are returned
+	Partial bool `json:"partial,omitempty"`
 }
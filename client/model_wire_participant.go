@@ -9,6 +9,10 @@
 
 package client
 
+import (
+	"time"
+)
+
 // WireParticipant WIREParticipant holds a FedWIRE dir routing record as defined by Fed WIRE Format.  https://frbservices.org/EPaymentsDirectory/fedwireFormat.html
 type WireParticipant struct {
 	// The institution's routing number
@@ -26,7 +30,8 @@ type WireParticipant struct {
 	BookEntrySecuritiesTransferStatus string `json:"bookEntrySecuritiesTransferStatus,omitempty"`
 	// Date of last revision  * YYYYMMDD * Blank
 	Date string `json:"date,omitempty"`
+	// Parsed value of date in RFC 3339, omitted when date is blank
+	RevisedAt time.Time `json:"revisedAt,omitempty"`
 	// Normalized name of Wire participant
 	CleanName string `json:"cleanName,omitempty"`
-	Logo      Logo   `json:"logo,omitempty"`
 }
@@ -0,0 +1,98 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// fakefrb serves FedACH and Fedwire directory files from local files the way the Federal Reserve's
+// ePayments directory download API does, so refreshes and download failures can be tested
+// end to end without network access or FRB credentials.
+//
+//	fakefrb [flags]
+//
+// Lists are served from /EPaymentsDirectory/directories/fedach and .../fedwire, as JSON with
+// ?format=json and as plaintext otherwise. Point Fed at it with:
+//
+//	FRB_ROUTING_NUMBER=123456780 FRB_DOWNLOAD_CODE=fake \
+//	FRB_DOWNLOAD_URL_TEMPLATE='http://localhost:8089/EPaymentsDirectory/directories/%s?format=json'
+//
+// The -dir directory holds fedachdir.json, FedACHdir.txt, fpddir.json and fpddir.txt, or
+// subdirectories named by date (e.g. 2024-03-01/) holding daily versions of them. The newest
+// version dated on or before the current date is served.
+//
+// Faults are injected into requests with -faults, -fault-rate and the /_fakefrb/faults endpoint.
+// They are: any HTTP status code (e.g. 401, 500), "truncate" for a body cut short and "html"
+// for a login page.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/moov-io/base/log"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("fakefrb", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	addr := fs.String("addr", ":8089", "HTTP listen address")
+	dir := fs.String("dir", "./data", "directory holding the directory files, or daily versions of them")
+	routingNumber := fs.String("routing-number", "", "required X_FRB_EPAYMENTS_DIRECTORY_ORG_ID header, any value is accepted when empty")
+	downloadCode := fs.String("download-code", "", "required X_FRB_EPAYMENTS_DIRECTORY_DOWNLOAD_CD header, any value is accepted when empty")
+	latency := fs.Duration("latency", 0, "delay before each response")
+	faults := fs.String("faults", "", "comma separated faults injected into the first requests, e.g. 500,truncate")
+	faultRate := fs.Float64("fault-rate", 0, "probability (0-1) of injecting a random fault from -fault-kinds into each request")
+	faultKinds := fs.String("fault-kinds", "500,truncate,html", "faults picked from by -fault-rate")
+	date := fs.String("date", "", "date (YYYY-MM-DD) used to pick daily versions, defaults to today")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := config{
+		dir:           *dir,
+		routingNumber: *routingNumber,
+		downloadCode:  *downloadCode,
+		latency:       *latency,
+		faultRate:     *faultRate,
+	}
+	var err error
+	if cfg.faults, err = parseFaults(*faults); err != nil {
+		return fmt.Errorf("-faults: %w", err)
+	}
+	if cfg.faultKinds, err = parseFaults(*faultKinds); err != nil {
+		return fmt.Errorf("-fault-kinds: %w", err)
+	}
+	if cfg.faultRate < 0 || cfg.faultRate > 1 {
+		return fmt.Errorf("-fault-rate %v isn't between 0 and 1", cfg.faultRate)
+	}
+	if cfg.faultRate > 0 && len(cfg.faultKinds) == 0 {
+		return errors.New("-fault-rate needs -fault-kinds")
+	}
+	if *date != "" {
+		if cfg.date, err = time.Parse(dateFormat, *date); err != nil {
+			return fmt.Errorf("-date: %w", err)
+		}
+	}
+
+	logger := log.NewDefaultLogger().Set("app", log.String("fakefrb"))
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           newServer(logger, cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logger.Logf("serving %s on %s", cfg.dir, *addr)
+	return srv.ListenAndServe()
+}
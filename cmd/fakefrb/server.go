@@ -0,0 +1,319 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base/log"
+)
+
+const dateFormat = "2006-01-02"
+
+// listFiles are the files served for each list, keyed by list name and then by format
+var listFiles = map[string]map[string]string{
+	"fedach": {
+		"json": "fedachdir.json",
+		"text": "FedACHdir.txt",
+	},
+	"fedwire": {
+		"json": "fpddir.json",
+		"text": "fpddir.txt",
+	},
+}
+
+// participantKeys wrap the participants in each list's JSON file
+var participantKeys = map[string]string{
+	"fedach":  "fedACHParticipants",
+	"fedwire": "fedwireParticipants",
+}
+
+// loginPage is served by the "html" fault, like the sign-in pages returned by proxies and the FRB
+const loginPage = `<!DOCTYPE html>
+<html>
+<head><title>Sign In</title></head>
+<body><form method="post" action="/login"><input name="username"><input name="password" type="password"></form></body>
+</html>
+`
+
+// fault is an error injected into a response: an HTTP status code, "truncate" or "html"
+type fault string
+
+const (
+	faultTruncate fault = "truncate"
+	faultHTML     fault = "html"
+)
+
+func parseFaults(value string) ([]fault, error) {
+	var out []fault
+	for _, v := range strings.Split(value, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		switch {
+		case v == "":
+			continue
+		case v == string(faultTruncate), v == string(faultHTML):
+		default:
+			if code, err := strconv.Atoi(v); err != nil || code < 400 || code > 599 {
+				return nil, fmt.Errorf("unknown fault %q", v)
+			}
+		}
+		out = append(out, fault(v))
+	}
+	return out, nil
+}
+
+type config struct {
+	dir string
+
+	routingNumber, downloadCode string
+
+	latency time.Duration
+
+	// faults are injected into the first requests, in order
+	faults []fault
+	// faultRate is the probability of injecting one of faultKinds into a request
+	faultRate  float64
+	faultKinds []fault
+
+	// date picks daily versions, the current date is used when it's zero
+	date time.Time
+}
+
+type server struct {
+	cfg    config
+	logger log.Logger
+	mux    *http.ServeMux
+
+	mu     sync.Mutex
+	faults []fault
+	date   time.Time
+
+	now func() time.Time
+}
+
+func newServer(logger log.Logger, cfg config) *server {
+	s := &server{
+		cfg:    cfg,
+		logger: logger,
+		mux:    http.NewServeMux(),
+		faults: cfg.faults,
+		date:   cfg.date,
+		now:    time.Now,
+	}
+	s.mux.HandleFunc("GET /EPaymentsDirectory/directories/{list}", s.serveList)
+	s.mux.HandleFunc("PUT /_fakefrb/faults", s.setFaults)
+	s.mux.HandleFunc("PUT /_fakefrb/date", s.setDate)
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// setFaults replaces the faults injected into the next requests with the comma separated request body
+func (s *server) setFaults(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(io.LimitReader(r.Body, 1024))
+	faults, err := parseFaults(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.faults = faults
+	s.mu.Unlock()
+
+	s.logger.Logf("injecting faults into the next %d requests", len(faults))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setDate changes the date used to pick daily versions to the request body, or today when it's empty
+func (s *server) setDate(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(io.LimitReader(r.Body, 1024))
+
+	var date time.Time
+	if v := strings.TrimSpace(string(body)); v != "" {
+		var err error
+		if date, err = time.Parse(dateFormat, v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.date = date
+	s.mu.Unlock()
+
+	s.logger.Logf("serving versions from %s", s.currentDate().Format(dateFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) currentDate() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.date.IsZero() {
+		return s.date
+	}
+	return s.now().UTC().Truncate(24 * time.Hour)
+}
+
+// nextFault returns the fault to inject into a request, or an empty fault
+func (s *server) nextFault() fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.faults) > 0 {
+		f := s.faults[0]
+		s.faults = s.faults[1:]
+		return f
+	}
+	if s.cfg.faultRate > 0 && rand.Float64() < s.cfg.faultRate {
+		return s.cfg.faultKinds[rand.IntN(len(s.cfg.faultKinds))]
+	}
+	return ""
+}
+
+func (s *server) serveList(w http.ResponseWriter, r *http.Request) {
+	listName := strings.ToLower(r.PathValue("list"))
+
+	if s.cfg.latency > 0 {
+		timer := time.NewTimer(s.cfg.latency)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	files, exists := listFiles[listName]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The FRB responds with an empty object when the headers are missing and a 202 response
+	// code when they're wrong.
+	if s.cfg.routingNumber != "" || s.cfg.downloadCode != "" {
+		orgID := r.Header.Get("X_FRB_EPAYMENTS_DIRECTORY_ORG_ID")
+		downloadCode := r.Header.Get("X_FRB_EPAYMENTS_DIRECTORY_DOWNLOAD_CD")
+		if orgID == "" || downloadCode == "" {
+			s.logger.Warn().Logf("%s request without FRB headers", listName)
+			writeJSON(w, "{ }")
+			return
+		}
+		if orgID != s.cfg.routingNumber || downloadCode != s.cfg.downloadCode {
+			s.logger.Warn().Logf("%s request with incorrect FRB headers", listName)
+			key := participantKeys[listName]
+			writeJSON(w, fmt.Sprintf(`{"%s": {"response": {"code": 202}, "%s": []}}`, key, key))
+			return
+		}
+	}
+
+	format := "text"
+	if strings.EqualFold(r.URL.Query().Get("format"), "json") {
+		format = "json"
+	}
+	dir, modTime, err := s.versionDir()
+	if err != nil {
+		s.logger.Error().Logf("finding %s version: %v", listName, err)
+		http.Error(w, "no version available", http.StatusNotFound)
+		return
+	}
+	path := filepath.Join(dir, files[format])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.logger.Error().Logf("reading %s: %v", listName, err)
+		http.NotFound(w, r)
+		return
+	}
+	if modTime.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+	}
+
+	if f := s.nextFault(); f != "" {
+		s.logger.Info().Logf("injecting %s fault into %s response", f, listName)
+		injectFault(w, f, data)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, files[format], modTime, bytes.NewReader(data))
+}
+
+// versionDir returns the newest daily version on or before the current date, and its date.
+// The data directory itself is returned when it has no versions.
+func (s *server) versionDir() (string, time.Time, error) {
+	entries, err := os.ReadDir(s.cfg.dir)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	today := s.currentDate()
+
+	var found bool
+	var newest time.Time
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		date, err := time.Parse(dateFormat, entry.Name())
+		if err != nil {
+			continue
+		}
+		found = true
+		if !date.After(today) && date.After(newest) {
+			newest = date
+		}
+	}
+	switch {
+	case !found:
+		return s.cfg.dir, time.Time{}, nil
+	case newest.IsZero():
+		return "", time.Time{}, fmt.Errorf("no version on or before %s", today.Format(dateFormat))
+	}
+	return filepath.Join(s.cfg.dir, newest.Format(dateFormat)), newest, nil
+}
+
+func injectFault(w http.ResponseWriter, f fault, data []byte) {
+	switch f {
+	case faultHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(loginPage))
+
+	case faultTruncate:
+		// Promise the whole file, send half of it and drop the connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data[:len(data)/2])
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		panic(http.ErrAbortHandler)
+
+	default:
+		code, _ := strconv.Atoi(string(f))
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(body))
+}
@@ -0,0 +1,230 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed/pkg/download"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer starts fakefrb and returns a download client configured for it
+func newTestServer(t *testing.T, cfg config) (*server, *httptest.Server, *download.Client) {
+	t.Helper()
+
+	if cfg.dir == "" {
+		cfg.dir = filepath.Join("..", "..", "data")
+	}
+	if cfg.routingNumber == "" {
+		cfg.routingNumber, cfg.downloadCode = "123456780", "fake"
+	}
+	s := newServer(log.NewTestLogger(), cfg)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	t.Setenv("FRB_ROUTING_NUMBER", cfg.routingNumber)
+	t.Setenv("FRB_DOWNLOAD_CODE", cfg.downloadCode)
+	t.Setenv("FRB_DOWNLOAD_URL_TEMPLATE", ts.URL+"/EPaymentsDirectory/directories/%s?format=json")
+	client, err := download.NewClient(&download.ClientOpts{
+		RetryBackoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+	return s, ts, client
+}
+
+func get(t *testing.T, ts *httptest.Server, path string, headers map[string]string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest("GET", ts.URL+path, nil)
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, body
+}
+
+var frbHeaders = map[string]string{
+	"X_FRB_EPAYMENTS_DIRECTORY_ORG_ID":      "123456780",
+	"X_FRB_EPAYMENTS_DIRECTORY_DOWNLOAD_CD": "fake",
+}
+
+func TestServer__lists(t *testing.T) {
+	_, ts, client := newTestServer(t, config{})
+
+	for listName, files := range listFiles {
+		r, err := client.GetList(listName)
+		require.NoError(t, err)
+		got, _ := io.ReadAll(r)
+		expected, _ := os.ReadFile(filepath.Join("..", "..", "data", files["json"]))
+		require.Equal(t, expected, got)
+
+		resp, body := get(t, ts, "/EPaymentsDirectory/directories/"+listName, frbHeaders)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, download.ValidatePayload(body))
+		require.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+	}
+
+	resp, _ := get(t, ts, "/EPaymentsDirectory/directories/other", frbHeaders)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer__headers(t *testing.T) {
+	_, ts, _ := newTestServer(t, config{})
+
+	resp, body := get(t, ts, "/EPaymentsDirectory/directories/fedach?format=json", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.ErrorContains(t, download.ValidatePayload(body), "JSON without an FRB response")
+
+	resp, body = get(t, ts, "/EPaymentsDirectory/directories/fedwire?format=json", map[string]string{
+		"X_FRB_EPAYMENTS_DIRECTORY_ORG_ID":      "123456780",
+		"X_FRB_EPAYMENTS_DIRECTORY_DOWNLOAD_CD": "wrong",
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(body), `"fedwireParticipants"`)
+	require.ErrorContains(t, download.ValidatePayload(body), "FRB response code 202")
+}
+
+func TestServer__conditional(t *testing.T) {
+	_, _, client := newTestServer(t, config{})
+
+	_, err := client.GetList("fedach")
+	require.NoError(t, err)
+	require.NotEmpty(t, client.Validators("fedach").ETag)
+
+	_, err = client.GetList("fedach")
+	require.ErrorIs(t, err, download.ErrNotModified)
+}
+
+func TestServer__faults(t *testing.T) {
+	s, ts, client := newTestServer(t, config{
+		faults: []fault{"500", faultTruncate, "503"},
+	})
+
+	// Server errors and truncated bodies are retried
+	_, err := client.GetList("fedach")
+	require.NoError(t, err)
+
+	for f, expected := range map[string]string{
+		"401":      "unexpected http status: 401",
+		"html":     "text/html response",
+		"truncate": "unexpected EOF",
+	} {
+		faults, err := parseFaults(strings.Repeat(f+",", download.DefaultMaxAttempts))
+		require.NoError(t, err)
+		s.mu.Lock()
+		s.faults = faults
+		s.mu.Unlock()
+
+		client.SetValidators("fedwire", download.Validators{})
+		_, err = client.GetList("fedwire")
+		require.ErrorContains(t, err, expected, f)
+	}
+
+	// Faults are set over HTTP
+	req, _ := http.NewRequest("PUT", ts.URL+"/_fakefrb/faults", strings.NewReader("429"))
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = get(t, ts, "/EPaymentsDirectory/directories/fedach", frbHeaders)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	resp, _ = get(t, ts, "/EPaymentsDirectory/directories/fedach", frbHeaders)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = parseFaults("200")
+	require.ErrorContains(t, err, `unknown fault "200"`)
+}
+
+func TestServer__faultRate(t *testing.T) {
+	_, ts, _ := newTestServer(t, config{
+		faultRate:  1,
+		faultKinds: []fault{"502"},
+	})
+	resp, _ := get(t, ts, "/EPaymentsDirectory/directories/fedach", frbHeaders)
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func TestServer__latency(t *testing.T) {
+	_, _, client := newTestServer(t, config{
+		latency: time.Minute,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetListContext(ctx, "fedach")
+	require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestServer__versions(t *testing.T) {
+	dir := t.TempDir()
+	for date, body := range map[string]string{
+		"2024-03-01": "011000015first",
+		"2024-03-04": "011000015second",
+		"2024-03-08": "011000015future",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, date), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, date, "FedACHdir.txt"), []byte(body), 0600))
+	}
+
+	s, ts, _ := newTestServer(t, config{dir: dir})
+	s.now = func() time.Time { return time.Date(2024, time.March, 5, 15, 0, 0, 0, time.UTC) }
+
+	resp, body := get(t, ts, "/EPaymentsDirectory/directories/fedach", frbHeaders)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "011000015second", string(body))
+	require.Equal(t, "Mon, 04 Mar 2024 00:00:00 GMT", resp.Header.Get("Last-Modified"))
+
+	// Daily versions are changed over HTTP
+	for date, expected := range map[string]string{
+		"2024-03-02": "011000015first",
+		"2024-03-09": "011000015future",
+	} {
+		req, _ := http.NewRequest("PUT", ts.URL+"/_fakefrb/date", strings.NewReader(date))
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		_, body := get(t, ts, "/EPaymentsDirectory/directories/fedach", frbHeaders)
+		require.Equal(t, expected, string(body))
+	}
+
+	s.mu.Lock()
+	s.date = time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+	s.mu.Unlock()
+	resp, _ = get(t, ts, "/EPaymentsDirectory/directories/fedach", frbHeaders)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestRun__flags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-faults", "teapot"},
+		{"-fault-rate", "2"},
+		{"-fault-rate", "0.5", "-fault-kinds", ""},
+		{"-date", "yesterday"},
+	} {
+		require.Error(t, run(args, io.Discard), args)
+	}
+}
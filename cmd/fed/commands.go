@@ -0,0 +1,231 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/moov-io/fed"
+)
+
+// lookup prints the participants with an exact routing number match
+func lookup(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("lookup", stderr)
+	opts := &options{}
+	opts.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: fed lookup [flags] <routing-number>")
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, opts, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	routingNumber := strings.TrimSpace(fs.Arg(0))
+
+	dicts, err := loadDictionaries(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return exitDataError
+	}
+
+	resp := &response{}
+	if dicts.ach != nil {
+		if p := dicts.ach.RoutingNumberSearchSingle(routingNumber); p != nil {
+			resp.ACHParticipants = append(resp.ACHParticipants, p)
+		}
+	}
+	if dicts.wire != nil {
+		if p := dicts.wire.RoutingNumberSearchSingle(routingNumber); p != nil {
+			resp.WIREParticipants = append(resp.WIREParticipants, p)
+		}
+	}
+	if err := resp.write(stdout, opts.format, opts.describe); err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return exitDataError
+	}
+	if resp.empty() {
+		fmt.Fprintf(stderr, "no participant found for %s\n", routingNumber)
+		return exitNotFound
+	}
+	return exitOK
+}
+
+// search prints participants matching a financial institution name, city and/or state
+func search(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("search", stderr)
+	opts := &options{}
+	opts.register(fs)
+	name := fs.String("name", "", "Financial institution name, matched with fuzzy search")
+	city := fs.String("city", "", "City, matched exactly ignoring case")
+	state := fs.String("state", "", "Two letter state code, matched exactly ignoring case")
+	limit := fs.Int("limit", 10, "Maximum number of participants to print per directory")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: fed search [flags] -name <name> -city <city> -state <state>")
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, opts, args); !ok {
+		return code
+	}
+	req := searchRequest{
+		name:  strings.TrimSpace(*name),
+		city:  strings.TrimSpace(*city),
+		state: strings.TrimSpace(*state),
+		limit: *limit,
+	}
+	if req.empty() || req.limit <= 0 || fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	dicts, err := loadDictionaries(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return exitDataError
+	}
+
+	resp := &response{}
+	if dicts.ach != nil {
+		resp.ACHParticipants = req.searchACH(dicts.ach)
+	}
+	if dicts.wire != nil {
+		resp.WIREParticipants = req.searchWire(dicts.wire)
+	}
+	if err := resp.write(stdout, opts.format, opts.describe); err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return exitDataError
+	}
+	if resp.empty() {
+		fmt.Fprintln(stderr, "no participants found")
+		return exitNotFound
+	}
+	return exitOK
+}
+
+type searchRequest struct {
+	name, city, state string
+	limit             int
+}
+
+func (req searchRequest) empty() bool {
+	return req.name == "" && req.city == "" && req.state == ""
+}
+
+func (req searchRequest) searchACH(dict *fed.ACHDictionary) []*fed.ACHParticipant {
+	var out []*fed.ACHParticipant
+	switch {
+	case req.name != "":
+		// Score every participant so the city and state filters are applied before the limit
+		out = dict.FinancialInstitutionSearch(req.name, len(dict.ACHParticipants))
+		if req.state != "" {
+			out = dict.ACHParticipantStateFilter(out, req.state)
+		}
+	case req.state != "":
+		out = dict.StateFilter(req.state)
+	default:
+		out = dict.CityFilter(req.city)
+	}
+	if req.city != "" {
+		out = dict.ACHParticipantCityFilter(out, req.city)
+	}
+	if len(out) > req.limit {
+		out = out[:req.limit]
+	}
+	return out
+}
+
+func (req searchRequest) searchWire(dict *fed.WIREDictionary) []*fed.WIREParticipant {
+	var out []*fed.WIREParticipant
+	switch {
+	case req.name != "":
+		// Score every participant so the city and state filters are applied before the limit
+		out = dict.FinancialInstitutionSearch(req.name, len(dict.WIREParticipants))
+		if req.state != "" {
+			out = dict.WIREParticipantStateFilter(out, req.state)
+		}
+	case req.state != "":
+		out = dict.StateFilter(req.state)
+	default:
+		out = dict.CityFilter(req.city)
+	}
+	if req.city != "" {
+		out = dict.WIREParticipantCityFilter(out, req.city)
+	}
+	if len(out) > req.limit {
+		out = out[:req.limit]
+	}
+	return out
+}
+
+// validation is the result of checking a routing number
+type validation struct {
+	RoutingNumber string `json:"routingNumber"`
+	Valid         bool   `json:"valid"`
+	Error         string `json:"error,omitempty"`
+
+	// ACH and Wire are nil when the directory was not read
+	ACH  *bool `json:"ach,omitempty"`
+	Wire *bool `json:"wire,omitempty"`
+}
+
+// validate checks the ABA check digit of a routing number and whether it's a directory participant
+func validate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", stderr)
+	opts := &options{}
+	opts.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: fed validate [flags] <routing-number>")
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, opts, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	result := &validation{
+		RoutingNumber: strings.TrimSpace(fs.Arg(0)),
+	}
+	if err := fed.ValidateRoutingNumber(result.RoutingNumber); err != nil {
+		result.Error = err.Error()
+	} else {
+		dicts, err := loadDictionaries(opts)
+		if err != nil {
+			fmt.Fprintf(stderr, "ERROR: %v\n", err)
+			return exitDataError
+		}
+		if dicts.ach != nil {
+			found := dicts.ach.RoutingNumberSearchSingle(result.RoutingNumber) != nil
+			result.ACH = &found
+		}
+		if dicts.wire != nil {
+			found := dicts.wire.RoutingNumberSearchSingle(result.RoutingNumber) != nil
+			result.Wire = &found
+		}
+		result.Valid = (result.ACH != nil && *result.ACH) || (result.Wire != nil && *result.Wire)
+		if !result.Valid {
+			result.Error = errNotParticipant.Error()
+		}
+	}
+
+	if err := result.write(stdout, opts.format); err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return exitDataError
+	}
+	if !result.Valid {
+		return exitNotFound
+	}
+	return exitOK
+}
+
+var errNotParticipant = errors.New("routing number is not a directory participant")
@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/moov-io/fed"
+	"github.com/moov-io/fed/pkg/archive"
+)
+
+var (
+	achFilenames  = []string{"FedACHdir.txt", "fedachdir.json", "fedach.txt", "fedach.json"}
+	wireFilenames = []string{"fpddir.json", "fpddir.txt", "fedwire.txt", "fedwire.json"}
+)
+
+// dictionaries holds the directories read for a command, either may be nil
+// when only one directory was requested.
+type dictionaries struct {
+	ach  *fed.ACHDictionary
+	wire *fed.WIREDictionary
+}
+
+func loadDictionaries(opts *options) (*dictionaries, error) {
+	out := &dictionaries{}
+	if opts.wantACH() {
+		path := dataFilepath(opts.achPath, "FEDACH_DATA_PATH", "./data/FedACHdir.txt")
+		out.ach = fed.NewACHDictionary()
+		if err := readFile(path, achFilenames, out.ach.Read); err != nil {
+			return nil, fmt.Errorf("reading FedACH file: %w", err)
+		}
+	}
+	if opts.wantWire() {
+		path := dataFilepath(opts.wirePath, "FEDWIRE_DATA_PATH", "./data/fpddir.txt")
+		out.wire = fed.NewWIREDictionary()
+		if err := readFile(path, wireFilenames, out.wire.Read); err != nil {
+			return nil, fmt.Errorf("reading Fedwire file: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// readFile parses the file at path, which can be gzip compressed or a zip archive. Zip archives are
+// searched for a file matching names and otherwise need to contain exactly one file.
+func readFile(path string, names []string, read func(io.Reader) error) error {
+	fd, err := archive.Open(path, names)
+	if errors.Is(err, archive.ErrNoMatchingFile) {
+		fd, err = archive.Open(path, nil)
+	}
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	if err := read(fd); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+func dataFilepath(flagValue, env, fallback string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return fallback
+}
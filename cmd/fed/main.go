@@ -0,0 +1,139 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// fed is a command line tool for answering routing number questions from local FedACH and
+// Fedwire directory files. It does not need network access or a running Fed server.
+//
+//	fed lookup [flags] <routing-number>
+//	fed search [flags] -name <name> -city <city> -state <state>
+//	fed validate [flags] <routing-number>
+//
+// Directory files are read from -ach and -wire, then FEDACH_DATA_PATH and FEDWIRE_DATA_PATH,
+// then ./data/FedACHdir.txt and ./data/fpddir.txt. Results are printed as a table or,
+// with -format json, as JSON.
+//
+// Exit codes: 0 on success, 1 when nothing was found or the routing number is invalid,
+// 2 for usage errors and 3 when the directory files could not be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/moov-io/fed"
+)
+
+const (
+	exitOK = iota
+	exitNotFound
+	exitUsage
+	exitDataError
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	cmd, args := args[0], args[1:]
+	switch strings.ToLower(cmd) {
+	case "lookup":
+		return lookup(args, stdout, stderr)
+	case "search":
+		return search(args, stdout, stderr)
+	case "validate":
+		return validate(args, stdout, stderr)
+	case "version":
+		fmt.Fprintln(stdout, fed.Version)
+		return exitOK
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n\n", cmd)
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, `fed %s - offline FedACH and Fedwire directory lookups
+
+Usage:
+  fed lookup [flags] <routing-number>
+  fed search [flags] -name <name> -city <city> -state <state>
+  fed validate [flags] <routing-number>
+  fed version
+
+Run 'fed <command> -h' for the flags of each command.
+`, fed.Version)
+}
+
+// options are the flags shared by every command
+type options struct {
+	achPath   string
+	wirePath  string
+	directory string
+	format    string
+	describe  bool
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.achPath, "ach", "", "Filepath to FedACH data file (default: FEDACH_DATA_PATH or ./data/FedACHdir.txt)")
+	fs.StringVar(&o.wirePath, "wire", "", "Filepath to Fedwire data file (default: FEDWIRE_DATA_PATH or ./data/fpddir.txt)")
+	fs.StringVar(&o.directory, "directory", "", "Only read one directory (Options: ach, wire)")
+	fs.StringVar(&o.format, "format", "table", "Output format (Options: table, json)")
+	fs.BoolVar(&o.describe, "describe", false, "Include descriptions of participant codes in JSON output")
+}
+
+func (o *options) validate() error {
+	switch strings.ToLower(o.directory) {
+	case "", "ach", "wire":
+	default:
+		return fmt.Errorf("unknown directory %q", o.directory)
+	}
+	switch strings.ToLower(o.format) {
+	case "table", "json":
+	default:
+		return fmt.Errorf("unknown format %q", o.format)
+	}
+	return nil
+}
+
+func (o *options) wantACH() bool {
+	return o.directory == "" || strings.EqualFold(o.directory, "ach")
+}
+
+func (o *options) wantWire() bool {
+	return o.directory == "" || strings.EqualFold(o.directory, "wire")
+}
+
+// parseFlags parses the command's flags and reports the exit code to use when parsing fails.
+func parseFlags(fs *flag.FlagSet, opts *options, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if err := opts.validate(); err != nil {
+		fmt.Fprintf(fs.Output(), "%v\n", err)
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
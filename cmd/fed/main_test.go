@@ -0,0 +1,130 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testACHFile  = filepath.Join("..", "..", "data", "FedACHdir.txt")
+	testWireFile = filepath.Join("..", "..", "data", "fpddir.txt")
+)
+
+func runTest(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func dataArgs(args ...string) []string {
+	return append([]string{args[0], "-ach", testACHFile, "-wire", testWireFile}, args[1:]...)
+}
+
+func TestRun__usage(t *testing.T) {
+	code, _, stderr := runTest(t)
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, "Usage:")
+
+	code, _, stderr = runTest(t, "other")
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, `unknown command "other"`)
+
+	code, _, _ = runTest(t, "lookup")
+	require.Equal(t, exitUsage, code)
+
+	code, _, stderr = runTest(t, dataArgs("lookup", "-format", "xml", "273976369")...)
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, `unknown format "xml"`)
+}
+
+func TestRun__lookup(t *testing.T) {
+	code, stdout, _ := runTest(t, dataArgs("lookup", "273976369")...)
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, "ACH        273976369       VERIDIAN CREDIT UNION")
+	require.Contains(t, stdout, "Wire       273976369       VERIDIAN CREDIT UNION")
+
+	code, stdout, _ = runTest(t, dataArgs("lookup", "-directory", "wire", "-format", "json", "273976369")...)
+	require.Equal(t, exitOK, code)
+
+	var resp response
+	require.NoError(t, json.Unmarshal([]byte(stdout), &resp))
+	require.Empty(t, resp.ACHParticipants)
+	require.Len(t, resp.WIREParticipants, 1)
+	require.Equal(t, "VERIDIAN CREDIT UNION", resp.WIREParticipants[0].CustomerName)
+
+	code, _, stderr := runTest(t, dataArgs("lookup", "123456789")...)
+	require.Equal(t, exitNotFound, code)
+	require.Contains(t, stderr, "no participant found for 123456789")
+}
+
+func TestRun__lookupMissingFile(t *testing.T) {
+	code, _, stderr := runTest(t, "lookup", "-ach", filepath.Join(t.TempDir(), "missing.txt"), "273976369")
+	require.Equal(t, exitDataError, code)
+	require.Contains(t, stderr, "reading FedACH file")
+}
+
+func TestRun__search(t *testing.T) {
+	code, stdout, _ := runTest(t, dataArgs("search", "-format", "json", "-name", "Veridian", "-city", "waterloo", "-state", "IA")...)
+	require.Equal(t, exitOK, code)
+
+	var resp response
+	require.NoError(t, json.Unmarshal([]byte(stdout), &resp))
+	require.Len(t, resp.ACHParticipants, 3)
+	for _, p := range resp.ACHParticipants {
+		require.Equal(t, "WATERLOO", p.City)
+	}
+	require.Len(t, resp.WIREParticipants, 1)
+
+	code, stdout, _ = runTest(t, dataArgs("search", "-directory", "ach", "-format", "json", "-state", "OH", "-limit", "5")...)
+	require.Equal(t, exitOK, code)
+	resp = response{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &resp))
+	require.Len(t, resp.ACHParticipants, 5)
+
+	code, _, _ = runTest(t, dataArgs("search", "-name", "zzzzzzzzzzzz")...)
+	require.Equal(t, exitNotFound, code)
+
+	code, _, _ = runTest(t, dataArgs("search")...)
+	require.Equal(t, exitUsage, code)
+}
+
+func TestRun__validate(t *testing.T) {
+	code, stdout, _ := runTest(t, dataArgs("validate", "-format", "json", "011000015")...)
+	require.Equal(t, exitOK, code)
+
+	var result validation
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	require.True(t, result.Valid)
+	require.True(t, *result.ACH)
+	require.True(t, *result.Wire)
+
+	// bad check digit
+	code, stdout, _ = runTest(t, dataArgs("validate", "011000016")...)
+	require.Equal(t, exitNotFound, code)
+	require.Contains(t, stdout, "check digit is invalid")
+
+	// valid check digit, but not a participant
+	code, stdout, _ = runTest(t, dataArgs("validate", "123456780")...)
+	require.Equal(t, exitNotFound, code)
+	require.Contains(t, stdout, errNotParticipant.Error())
+}
+
+func TestRun__describe(t *testing.T) {
+	code, stdout, _ := runTest(t, dataArgs("lookup", "-directory", "ach", "-format", "json", "-describe", "011000015")...)
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, `"description": "Institution is a Federal Reserve Bank"`)
+
+	code, stdout, _ = runTest(t, dataArgs("lookup", "-directory", "ach", "-format", "json", "011000015")...)
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, `"recordTypeCode": "0"`)
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moov-io/fed"
+)
+
+// response mirrors the JSON body returned by the Fed server's search endpoints
+type response struct {
+	ACHParticipants  []*fed.ACHParticipant  `json:"achParticipants,omitempty"`
+	WIREParticipants []*fed.WIREParticipant `json:"wireParticipants,omitempty"`
+}
+
+func (r *response) empty() bool {
+	return len(r.ACHParticipants) == 0 && len(r.WIREParticipants) == 0
+}
+
+// write prints the participants in format, describe encodes participant codes in JSON with their descriptions
+func (r *response) write(w io.Writer, format string, describe bool) error {
+	if strings.EqualFold(format, "json") {
+		if describe {
+			return writeJSON(w, struct {
+				ACHParticipants  []fed.DescribedACHParticipant  `json:"achParticipants,omitempty"`
+				WIREParticipants []fed.DescribedWIREParticipant `json:"wireParticipants,omitempty"`
+			}{
+				ACHParticipants:  fed.DescribeACHParticipants(r.ACHParticipants),
+				WIREParticipants: fed.DescribeWIREParticipants(r.WIREParticipants),
+			})
+		}
+		return writeJSON(w, r)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DIRECTORY\tROUTING NUMBER\tNAME\tCITY\tSTATE\tDETAILS")
+	for _, p := range r.ACHParticipants {
+		fmt.Fprintf(tw, "ACH\t%s\t%s\t%s\t%s\t%s\n", p.RoutingNumber, p.CustomerName, p.City, p.State, achDetails(p))
+	}
+	for _, p := range r.WIREParticipants {
+		fmt.Fprintf(tw, "Wire\t%s\t%s\t%s\t%s\t%s\n", p.RoutingNumber, p.CustomerName, p.City, p.State, wireDetails(p))
+	}
+	return tw.Flush()
+}
+
+func achDetails(p *fed.ACHParticipant) string {
+	details := []string{"office=" + p.OfficeCode.String(), "revised=" + p.Revised}
+	if p.IsRedirect() {
+		details = append(details, "new="+p.NewRoutingNumber)
+	}
+	return strings.Join(details, " ")
+}
+
+func wireDetails(p *fed.WIREParticipant) string {
+	details := []string{"funds=" + p.FundsTransferStatus.String(), "revised=" + strings.TrimSpace(p.Date)}
+	if p.IsSettlementOnly() {
+		details = append(details, "settlement-only")
+	}
+	return strings.Join(details, " ")
+}
+
+func (v *validation) write(w io.Writer, format string) error {
+	if strings.EqualFold(format, "json") {
+		return writeJSON(w, v)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ROUTING NUMBER\t%s\n", v.RoutingNumber)
+	fmt.Fprintf(tw, "VALID\t%t\n", v.Valid)
+	if v.ACH != nil {
+		fmt.Fprintf(tw, "ACH PARTICIPANT\t%t\n", *v.ACH)
+	}
+	if v.Wire != nil {
+		fmt.Fprintf(tw, "WIRE PARTICIPANT\t%t\n", *v.Wire)
+	}
+	if v.Error != "" {
+		fmt.Fprintf(tw, "ERROR\t%s\n", v.Error)
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
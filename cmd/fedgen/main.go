@@ -0,0 +1,124 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// fedgen writes synthetic FedACH and Fedwire directory files for load and integration testing,
+// so tests don't need the FRB's files. Records have valid check digits and plausible names and
+// locations, see package github.com/moov-io/fed/pkg/synthetic.
+//
+//	fedgen [flags]
+//
+// For example, a directory ten times the size of the FRB's in JSON:
+//
+//	fedgen -ach 180000 -wire 77000 -format json -out ./testdata
+//
+// Files are named like the FRB's (FedACHdir.txt and fpddir.txt, or fedachdir.json and fpddir.json).
+// The same -seed and -date write the same files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/moov-io/fed/pkg/synthetic"
+)
+
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fedgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	out := fs.String("out", ".", "directory the files are written into")
+	format := fs.String("format", "text", "file format: text or json")
+	achRecords := fs.Int("ach", 18000, "number of FedACH records")
+	wireRecords := fs.Int("wire", 7700, "number of Fedwire records")
+	seed := fs.Uint64("seed", 1, "random seed")
+	branchRate := fs.Float64("branches", 0.01, "fraction of FedACH records which are branch offices")
+	redirectRate := fs.Float64("redirects", 0.09, "fraction of FedACH records redirected to a new routing number")
+	defectRate := fs.Float64("defects", 0, "fraction of records given a deliberate defect")
+	date := fs.String("date", "", "latest change date of records (YYYY-MM-DD), defaults to today")
+	defectKinds := fs.String("defect-kinds", "all", "comma separated defects: check-digit, duplicate, dangling-redirect, long-name, unknown-state, invalid-date")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	f := synthetic.Format(*format)
+	if f != synthetic.FormatText && f != synthetic.FormatJSON {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return exitUsage
+	}
+	defects, err := synthetic.ParseDefects(*defectKinds)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	var now time.Time
+	if *date != "" {
+		if now, err = time.Parse("2006-01-02", *date); err != nil {
+			fmt.Fprintf(stderr, "invalid -date: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	dir, err := synthetic.Generate(synthetic.Options{
+		Seed:         *seed,
+		ACHRecords:   *achRecords,
+		WireRecords:  *wireRecords,
+		BranchRate:   *branchRate,
+		RedirectRate: *redirectRate,
+		DefectRate:   *defectRate,
+		Defects:      defects,
+		Now:          now,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	achFilename, wireFilename := f.Filenames()
+	for _, file := range []struct {
+		name  string
+		write func(io.Writer, synthetic.Format) error
+	}{
+		{achFilename, dir.WriteACH},
+		{wireFilename, dir.WriteWire},
+	} {
+		path := filepath.Join(*out, file.name)
+		if err := writeFile(path, f, file.write); err != nil {
+			fmt.Fprintf(stderr, "writing %s: %v\n", path, err)
+			return exitError
+		}
+		fmt.Fprintln(stdout, path)
+	}
+	return exitOK
+}
+
+func writeFile(path string, format synthetic.Format, write func(io.Writer, synthetic.Format) error) error {
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(fd, format); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/fed"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runTest(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		dir := t.TempDir()
+		code, stdout, stderr := runTest(t, "-out", dir, "-format", format, "-ach", "300", "-wire", "100", "-date", "2024-03-01")
+		require.Equal(t, exitOK, code, stderr)
+		require.Contains(t, stdout, dir)
+
+		files, err := filepath.Glob(filepath.Join(dir, "*"))
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+
+		ach := fed.NewACHDictionary()
+		wire := fed.NewWIREDictionary()
+		for _, path := range files {
+			fd, err := os.Open(path)
+			require.NoError(t, err)
+			if strings.HasPrefix(filepath.Base(path), "fpddir") {
+				require.NoError(t, wire.Read(fd), path)
+			} else {
+				require.NoError(t, ach.Read(fd), path)
+			}
+			fd.Close()
+		}
+		require.Len(t, ach.ACHParticipants, 300)
+		require.Len(t, wire.WIREParticipants, 100)
+	}
+}
+
+func TestRun__usage(t *testing.T) {
+	for _, args := range [][]string{
+		{"-format", "xml"},
+		{"-defect-kinds", "typo"},
+		{"-date", "yesterday"},
+		{"-ach", "-1"},
+		{"-unknown"},
+	} {
+		code, _, _ := runTest(t, append(args, "-out", t.TempDir())...)
+		require.Equal(t, exitUsage, code, args)
+	}
+}
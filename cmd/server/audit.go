@@ -0,0 +1,329 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+)
+
+const (
+	defaultAuditLogMaxMegabytes = 100
+	defaultAuditLogMaxBackups   = 5
+
+	auditRedacted = "REDACTED"
+)
+
+// auditParameters are the query parameters of searches and change feeds which are audited
+var auditParameters = []string{"name", "routingNumber", "city", "state", "postalCode", "revisedAfter", "revisedBefore", "since", "pageToken", "limit"}
+
+// auditEntry is one line of the audit log
+type auditEntry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestID,omitempty"`
+	UserID    string    `json:"userID,omitempty"`
+	List      string    `json:"list"`
+
+	// Parameters are the search's non-empty query parameters
+	Parameters map[string]string `json:"parameters"`
+
+	// Status is the HTTP status of the response, 0 when the client went away before one was written
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	Results        int      `json:"results"`
+	RoutingNumbers []string `json:"routingNumbers"`
+	Partial        bool     `json:"partial,omitempty"`
+}
+
+// auditResult is how a search was answered, rejected and failed searches have an err
+type auditResult struct {
+	status         int
+	err            error
+	routingNumbers []string
+	partial        bool
+}
+
+// auditConfig is read from AUDIT_LOG and the other AUDIT_LOG_* environment variables
+type auditConfig struct {
+	// destination is "stdout" or a file path, audit logging is disabled when empty
+	destination string
+
+	maxBytes   int64 // size a file is rotated at
+	maxBackups int   // rotated files kept
+
+	// redact holds the fields replaced with REDACTED: requestID, userID, routingNumbers or a parameter name
+	redact map[string]bool
+
+	// sampleRate is the fraction of searches logged
+	sampleRate float64
+}
+
+// readAuditConfig reads AUDIT_LOG, AUDIT_LOG_MAX_MEGABYTES, AUDIT_LOG_MAX_BACKUPS, AUDIT_LOG_REDACT
+// and AUDIT_LOG_SAMPLE_RATE
+func readAuditConfig() (auditConfig, error) {
+	cfg := auditConfig{
+		destination: strings.TrimSpace(os.Getenv("AUDIT_LOG")),
+		maxBytes:    defaultAuditLogMaxMegabytes * 1024 * 1024,
+		maxBackups:  defaultAuditLogMaxBackups,
+		redact:      make(map[string]bool),
+		sampleRate:  1.0,
+	}
+	if v := os.Getenv("AUDIT_LOG_MAX_MEGABYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid AUDIT_LOG_MAX_MEGABYTES: %q", v)
+		}
+		cfg.maxBytes = int64(n) * 1024 * 1024
+	}
+	if v := os.Getenv("AUDIT_LOG_MAX_BACKUPS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid AUDIT_LOG_MAX_BACKUPS: %q", v)
+		}
+		cfg.maxBackups = n
+	}
+	if v := os.Getenv("AUDIT_LOG_REDACT"); v != "" {
+		fields := append([]string{"requestID", "userID", "routingNumbers"}, auditParameters...)
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if !slices.Contains(fields, field) {
+				return cfg, fmt.Errorf("invalid AUDIT_LOG_REDACT: unknown field %q", field)
+			}
+			cfg.redact[field] = true
+		}
+	}
+	if v := os.Getenv("AUDIT_LOG_SAMPLE_RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return cfg, fmt.Errorf("invalid AUDIT_LOG_SAMPLE_RATE: %q must be above 0 and at most 1", v)
+		}
+		cfg.sampleRate = rate
+	}
+	return cfg, nil
+}
+
+// auditLogger appends an auditEntry JSON line for each sampled search to stdout or a rotatingFile.
+// Writes are serialized so lines from concurrent searches don't interleave.
+type auditLogger struct {
+	cfg    auditConfig
+	logger log.Logger
+
+	mu  sync.Mutex
+	out io.WriteCloser
+
+	now    func() time.Time
+	sample func() float64 // returns values in [0, 1)
+}
+
+// newAuditLogger opens the destination of cfg. Without AUDIT_LOG there's nothing to open and it
+// returns nil, which record and Close accept so searches aren't logged.
+func newAuditLogger(logger log.Logger, cfg auditConfig) (*auditLogger, error) {
+	if cfg.destination == "" {
+		return nil, nil
+	}
+
+	var out io.WriteCloser
+	if cfg.destination == "stdout" {
+		out = nopCloser{os.Stdout}
+	} else {
+		w, err := newRotatingFile(cfg.destination, cfg.maxBytes, cfg.maxBackups)
+		if err != nil {
+			return nil, fmt.Errorf("opening audit log: %w", err)
+		}
+		out = w
+	}
+	return &auditLogger{
+		cfg:    cfg,
+		logger: logger,
+		out:    out,
+		now:    time.Now,
+		sample: rand.Float64,
+	}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// record logs a search of listName (ach or wire) and its result
+func (a *auditLogger) record(r *http.Request, listName string, result auditResult) {
+	if a == nil || a.sample() >= a.cfg.sampleRate {
+		return
+	}
+
+	routingNumbers := result.routingNumbers
+	if routingNumbers == nil {
+		routingNumbers = []string{}
+	}
+	entry := auditEntry{
+		Time:           a.now(),
+		RequestID:      a.redacted("requestID", moovhttp.GetRequestID(r)),
+		UserID:         a.redacted("userID", moovhttp.GetUserID(r)),
+		List:           listName,
+		Parameters:     make(map[string]string),
+		Status:         result.status,
+		Results:        len(routingNumbers),
+		RoutingNumbers: routingNumbers,
+		Partial:        result.partial,
+	}
+	if result.err != nil {
+		entry.Error = result.err.Error()
+	}
+	for _, key := range auditParameters {
+		if v := strings.TrimSpace(r.URL.Query().Get(key)); v != "" {
+			entry.Parameters[key] = a.redacted(key, v)
+		}
+	}
+	if a.cfg.redact["routingNumbers"] {
+		entry.RoutingNumbers = make([]string, len(routingNumbers))
+		for i := range entry.RoutingNumbers {
+			entry.RoutingNumbers[i] = auditRedacted
+		}
+	}
+
+	bs, err := json.Marshal(entry)
+	if err != nil {
+		a.logger.Error().Logf("problem encoding audit log entry: %v", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.out.Write(append(bs, '\n')); err != nil {
+		a.logger.Error().Logf("problem writing audit log: %v", err)
+	}
+}
+
+func achRoutingNumbers(participants []*fed.ACHParticipant) []string {
+	out := make([]string, len(participants))
+	for i := range participants {
+		out[i] = participants[i].RoutingNumber
+	}
+	return out
+}
+
+func wireRoutingNumbers(participants []*fed.WIREParticipant) []string {
+	out := make([]string, len(participants))
+	for i := range participants {
+		out[i] = participants[i].RoutingNumber
+	}
+	return out
+}
+
+func (a *auditLogger) redacted(field, v string) string {
+	if v != "" && a.cfg.redact[field] {
+		return auditRedacted
+	}
+	return v
+}
+
+// Close closes the audit log file
+func (a *auditLogger) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.out.Close()
+}
+
+// rotatingFile is a file which is renamed with a numbered suffix (audit.log.1, audit.log.2, ...)
+// once it grows past maxBytes, keeping at most maxBackups of them.
+type rotatingFile struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	fd   *os.File
+	size int64
+}
+
+func newRotatingFile(path string, maxBytes int64, maxBackups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f := &rotatingFile{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	fd, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return err
+	}
+	f.fd, f.size = fd, info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	var rotateErr error
+	if f.size > 0 && f.size+int64(len(p)) > f.maxBytes {
+		if err := f.rotate(); err != nil {
+			rotateErr = fmt.Errorf("rotating %s: %w", f.path, err)
+		}
+	}
+	n, err := f.fd.Write(p)
+	f.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+// rotate shifts each backup up by one, dropping the oldest, and starts a new file. When that
+// fails the current file is reopened, so entries are still written to it.
+func (f *rotatingFile) rotate() error {
+	closeErr := f.fd.Close()
+	if err := f.shift(); err != nil {
+		return errors.Join(closeErr, err, f.open())
+	}
+	return errors.Join(closeErr, f.open())
+}
+
+func (f *rotatingFile) shift() error {
+	if f.maxBackups == 0 {
+		return os.Remove(f.path)
+	}
+	for i := f.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(f.path, f.path+".1")
+}
+
+// Close syncs the file to disk and closes it
+func (f *rotatingFile) Close() error {
+	return errors.Join(f.fd.Sync(), f.fd.Close())
+}
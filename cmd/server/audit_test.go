@@ -0,0 +1,299 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAuditConfig(t *testing.T) {
+	cfg, err := readAuditConfig()
+	require.NoError(t, err)
+	require.Empty(t, cfg.destination)
+	require.Equal(t, int64(100*1024*1024), cfg.maxBytes)
+	require.Equal(t, 5, cfg.maxBackups)
+	require.Empty(t, cfg.redact)
+	require.Equal(t, 1.0, cfg.sampleRate)
+
+	logger, err := newAuditLogger(log.NewNopLogger(), cfg)
+	require.NoError(t, err)
+	require.Nil(t, logger)
+
+	t.Setenv("AUDIT_LOG", "stdout")
+	t.Setenv("AUDIT_LOG_MAX_MEGABYTES", "10")
+	t.Setenv("AUDIT_LOG_MAX_BACKUPS", "0")
+	t.Setenv("AUDIT_LOG_REDACT", "userID, name")
+	t.Setenv("AUDIT_LOG_SAMPLE_RATE", "0.25")
+	cfg, err = readAuditConfig()
+	require.NoError(t, err)
+	require.Equal(t, "stdout", cfg.destination)
+	require.Equal(t, int64(10*1024*1024), cfg.maxBytes)
+	require.Equal(t, 0, cfg.maxBackups)
+	require.Equal(t, map[string]bool{"userID": true, "name": true}, cfg.redact)
+	require.Equal(t, 0.25, cfg.sampleRate)
+
+	for env, v := range map[string]string{
+		"AUDIT_LOG_MAX_MEGABYTES": "0",
+		"AUDIT_LOG_MAX_BACKUPS":   "-1",
+		"AUDIT_LOG_REDACT":        "password",
+		"AUDIT_LOG_SAMPLE_RATE":   "1.5",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, v)
+			_, err := readAuditConfig()
+			require.ErrorContains(t, err, "invalid "+env)
+		})
+	}
+}
+
+func readAuditEntries(t *testing.T, path string) []auditEntry {
+	t.Helper()
+
+	fd, err := os.Open(path)
+	require.NoError(t, err)
+	defer fd.Close()
+
+	var out []auditEntry
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		var entry auditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		out = append(out, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return out
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "fed.log")
+	audit, err := newAuditLogger(log.NewNopLogger(), auditConfig{
+		destination: path,
+		maxBytes:    1024 * 1024,
+		redact:      map[string]bool{"userID": true},
+		sampleRate:  1.0,
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	audit.now = func() time.Time { return now }
+
+	s := searcher{audit: audit}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+	require.NoError(t, s.helperLoadFEDWIREFile(t))
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+	search := func(path string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Request-Id", "audit-test")
+		req.Header.Set("X-User-Id", "jane")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	search("/fed/ach/search?routingNumber=044112187&limit=3")
+	search("/fed/wire/search?state=ZZ")
+
+	// Rejected searches are logged with their error
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fed/ach/search", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fed/wire/search?revisedAfter=yesterday", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	require.NoError(t, audit.Close())
+
+	entries := readAuditEntries(t, path)
+	require.Len(t, entries, 4)
+	require.Equal(t, auditEntry{
+		Time:           now,
+		RequestID:      "audit-test",
+		UserID:         "REDACTED",
+		List:           "ach",
+		Parameters:     map[string]string{"routingNumber": "044112187", "limit": "3"},
+		Status:         http.StatusOK,
+		Results:        1,
+		RoutingNumbers: []string{"044112187"},
+	}, entries[0])
+
+	require.Equal(t, "wire", entries[1].List)
+	require.Equal(t, http.StatusOK, entries[1].Status)
+	require.Equal(t, 0, entries[1].Results)
+	require.Empty(t, entries[1].RoutingNumbers)
+
+	require.Equal(t, "ach", entries[2].List)
+	require.Equal(t, http.StatusBadRequest, entries[2].Status)
+	require.Equal(t, errNoSearchParams.Error(), entries[2].Error)
+
+	require.Equal(t, "wire", entries[3].List)
+	require.Equal(t, map[string]string{"revisedAfter": "yesterday"}, entries[3].Parameters)
+	require.Equal(t, http.StatusBadRequest, entries[3].Status)
+	require.Contains(t, entries[3].Error, "revisedAfter")
+}
+
+func TestAuditLog__failedSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLogger(log.NewNopLogger(), auditConfig{
+		destination: path,
+		maxBytes:    1024 * 1024,
+		sampleRate:  1.0,
+	})
+	require.NoError(t, err)
+
+	// Searches time out before finding anything
+	s := searcher{audit: audit, searchTimeout: time.Nanosecond}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fed/ach/search?name=Farmers", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.NoError(t, audit.Close())
+
+	entries := readAuditEntries(t, path)
+	require.Len(t, entries, 1)
+	require.Equal(t, http.StatusServiceUnavailable, entries[0].Status)
+	require.Contains(t, entries[0].Error, "deadline exceeded")
+	require.Empty(t, entries[0].RoutingNumbers)
+}
+
+func TestAuditLog__Changes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLogger(log.NewNopLogger(), auditConfig{
+		destination: path,
+		maxBytes:    1024 * 1024,
+		redact:      map[string]bool{"pageToken": true},
+		sampleRate:  1.0,
+	})
+	require.NoError(t, err)
+
+	router, s := setupChangesRouter(t)
+	s.audit = audit
+
+	resp := readChanges(t, router, "/fed/wire/changes?since=2018-06-01&limit=2")
+	require.Len(t, resp.WIREParticipants, 2)
+	require.NotEmpty(t, resp.NextPageToken)
+	first := readChanges(t, router, "/fed/ach/changes?since=2018-06-01&limit=2")
+	readChanges(t, router, "/fed/ach/changes?since=2018-06-01&limit=2&pageToken="+first.NextPageToken)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fed/ach/changes", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, audit.Close())
+
+	entries := readAuditEntries(t, path)
+	require.Len(t, entries, 4)
+
+	require.Equal(t, "wire", entries[0].List)
+	require.Equal(t, map[string]string{"since": "2018-06-01", "limit": "2"}, entries[0].Parameters)
+	require.Equal(t, http.StatusOK, entries[0].Status)
+	require.Equal(t, 2, entries[0].Results)
+	require.Equal(t, []string{resp.WIREParticipants[0].RoutingNumber, resp.WIREParticipants[1].RoutingNumber}, entries[0].RoutingNumbers)
+
+	require.Equal(t, "ach", entries[1].List)
+	require.Empty(t, entries[1].Parameters["pageToken"])
+	require.Equal(t, "REDACTED", entries[2].Parameters["pageToken"])
+	require.Equal(t, 2, entries[2].Results)
+
+	require.Equal(t, "ach", entries[3].List)
+	require.Equal(t, http.StatusBadRequest, entries[3].Status)
+	require.Contains(t, entries[3].Error, "since")
+	require.Empty(t, entries[3].RoutingNumbers)
+}
+
+func TestAuditLog__Sampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLogger(log.NewNopLogger(), auditConfig{
+		destination: path,
+		maxBytes:    1024 * 1024,
+		redact:      map[string]bool{"routingNumbers": true},
+		sampleRate:  0.5,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/fed/ach/search?name=farmers", nil)
+	for _, sample := range []float64{0.1, 0.7, 0.49, 0.5} {
+		audit.sample = func() float64 { return sample }
+		audit.record(req, "ach", auditResult{status: http.StatusOK, routingNumbers: []string{"044112187", "041215663"}})
+	}
+	require.NoError(t, audit.Close())
+
+	entries := readAuditEntries(t, path)
+	require.Len(t, entries, 2)
+	require.Equal(t, []string{"REDACTED", "REDACTED"}, entries[0].RoutingNumbers)
+	require.Equal(t, 2, entries[0].Results)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	read := func(path string) string {
+		bs, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(bs)
+	}
+	require.Equal(t, "fourth\n", read(path))
+	require.Equal(t, "third\n", read(path+".1"))
+	require.Equal(t, "second\n", read(path+".2"))
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+
+	// Appending to an existing file continues from its size
+	f, err = newRotatingFile(path, 10, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("fifth\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, "fifth\n", read(path))
+	require.True(t, strings.HasPrefix(read(path+".1"), "third"))
+}
+
+func TestRotatingFile__renameError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := newRotatingFile(path, 10, 1)
+	require.NoError(t, err)
+
+	// The first backup can't replace a directory
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocked"), 0755))
+
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+	_, err = f.Write([]byte("second\n"))
+	require.ErrorContains(t, err, "rotating "+path)
+
+	// Entries are still written to the current file
+	_, err = f.Write([]byte("third\n"))
+	require.ErrorContains(t, err, "rotating "+path)
+	require.NoError(t, f.Close())
+
+	bs, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "first\nsecond\nthird\n", string(bs))
+}
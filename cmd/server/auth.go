@@ -0,0 +1,289 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Scopes granted to API keys and JWTs
+const (
+	scopeACHRead  = "ach:read"
+	scopeWireRead = "wire:read"
+	scopeAdmin    = "admin" // needed for the change feeds, and grants every other scope
+)
+
+var knownScopes = []string{scopeACHRead, scopeWireRead, scopeAdmin}
+
+var (
+	errMissingCredentials = errors.New("missing API key or bearer token")
+	errInvalidCredentials = errors.New("invalid API key or bearer token")
+)
+
+// authConfig is read from the AUTH_* environment variables. Authentication is disabled when
+// neither API keys nor a JWKS are configured.
+type authConfig struct {
+	apiKeysFile string
+
+	jwksFile string
+	jwksURL  string
+
+	// issuer and audience are checked in JWTs when set
+	issuer   string
+	audience string
+}
+
+// readAuthConfig reads AUTH_API_KEYS_FILE, AUTH_JWKS_FILE, AUTH_JWKS_URL, AUTH_JWT_ISSUER and AUTH_JWT_AUDIENCE
+func readAuthConfig() (authConfig, error) {
+	cfg := authConfig{
+		apiKeysFile: os.Getenv("AUTH_API_KEYS_FILE"),
+		jwksFile:    os.Getenv("AUTH_JWKS_FILE"),
+		jwksURL:     os.Getenv("AUTH_JWKS_URL"),
+		issuer:      os.Getenv("AUTH_JWT_ISSUER"),
+		audience:    os.Getenv("AUTH_JWT_AUDIENCE"),
+	}
+	if cfg.jwksFile != "" && cfg.jwksURL != "" {
+		return cfg, errors.New("only one of AUTH_JWKS_FILE and AUTH_JWKS_URL can be set")
+	}
+	return cfg, nil
+}
+
+func (cfg authConfig) enabled() bool {
+	return cfg.apiKeysFile != "" || cfg.jwksFile != "" || cfg.jwksURL != ""
+}
+
+// apiKey is an entry of the AUTH_API_KEYS_FILE, which holds a JSON array of them
+type apiKey struct {
+	Key    string   `json:"key"`
+	UserID string   `json:"userID"`
+	Scopes []string `json:"scopes"`
+}
+
+// principal is who made an authenticated request
+type principal struct {
+	userID string
+	scopes []string
+}
+
+// allowed returns true if the principal was granted scope, or admin
+func (p principal) allowed(scope string) bool {
+	return slices.Contains(p.scopes, scope) || slices.Contains(p.scopes, scopeAdmin)
+}
+
+type principalKey struct{}
+
+// principalFromContext returns the principal of an authenticated request
+func principalFromContext(ctx context.Context) (principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(principal)
+	return p, ok
+}
+
+// authenticator checks the API key (X-API-Key header) or JWT (Authorization: Bearer header) of
+// requests and that they were granted the scope of the route.
+type authenticator struct {
+	logger log.Logger
+
+	// apiKeys is keyed by the SHA-256 of each key, so lookups don't leak timing of the keys
+	apiKeys map[[sha256.Size]byte]principal
+
+	jwks    *jwks
+	options []jwt.ParserOption
+}
+
+// newAuthenticator reads the API keys and JWKS of cfg. It returns nil when none are configured,
+// and the middleware of a nil authenticator leaves every route public.
+func newAuthenticator(logger log.Logger, cfg authConfig) (*authenticator, error) {
+	if !cfg.enabled() {
+		return nil, nil
+	}
+
+	a := &authenticator{
+		logger:  logger,
+		apiKeys: make(map[[sha256.Size]byte]principal),
+	}
+	if cfg.apiKeysFile != "" {
+		keys, err := readAPIKeys(cfg.apiKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading AUTH_API_KEYS_FILE: %w", err)
+		}
+		for _, key := range keys {
+			a.apiKeys[sha256.Sum256([]byte(key.Key))] = principal{userID: key.UserID, scopes: key.Scopes}
+		}
+	}
+
+	switch {
+	case cfg.jwksFile != "":
+		a.jwks = newFileJWKS(cfg.jwksFile)
+	case cfg.jwksURL != "":
+		a.jwks = newURLJWKS(cfg.jwksURL)
+	}
+	if a.jwks != nil {
+		if err := a.jwks.refresh(); err != nil {
+			return nil, fmt.Errorf("reading JWKS: %w", err)
+		}
+		a.options = []jwt.ParserOption{
+			jwt.WithValidMethods(jwtSigningMethods),
+			jwt.WithExpirationRequired(),
+		}
+		if cfg.issuer != "" {
+			a.options = append(a.options, jwt.WithIssuer(cfg.issuer))
+		}
+		if cfg.audience != "" {
+			a.options = append(a.options, jwt.WithAudience(cfg.audience))
+		}
+	}
+	return a, nil
+}
+
+// readAPIKeys reads the JSON array of API keys in path
+func readAPIKeys(path string) ([]apiKey, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []apiKey
+	if err := json.Unmarshal(bs, &keys); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		if key.Key == "" || key.UserID == "" {
+			return nil, fmt.Errorf("key %d: key and userID are required", i)
+		}
+		if seen[key.Key] {
+			return nil, fmt.Errorf("key %d (%s): duplicate key", i, key.UserID)
+		}
+		seen[key.Key] = true
+
+		for _, scope := range key.Scopes {
+			if !slices.Contains(knownScopes, scope) {
+				return nil, fmt.Errorf("key %d (%s): unknown scope %q", i, key.UserID, scope)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// requiredScope returns the scope needed to call the route of r, routes without one are public
+func requiredScope(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/fed/ach/changes" || r.URL.Path == "/fed/wire/changes":
+		// Change feeds page through whole directories, so they're kept for trusted clients
+		return scopeAdmin
+	case strings.HasPrefix(r.URL.Path, "/fed/ach/"):
+		return scopeACHRead
+	case strings.HasPrefix(r.URL.Path, "/fed/wire/"):
+		return scopeWireRead
+	}
+	return ""
+}
+
+// authenticate returns the principal of r's API key or bearer token
+func (a *authenticator) authenticate(r *http.Request) (principal, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		p, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+		if !ok {
+			return p, errInvalidCredentials
+		}
+		return p, nil
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return principal{}, errMissingCredentials
+	}
+	if a.jwks == nil {
+		return principal{}, errInvalidCredentials
+	}
+
+	var claims jwtClaims
+	if _, err := jwt.ParseWithClaims(token, &claims, a.jwks.keyfunc, a.options...); err != nil {
+		return principal{}, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+	}
+	if claims.Subject == "" {
+		return principal{}, fmt.Errorf("%w: missing sub claim", errInvalidCredentials)
+	}
+	return principal{userID: claims.Subject, scopes: claims.scopes()}, nil
+}
+
+// middleware rejects requests without credentials granted the route's scope. The X-User-ID
+// header of authenticated requests is replaced with their user so it can be trusted in logs.
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := requiredScope(r)
+		if scope == "" || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, err := a.authenticate(r)
+		if err != nil {
+			a.logger.With(log.Fields{
+				"requestID": log.String(moovhttp.GetRequestID(r)),
+			}).Logf("rejected request to %s: %v", r.URL.Path, err)
+
+			w.Header().Set("WWW-Authenticate", `Bearer realm="fed"`)
+			writeAuthError(w, http.StatusUnauthorized, err)
+			return
+		}
+		if !p.allowed(scope) {
+			a.logger.With(log.Fields{
+				"requestID": log.String(moovhttp.GetRequestID(r)),
+				"userID":    log.String(p.userID),
+			}).Logf("rejected request to %s without %s scope", r.URL.Path, scope)
+
+			writeAuthError(w, http.StatusForbidden, fmt.Errorf("%s scope is required", scope))
+			return
+		}
+
+		r = r.Clone(context.WithValue(r.Context(), principalKey{}, p))
+		// moovhttp.GetUserID prefers X-User over X-User-Id, so a caller's X-User would be logged instead
+		r.Header.Del("X-User")
+		r.Header.Set("X-User-Id", p.userID)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeAuthError(w http.ResponseWriter, code int, err error) {
+	// Don't echo token parsing details back to callers
+	if errors.Is(err, errInvalidCredentials) {
+		err = errInvalidCredentials
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
+
+// jwtClaims are the registered claims and scopes of a JWT. Scopes are read from the space
+// separated "scope" claim (RFC 8693) or the "scp" array.
+type jwtClaims struct {
+	jwt.RegisteredClaims
+
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+}
+
+func (c jwtClaims) scopes() []string {
+	return append(strings.Fields(c.Scope), c.Scp...)
+}
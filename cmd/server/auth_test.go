@@ -0,0 +1,401 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAuthConfig(t *testing.T) {
+	cfg, err := readAuthConfig()
+	require.NoError(t, err)
+	require.False(t, cfg.enabled())
+
+	a, err := newAuthenticator(log.NewNopLogger(), cfg)
+	require.NoError(t, err)
+	require.Nil(t, a)
+
+	t.Setenv("AUTH_JWKS_FILE", "jwks.json")
+	cfg, err = readAuthConfig()
+	require.NoError(t, err)
+	require.True(t, cfg.enabled())
+
+	t.Setenv("AUTH_JWKS_URL", "https://auth.example.com/.well-known/jwks.json")
+	_, err = readAuthConfig()
+	require.ErrorContains(t, err, "only one of AUTH_JWKS_FILE and AUTH_JWKS_URL")
+}
+
+func writeTestFile(t *testing.T, name string, v interface{}) string {
+	t.Helper()
+
+	bs, err := json.Marshal(v)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, bs, 0600))
+	return path
+}
+
+func TestReadAPIKeys(t *testing.T) {
+	keys, err := readAPIKeys(writeTestFile(t, "keys.json", []apiKey{
+		{Key: "secret", UserID: "batch", Scopes: []string{scopeACHRead}},
+	}))
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	cases := map[string][]apiKey{
+		"key and userID are required": {{Key: "secret"}},
+		"duplicate key":               {{Key: "a", UserID: "x"}, {Key: "a", UserID: "y"}},
+		`unknown scope "ach:write"`:   {{Key: "a", UserID: "x", Scopes: []string{"ach:write"}}},
+	}
+	for msg, keys := range cases {
+		_, err := readAPIKeys(writeTestFile(t, "keys.json", keys))
+		require.ErrorContains(t, err, msg)
+	}
+}
+
+func jwkInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func TestParseJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys, err := parseJWKS([]byte(`{"keys":[
+		{"kty":"EC","kid":"ec","crv":"P-256","x":"` + jwkInt(ecKey.X) + `","y":"` + jwkInt(ecKey.Y) + `"},
+		{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, &ecKey.PublicKey, keys["ec"])
+
+	_, err = parseJWKS([]byte(`{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"AQAB","y":"AQAB"}]}`))
+	require.ErrorContains(t, err, "point isn't on the curve")
+
+	_, err = parseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"hmac","k":"c2VjcmV0"}]}`))
+	require.ErrorContains(t, err, `unsupported key type "oct"`)
+
+	_, err = parseJWKS([]byte(`{"keys":[]}`))
+	require.ErrorContains(t, err, "no signing keys")
+}
+
+type authTest struct {
+	router *mux.Router
+	key    *rsa.PrivateKey
+}
+
+func setupAuthTest(t *testing.T) *authTest {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksPath := writeTestFile(t, "jwks.json", map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   jwkInt(key.N),
+			"e":   jwkInt(big.NewInt(int64(key.E))),
+		}},
+	})
+	keysPath := writeTestFile(t, "keys.json", []apiKey{
+		{Key: "ach-key", UserID: "batch-job", Scopes: []string{scopeACHRead}},
+		{Key: "ops-key", UserID: "ops", Scopes: []string{scopeACHRead, scopeWireRead}},
+		{Key: "admin-key", UserID: "admin", Scopes: []string{scopeAdmin}},
+	})
+
+	a, err := newAuthenticator(log.NewNopLogger(), authConfig{
+		apiKeysFile: keysPath,
+		jwksFile:    jwksPath,
+		issuer:      "https://auth.example.com",
+		audience:    "fed",
+	})
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(a.middleware)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(moovhttp.GetUserID(r)))
+	}
+	router.Methods("GET").Path("/fed/ach/search").HandlerFunc(handler)
+	router.Methods("GET").Path("/fed/wire/search").HandlerFunc(handler)
+	router.Methods("GET").Path("/fed/ach/changes").HandlerFunc(handler)
+	router.Methods("GET").Path("/fed/wire/changes").HandlerFunc(handler)
+	router.Methods("GET").Path("/ping").HandlerFunc(handler)
+
+	return &authTest{router: router, key: key}
+}
+
+func (at *authTest) get(t *testing.T, path string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	at.router.ServeHTTP(w, req)
+	return w
+}
+
+func (at *authTest) token(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuth__APIKeys(t *testing.T) {
+	at := setupAuthTest(t)
+
+	// Public routes don't need credentials
+	w := at.get(t, "/ping", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = at.get(t, "/fed/ach/search", map[string]string{"X-User-Id": "mallory"})
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), errMissingCredentials.Error())
+	require.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	w = at.get(t, "/fed/ach/search", map[string]string{"X-API-Key": "wrong"})
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The user ID comes from the key, not the caller's headers
+	w = at.get(t, "/fed/ach/search", map[string]string{"X-API-Key": "ach-key", "X-User-Id": "mallory", "X-User": "mallory"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "batch-job", w.Body.String())
+
+	w = at.get(t, "/fed/wire/search", map[string]string{"X-API-Key": "ach-key"})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), "wire:read scope is required")
+
+	// Keys can be granted several scopes
+	w = at.get(t, "/fed/wire/search", map[string]string{"X-API-Key": "ops-key"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "ops", w.Body.String())
+}
+
+func TestAuth__AdminScope(t *testing.T) {
+	at := setupAuthTest(t)
+
+	// Change feeds need admin, even with the list's read scope
+	for _, path := range []string{"/fed/ach/changes", "/fed/wire/changes"} {
+		w := at.get(t, path, map[string]string{"X-API-Key": "ops-key"})
+		require.Equal(t, http.StatusForbidden, w.Code, path)
+		require.Contains(t, w.Body.String(), "admin scope is required")
+
+		w = at.get(t, path, map[string]string{"X-API-Key": "admin-key"})
+		require.Equal(t, http.StatusOK, w.Code, path)
+		require.Equal(t, "admin", w.Body.String())
+	}
+
+	// admin grants every other scope
+	w := at.get(t, "/fed/ach/search", map[string]string{"X-API-Key": "admin-key"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	token := at.token(t, jwt.SigningMethodRS256, at.key, jwt.MapClaims{
+		"sub":   "jane",
+		"iss":   "https://auth.example.com",
+		"aud":   "fed",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "ach:read wire:read",
+	})
+	w = at.get(t, "/fed/ach/changes", map[string]string{"Authorization": "Bearer " + token})
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuth__JWT(t *testing.T) {
+	at := setupAuthTest(t)
+
+	claims := func(scope string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "jane",
+			"iss":   "https://auth.example.com",
+			"aud":   "fed",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": scope,
+		}
+	}
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+
+	token := at.token(t, jwt.SigningMethodRS256, at.key, claims("ach:read wire:read"))
+	w := at.get(t, "/fed/wire/search", bearer(token))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "jane", w.Body.String())
+
+	token = at.token(t, jwt.SigningMethodRS256, at.key, claims("ach:read"))
+	w = at.get(t, "/fed/wire/search", bearer(token))
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// scp arrays are read too
+	c := claims("")
+	c["scp"] = []string{"wire:read"}
+	w = at.get(t, "/fed/wire/search", bearer(at.token(t, jwt.SigningMethodRS256, at.key, c)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Expired tokens, other issuers and audiences, other keys and HMAC tokens are rejected
+	expired := claims("ach:read")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	issuer := claims("ach:read")
+	issuer["iss"] = "https://evil.example.com"
+	audience := claims("ach:read")
+	audience["aud"] = "other"
+	noExpiry := claims("ach:read")
+	delete(noExpiry, "exp")
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"expired":   at.token(t, jwt.SigningMethodRS256, at.key, expired),
+		"issuer":    at.token(t, jwt.SigningMethodRS256, at.key, issuer),
+		"audience":  at.token(t, jwt.SigningMethodRS256, at.key, audience),
+		"no expiry": at.token(t, jwt.SigningMethodRS256, at.key, noExpiry),
+		"other key": at.token(t, jwt.SigningMethodRS256, otherKey, claims("ach:read")),
+		"hmac":      at.token(t, jwt.SigningMethodHS256, []byte("secret"), claims("ach:read")),
+		"garbage":   "not.a.token",
+	} {
+		w := at.get(t, "/fed/ach/search", bearer(token))
+		require.Equal(t, http.StatusUnauthorized, w.Code, name)
+		require.JSONEq(t, `{"error":"invalid API key or bearer token"}`, w.Body.String(), name)
+	}
+}
+
+func TestJWKS__URLRotation(t *testing.T) {
+	first, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	second, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	current, kid := first, "first"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "EC", "kid": kid, "crv": "P-256", "x": jwkInt(current.X), "y": jwkInt(current.Y),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	keys := newURLJWKS(server.URL)
+	require.NoError(t, keys.refresh())
+
+	token := func(kid string) *jwt.Token {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "jane"})
+		token.Header["kid"] = kid
+		return token
+	}
+	found, err := keys.keyfunc(token("first"))
+	require.NoError(t, err)
+	require.Equal(t, &first.PublicKey, found)
+
+	// Unknown keys are only looked up once per refresh interval
+	current, kid = second, "second"
+	_, err = keys.keyfunc(token("second"))
+	require.ErrorIs(t, err, errUnknownJWK)
+
+	prev := jwksRefreshInterval
+	jwksRefreshInterval = 0
+	t.Cleanup(func() { jwksRefreshInterval = prev })
+
+	found, err = keys.keyfunc(token("second"))
+	require.NoError(t, err)
+	require.Equal(t, &second.PublicKey, found)
+}
+
+func TestJWKS__SlowURL(t *testing.T) {
+	first, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reads after the first one hang until the test releases them
+		if requests.Add(1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "EC", "kid": "first", "crv": "P-256", "x": jwkInt(first.X), "y": jwkInt(first.Y),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	// Hanging reads are released before the server is closed
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	t.Cleanup(unblock)
+
+	keys := newURLJWKS(server.URL)
+	require.NoError(t, keys.refresh())
+
+	prev := jwksRefreshInterval
+	jwksRefreshInterval = 0
+	t.Cleanup(func() { jwksRefreshInterval = prev })
+
+	token := func(kid string) *jwt.Token {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "jane"})
+		token.Header["kid"] = kid
+		return token
+	}
+
+	// Tokens with unknown keys wait on one read of the JWKS
+	unknown := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := keys.keyfunc(token("unknown"))
+			unknown <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return requests.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// Known keys are found while the JWKS is read
+	found := make(chan interface{}, 1)
+	go func() {
+		key, _ := keys.keyfunc(token("first"))
+		found <- key
+	}()
+	select {
+	case key := <-found:
+		require.Equal(t, &first.PublicKey, key)
+	case <-time.After(5 * time.Second):
+		t.Fatal("known key blocked by JWKS read")
+	}
+
+	unblock()
+	for i := 0; i < 2; i++ {
+		require.ErrorIs(t, <-unknown, errUnknownJWK)
+	}
+	require.Equal(t, int32(2), requests.Load())
+}
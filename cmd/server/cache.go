@@ -0,0 +1,211 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	searchCacheHits = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "search_cache_hits_total",
+		Help: "Counter of searches answered from the search cache",
+	}, []string{"list"})
+
+	searchCacheMisses = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "search_cache_misses_total",
+		Help: "Counter of searches not found in the search cache",
+	}, []string{"list"})
+
+	defaultSearchCacheSize = 1000
+	defaultSearchCacheTTL  = 5 * time.Minute
+)
+
+// searchCache holds the results of recent searches of one list, keyed by their parameters and limit.
+// The least recently used search is evicted once maxEntries are held, and results expire after ttl.
+type searchCache[V any] struct {
+	listName   string // "ach" or "wire", used in metrics
+	maxEntries int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is the most recently used
+	gen     uint64     // incremented by flush
+
+	now func() time.Time
+}
+
+type searchCacheEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// newSearchCache returns nil when SEARCH_CACHE_SIZE is 0. Every search of a nil cache misses and
+// results added to it are dropped.
+func newSearchCache[V any](listName string, maxEntries int, ttl time.Duration) *searchCache[V] {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &searchCache[V]{
+		listName:   listName,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// generation returns a value which changes whenever the cache is flushed. Read it before searching
+// and pass it to add so results from replaced data are never cached.
+func (c *searchCache[V]) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+func (c *searchCache[V]) get(key string) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elm, ok := c.entries[key]
+	if !ok {
+		searchCacheMisses.With("list", c.listName).Add(1)
+		return zero, false
+	}
+	entry := elm.Value.(*searchCacheEntry[V])
+	if c.ttl > 0 && c.now().After(entry.expires) {
+		c.order.Remove(elm)
+		delete(c.entries, key)
+		searchCacheMisses.With("list", c.listName).Add(1)
+		return zero, false
+	}
+	c.order.MoveToFront(elm)
+	searchCacheHits.With("list", c.listName).Add(1)
+	return entry.value, true
+}
+
+func (c *searchCache[V]) add(key string, generation uint64, value V) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.gen {
+		return // the data changed during the search
+	}
+
+	entry := &searchCacheEntry[V]{key: key, value: value, expires: c.now().Add(c.ttl)}
+	if elm, ok := c.entries[key]; ok {
+		elm.Value = entry
+		c.order.MoveToFront(elm)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*searchCacheEntry[V]).key)
+	}
+}
+
+// flush removes every entry, it's called when the data being searched is replaced
+func (c *searchCache[V]) flush() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *searchCache[V]) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// readSearchCacheConfig parses the SEARCH_CACHE_SIZE and SEARCH_CACHE_TTL values. Empty values
+// are the defaults and a size of zero disables caching.
+func readSearchCacheConfig(size, ttl string) (int, time.Duration, error) {
+	maxEntries, dur := defaultSearchCacheSize, defaultSearchCacheTTL
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid search cache size: %q", size)
+		}
+		maxEntries = n
+	}
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d < 0 {
+			return 0, 0, fmt.Errorf("invalid search cache ttl: %q", ttl)
+		}
+		dur = d
+	}
+	return maxEntries, dur, nil
+}
+
+// searchCacheControl reads the Cache-Control of a search. Requests with no-cache skip reading the
+// cache but their results still replace any cached ones, and no-store requests don't use it at all.
+func searchCacheControl(r *http.Request) (read, write bool) {
+	read, write = true, true
+	for _, v := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "no-cache":
+			read = false
+		case "no-store":
+			read, write = false, false
+		}
+	}
+	return read, write
+}
+
+// cacheKey returns the search cache key for req returning up to limit results. Fields are JSON
+// encoded so values holding separators can't be mistaken for other fields.
+func (req fedSearchRequest) cacheKey(limit int) string {
+	key, _ := json.Marshal([]string{
+		req.Name, req.RoutingNumber, req.City, req.State, req.PostalCode,
+		formatCacheTime(req.RevisedAfter), formatCacheTime(req.RevisedBefore),
+		strconv.Itoa(limit),
+	})
+	return string(key)
+}
+
+func formatCacheTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchCache(t *testing.T) {
+	cache := newSearchCache[[]string]("test", 2, time.Minute)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, found := cache.get("a")
+	require.False(t, found)
+
+	gen := cache.generation()
+	cache.add("a", gen, []string{"a"})
+	cache.add("b", gen, []string{"b"})
+
+	v, found := cache.get("a")
+	require.True(t, found)
+	require.Equal(t, []string{"a"}, v)
+
+	// "b" is the least recently used
+	cache.add("c", gen, []string{"c"})
+	require.Equal(t, 2, cache.len())
+	_, found = cache.get("b")
+	require.False(t, found)
+
+	// Entries expire
+	now = now.Add(2 * time.Minute)
+	_, found = cache.get("a")
+	require.False(t, found)
+
+	// Results from before a flush are dropped
+	cache.flush()
+	require.Equal(t, 0, cache.len())
+	cache.add("d", gen, []string{"d"})
+	require.Equal(t, 0, cache.len())
+	cache.add("d", cache.generation(), []string{"d"})
+	require.Equal(t, 1, cache.len())
+}
+
+func TestSearchCache__Disabled(t *testing.T) {
+	cache := newSearchCache[[]string]("test", 0, time.Minute)
+	require.Nil(t, cache)
+
+	cache.add("a", cache.generation(), []string{"a"})
+	_, found := cache.get("a")
+	require.False(t, found)
+	cache.flush()
+	require.Equal(t, 0, cache.len())
+}
+
+func TestSearchCache__readConfig(t *testing.T) {
+	size, ttl, err := readSearchCacheConfig("", "")
+	require.NoError(t, err)
+	require.Equal(t, defaultSearchCacheSize, size)
+	require.Equal(t, defaultSearchCacheTTL, ttl)
+
+	size, ttl, err = readSearchCacheConfig("0", "30s")
+	require.NoError(t, err)
+	require.Equal(t, 0, size)
+	require.Equal(t, 30*time.Second, ttl)
+
+	_, _, err = readSearchCacheConfig("-1", "")
+	require.Error(t, err)
+	_, _, err = readSearchCacheConfig("", "later")
+	require.Error(t, err)
+}
+
+func TestSearchCache__control(t *testing.T) {
+	cases := map[string][2]bool{
+		"":                    {true, true},
+		"max-age=0, No-Cache": {false, true},
+		"no-store":            {false, false},
+		"no-cache, no-store":  {false, false},
+	}
+	for v, expected := range cases {
+		req := httptest.NewRequest("GET", "/fed/ach/search?name=chase", nil)
+		req.Header.Set("Cache-Control", v)
+		read, write := searchCacheControl(req)
+		require.Equal(t, expected, [2]bool{read, write}, v)
+	}
+}
+
+func TestSearchCache__key(t *testing.T) {
+	a := fedSearchRequest{Name: "X|0123"}
+	b := fedSearchRequest{Name: "X", RoutingNumber: "0123"}
+	require.NotEqual(t, a.cacheKey(10), b.cacheKey(10))
+	require.NotEqual(t, a.cacheKey(10), a.cacheKey(100))
+	require.Equal(t, b.cacheKey(10), fedSearchRequest{Name: "X", RoutingNumber: "0123"}.cacheKey(10))
+}
+
+func TestSearchCache__Handlers(t *testing.T) {
+	s := searcher{
+		achCache:  newSearchCache[[]*fed.ACHParticipant]("ach", 10, time.Minute),
+		wireCache: newSearchCache[[]*fed.WIREParticipant]("wire", 10, time.Minute),
+	}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+	require.NoError(t, s.helperLoadFEDWIREFile(t))
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+
+	search := func(path string, header http.Header) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		router.ServeHTTP(w, req)
+		w.Flush()
+
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	first := search("/fed/ach/search?name=Farmers&limit=5", nil)
+	require.Equal(t, 1, s.achCache.len())
+
+	// Requests are normalized before caching
+	require.Equal(t, first, search("/fed/ach/search?name=+farmers&limit=5", nil))
+	require.Equal(t, 1, s.achCache.len())
+
+	// Different limits are different searches
+	search("/fed/ach/search?name=Farmers&limit=6", nil)
+	require.Equal(t, 2, s.achCache.len())
+
+	search("/fed/wire/search?state=IA", http.Header{"Cache-Control": []string{"no-store"}})
+	require.Equal(t, 0, s.wireCache.len())
+
+	search("/fed/wire/search?state=IA", http.Header{"Cache-Control": []string{"no-cache"}})
+	require.Equal(t, 1, s.wireCache.len())
+
+	// Reloading data flushes the cache
+	fd, err := os.Open(filepath.Join("..", "..", "data", "FedACHdir.txt"))
+	require.NoError(t, err)
+	require.NoError(t, s.readFEDACHData(fd))
+	require.Equal(t, 0, s.achCache.len())
+	require.Equal(t, 1, s.wireCache.len())
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+)
+
+var (
+	errMissingSince     = errors.New("missing since parameter")
+	errInvalidPageToken = errors.New("invalid pageToken")
+)
+
+func addChangesRoutes(logger log.Logger, r *mux.Router, searcher *searcher) {
+	r.Methods("GET").Path("/fed/ach/changes").HandlerFunc(achChanges(logger, searcher))
+	r.Methods("GET").Path("/fed/wire/changes").HandlerFunc(wireChanges(logger, searcher))
+}
+
+// changesResponse is a page of participants revised since a given date
+type changesResponse struct {
+	ACHParticipants  []*fed.ACHParticipant  `json:"achParticipants,omitempty"`
+	WIREParticipants []*fed.WIREParticipant `json:"wireParticipants,omitempty"`
+
+	// NextPageToken is set when more participants are available and is passed as pageToken to read them
+	NextPageToken string `json:"nextPageToken,omitempty"`
+
+	Stats *ListStats `json:"stats"`
+}
+
+func (resp *changesResponse) described() interface{} {
+	return struct {
+		*changesResponse
+		ACHParticipants  []fed.DescribedACHParticipant  `json:"achParticipants,omitempty"`
+		WIREParticipants []fed.DescribedWIREParticipant `json:"wireParticipants,omitempty"`
+	}{
+		changesResponse:  resp,
+		ACHParticipants:  fed.DescribeACHParticipants(resp.ACHParticipants),
+		WIREParticipants: fed.DescribeWIREParticipants(resp.WIREParticipants),
+	}
+}
+
+// changesRequest contains the url parameters for reading changed participants
+type changesRequest struct {
+	Since time.Time
+	Limit int
+
+	// after is decoded from the pageToken and is the last participant of the previous page
+	after *changesCursor
+}
+
+// changesCursor is the position in a list of participants sorted by revision date and routing number
+type changesCursor struct {
+	RevisedAt     time.Time
+	RoutingNumber string
+}
+
+func (c changesCursor) encode() string {
+	value := fmt.Sprintf("%s|%s", c.RevisedAt.Format(time.RFC3339), c.RoutingNumber)
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeChangesCursor(token string) (*changesCursor, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidPageToken
+	}
+	revisedAt, routingNumber, found := strings.Cut(string(bs), "|")
+	if !found || routingNumber == "" {
+		return nil, errInvalidPageToken
+	}
+	t, err := time.Parse(time.RFC3339, revisedAt)
+	if err != nil {
+		return nil, errInvalidPageToken
+	}
+	return &changesCursor{RevisedAt: t, RoutingNumber: routingNumber}, nil
+}
+
+// less returns true when a participant revised at t with routingNumber is sorted before the cursor
+func (c *changesCursor) less(t time.Time, routingNumber string) bool {
+	if !t.Equal(c.RevisedAt) {
+		return t.Before(c.RevisedAt)
+	}
+	return routingNumber <= c.RoutingNumber
+}
+
+func readChangesRequest(r *http.Request) (changesRequest, error) {
+	req := changesRequest{
+		Limit: extractSearchLimit(r),
+	}
+
+	var err error
+	if r.URL.Query().Get("since") == "" {
+		return req, errMissingSince
+	}
+	req.Since, err = readQueryDate(r.URL, "since")
+	if err != nil {
+		return req, err
+	}
+	if token := r.URL.Query().Get("pageToken"); token != "" {
+		req.after, err = decodeChangesCursor(token)
+		if err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// ACHChanges returns a page of ACH Participants revised on or after req.Since
+func (s *searcher) ACHChanges(req changesRequest) ([]*fed.ACHParticipant, *changesCursor) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return changesPage(s.ACHDictionary.RevisedSince(req.Since), req, func(p *fed.ACHParticipant) changesCursor {
+		return changesCursor{RevisedAt: *p.RevisedAt, RoutingNumber: p.RoutingNumber}
+	})
+}
+
+// WIREChanges returns a page of WIRE Participants revised on or after req.Since
+func (s *searcher) WIREChanges(req changesRequest) ([]*fed.WIREParticipant, *changesCursor) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return changesPage(s.WIREDictionary.RevisedSince(req.Since), req, func(p *fed.WIREParticipant) changesCursor {
+		return changesCursor{RevisedAt: *p.RevisedAt, RoutingNumber: p.RoutingNumber}
+	})
+}
+
+// changesPage returns the participants of all, which is sorted by revision date and routing number, after
+// req's cursor up to its limit. The cursor of the page's last participant is returned when more follow.
+func changesPage[T any](all []T, req changesRequest, cursor func(T) changesCursor) ([]T, *changesCursor) {
+	start := 0
+	if req.after != nil {
+		start = sort.Search(len(all), func(i int) bool {
+			c := cursor(all[i])
+			return !req.after.less(c.RevisedAt, c.RoutingNumber)
+		})
+	}
+	end := min(start+req.Limit, len(all))
+
+	var next *changesCursor
+	if end < len(all) && end > start {
+		last := cursor(all[end-1])
+		next = &last
+	}
+	return all[start:end], next
+}
+
+// achChanges returns ACH participants revised since the requested date
+func achChanges(logger log.Logger, searcher *searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: wrapResponseWriter(logger, w, r)}
+		w = sw
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		// Change feeds are audited like searches
+		var audit auditResult
+		defer func() {
+			audit.status = sw.code
+			searcher.audit.record(r, "ach", audit)
+		}()
+
+		req, err := readChangesRequest(r)
+		if err != nil {
+			audit.err = err
+			moovhttp.Problem(w, err)
+			return
+		}
+
+		participants, next := searcher.ACHChanges(req)
+		audit.routingNumbers = achRoutingNumbers(participants)
+		resp := &changesResponse{
+			ACHParticipants: participants,
+			Stats:           searcher.ACHStats(),
+		}
+		if next != nil {
+			resp.NextPageToken = next.encode()
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(searcher.response(resp))
+	}
+}
+
+// wireChanges returns Wire participants revised since the requested date
+func wireChanges(logger log.Logger, searcher *searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: wrapResponseWriter(logger, w, r)}
+		w = sw
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		// Change feeds are audited like searches
+		var audit auditResult
+		defer func() {
+			audit.status = sw.code
+			searcher.audit.record(r, "wire", audit)
+		}()
+
+		req, err := readChangesRequest(r)
+		if err != nil {
+			audit.err = err
+			moovhttp.Problem(w, err)
+			return
+		}
+
+		participants, next := searcher.WIREChanges(req)
+		audit.routingNumbers = wireRoutingNumbers(participants)
+		resp := &changesResponse{
+			WIREParticipants: participants,
+			Stats:            searcher.WIREStats(),
+		}
+		if next != nil {
+			resp.NextPageToken = next.encode()
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(searcher.response(resp))
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func setupChangesRouter(t *testing.T) (*mux.Router, *searcher) {
+	t.Helper()
+
+	s := &searcher{}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+	require.NoError(t, s.helperLoadFEDWIREFile(t))
+	s.precompute()
+
+	router := mux.NewRouter()
+	addChangesRoutes(log.NewNopLogger(), router, s)
+	return router, s
+}
+
+func readChanges(t *testing.T, router *mux.Router, path string) changesResponse {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp changesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func TestChanges__ACH(t *testing.T) {
+	router, s := setupChangesRouter(t)
+
+	since := time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC)
+	expected := s.ACHDictionary.RevisedSince(since)
+	require.Greater(t, len(expected), 50)
+
+	// Follow every page and compare against the full list
+	var routingNumbers []string
+	path := "/fed/ach/changes?since=2018-06-01&limit=25"
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 1000)
+
+		resp := readChanges(t, router, path)
+		require.Empty(t, resp.WIREParticipants)
+		require.LessOrEqual(t, len(resp.ACHParticipants), 25)
+		require.Equal(t, len(s.ACHDictionary.ACHParticipants), resp.Stats.Records)
+
+		for _, p := range resp.ACHParticipants {
+			require.False(t, p.RevisedAt.Before(since))
+			routingNumbers = append(routingNumbers, p.RoutingNumber)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		path = "/fed/ach/changes?since=2018-06-01&limit=25&pageToken=" + resp.NextPageToken
+	}
+
+	require.Len(t, routingNumbers, len(expected))
+	for i := range expected {
+		require.Equal(t, expected[i].RoutingNumber, routingNumbers[i])
+	}
+}
+
+func TestChanges__Wire(t *testing.T) {
+	router, s := setupChangesRouter(t)
+
+	expected := s.WIREDictionary.RevisedSince(time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC))
+	require.Greater(t, len(expected), softResultsLimit)
+
+	resp := readChanges(t, router, "/fed/wire/changes?since=2018-01-01")
+	require.Empty(t, resp.ACHParticipants)
+	require.Len(t, resp.WIREParticipants, softResultsLimit)
+	require.NotEmpty(t, resp.NextPageToken)
+	require.Equal(t, expected[0].RoutingNumber, resp.WIREParticipants[0].RoutingNumber)
+
+	for i := 1; i < len(resp.WIREParticipants); i++ {
+		require.False(t, resp.WIREParticipants[i].RevisedAt.Before(*resp.WIREParticipants[i-1].RevisedAt))
+	}
+
+	// Nothing has changed in the future
+	resp = readChanges(t, router, "/fed/wire/changes?since=2100-01-01")
+	require.Empty(t, resp.WIREParticipants)
+	require.Empty(t, resp.NextPageToken)
+}
+
+func TestChanges__Errors(t *testing.T) {
+	router, _ := setupChangesRouter(t)
+
+	for _, path := range []string{
+		"/fed/ach/changes",
+		"/fed/ach/changes?since=June",
+		"/fed/wire/changes?since=2018-01-01&pageToken=invalid",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		w.Flush()
+		require.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestChanges__cursor(t *testing.T) {
+	cursor := changesCursor{
+		RevisedAt:     time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC),
+		RoutingNumber: "011000015",
+	}
+	decoded, err := decodeChangesCursor(cursor.encode())
+	require.NoError(t, err)
+	require.Equal(t, cursor.RoutingNumber, decoded.RoutingNumber)
+	require.True(t, cursor.RevisedAt.Equal(decoded.RevisedAt))
+
+	require.True(t, decoded.less(cursor.RevisedAt, "011000015"))
+	require.False(t, decoded.less(cursor.RevisedAt, "011000028"))
+	require.True(t, decoded.less(cursor.RevisedAt.Add(-time.Hour), "999999999"))
+}
+
+func TestChanges__CodeDescriptions(t *testing.T) {
+	router, s := setupChangesRouter(t)
+	s.describeCodes = true
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fed/wire/changes?since=2018-06-01&limit=1", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"fundsTransferStatus":{"code":"Y","description":"Eligible"}`)
+	require.Contains(t, w.Body.String(), `"nextPageToken":`)
+
+	// Described codes decode back into the raw codes
+	var resp changesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.WIREParticipants, 1)
+	require.True(t, resp.WIREParticipants[0].IsWireEligible())
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/base/strx"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dataAgeSeconds = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "fed_data_age_seconds",
+		Help: "Seconds since the latest record of each directory was revised",
+	}, []string{"list"})
+
+	recordsTotal = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "fed_records_total",
+		Help: "Records in each directory currently searched",
+	}, []string{"list"})
+
+	freshnessCheckInterval = time.Minute
+)
+
+// freshnessConfig is the oldest data accepted for each directory
+type freshnessConfig struct {
+	// maxAge is keyed by list name, directories without one are never stale
+	maxAge map[string]time.Duration
+
+	// failReadiness fails the admin server's readiness checks while data is stale
+	failReadiness bool
+}
+
+// readFreshnessConfig reads FEDACH_MAX_DATA_AGE, FEDWIRE_MAX_DATA_AGE and DATA_STALE_FAIL_READINESS
+func readFreshnessConfig() (freshnessConfig, error) {
+	cfg := freshnessConfig{
+		maxAge:        make(map[string]time.Duration),
+		failReadiness: strx.Yes(os.Getenv("DATA_STALE_FAIL_READINESS")),
+	}
+	for listName, env := range map[string]string{"fedach": "FEDACH_MAX_DATA_AGE", "fedwire": "FEDWIRE_MAX_DATA_AGE"} {
+		if v := os.Getenv(env); v != "" {
+			age, err := parseMaxAge(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", env, err)
+			}
+			cfg.maxAge[listName] = age
+		}
+	}
+	return cfg, nil
+}
+
+// parseMaxAge reads a Go duration (e.g. 720h) or a number of days (e.g. 30d)
+func parseMaxAge(v string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%q isn't a number of days", v)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(v); err != nil {
+			return 0, err
+		}
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("%q isn't positive", v)
+	}
+	return age, nil
+}
+
+// freshnessMonitor reports how old each directory is and warns when it's older than allowed
+type freshnessMonitor struct {
+	searcher *searcher
+	cfg      freshnessConfig
+	logger   log.Logger
+
+	mu    sync.Mutex
+	stale map[string]bool // keyed by list name
+
+	now func() time.Time
+}
+
+func newFreshnessMonitor(logger log.Logger, s *searcher, cfg freshnessConfig) *freshnessMonitor {
+	return &freshnessMonitor{
+		searcher: s,
+		cfg:      cfg,
+		logger:   logger,
+		stale:    make(map[string]bool),
+		now:      time.Now,
+	}
+}
+
+// stats returns the stats of each loaded directory, keyed by list name
+func (m *freshnessMonitor) stats() map[string]ListStats {
+	m.searcher.RLock()
+	defer m.searcher.RUnlock()
+
+	out := make(map[string]ListStats)
+	if m.searcher.ACHDictionary != nil {
+		out["fedach"] = m.searcher.achStats
+	}
+	if m.searcher.WIREDictionary != nil {
+		out["fedwire"] = m.searcher.wireStats
+	}
+	return out
+}
+
+// staleErr returns an error when the directory's latest record is older than allowed
+func (m *freshnessMonitor) staleErr(listName string, stats ListStats) error {
+	maxAge := m.cfg.maxAge[listName]
+	if maxAge <= 0 || stats.Latest.IsZero() {
+		return nil
+	}
+	if age := m.now().Sub(stats.Latest); age > maxAge {
+		return fmt.Errorf("%s data was last revised %s, %s ago which is older than the %s allowed",
+			listName, stats.Latest.Format("2006-01-02"), age.Truncate(time.Hour), maxAge)
+	}
+	return nil
+}
+
+// check updates the freshness metrics and logs when a directory becomes stale or fresh again
+func (m *freshnessMonitor) check() {
+	for listName, stats := range m.stats() {
+		recordsTotal.With("list", listName).Set(float64(stats.Records))
+		if !stats.Latest.IsZero() {
+			dataAgeSeconds.With("list", listName).Set(m.now().Sub(stats.Latest).Seconds())
+		}
+
+		err := m.staleErr(listName, stats)
+
+		m.mu.Lock()
+		wasStale := m.stale[listName]
+		m.stale[listName] = err != nil
+		m.mu.Unlock()
+
+		switch {
+		case err != nil && !wasStale:
+			m.logger.Warn().With(log.Fields{
+				"list":    log.String(listName),
+				"records": log.Int(stats.Records),
+				"latest":  log.Time(stats.Latest),
+			}).Logf("stale data: %v", err)
+		case err == nil && wasStale:
+			m.logger.Info().Logf("%s data is no longer stale", listName)
+		}
+	}
+}
+
+// run checks the data every interval until done is closed
+func (m *freshnessMonitor) run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.check()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+// readinessCheck returns an error while listName is stale. Directories which haven't been
+// loaded yet aren't stale.
+func (m *freshnessMonitor) readinessCheck(listName string) func() error {
+	return func() error {
+		stats, loaded := m.stats()[listName]
+		if !loaded {
+			return nil
+		}
+		return m.staleErr(listName, stats)
+	}
+}
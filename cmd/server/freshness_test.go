@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFreshnessConfig(t *testing.T) {
+	cfg, err := readFreshnessConfig()
+	require.NoError(t, err)
+	require.Empty(t, cfg.maxAge)
+	require.False(t, cfg.failReadiness)
+
+	t.Setenv("FEDACH_MAX_DATA_AGE", "30d")
+	t.Setenv("FEDWIRE_MAX_DATA_AGE", "36h")
+	t.Setenv("DATA_STALE_FAIL_READINESS", "yes")
+	cfg, err = readFreshnessConfig()
+	require.NoError(t, err)
+	require.Equal(t, 30*24*time.Hour, cfg.maxAge["fedach"])
+	require.Equal(t, 36*time.Hour, cfg.maxAge["fedwire"])
+	require.True(t, cfg.failReadiness)
+
+	for _, v := range []string{"soon", "-1h", "0d", "xd"} {
+		t.Setenv("FEDWIRE_MAX_DATA_AGE", v)
+		_, err = readFreshnessConfig()
+		require.ErrorContains(t, err, "invalid FEDWIRE_MAX_DATA_AGE", v)
+	}
+}
+
+// gaugeValue reads a gauge with a list label from the default Prometheus registry
+func gaugeValue(t *testing.T, name, listName string) float64 {
+	t.Helper()
+
+	families, err := stdprometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "list" && label.GetValue() == listName {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("%s{list=%q} not found", name, listName)
+	return 0
+}
+
+func TestFreshnessMonitor(t *testing.T) {
+	s := &searcher{
+		ACHDictionary:  fed.NewACHDictionary(),
+		WIREDictionary: fed.NewWIREDictionary(),
+	}
+	latest := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	s.achStats = ListStats{Records: 18000, Latest: latest}
+	s.wireStats = ListStats{Records: 7600, Latest: latest}
+
+	buf, logger := log.NewBufferLogger()
+	m := newFreshnessMonitor(logger, s, freshnessConfig{
+		maxAge: map[string]time.Duration{"fedach": 72 * time.Hour},
+	})
+	m.now = func() time.Time { return latest.Add(48 * time.Hour) }
+
+	m.check()
+	require.Equal(t, float64(18000), gaugeValue(t, "fed_records_total", "fedach"))
+	require.Equal(t, float64(7600), gaugeValue(t, "fed_records_total", "fedwire"))
+	require.Equal(t, float64(48*60*60), gaugeValue(t, "fed_data_age_seconds", "fedach"))
+	require.NotContains(t, buf.String(), "stale")
+	require.NoError(t, m.readinessCheck("fedach")())
+
+	// The data becomes stale once, Fedwire has no maximum age
+	m.now = func() time.Time { return latest.Add(96 * time.Hour) }
+	m.check()
+	m.check()
+	require.Equal(t, 1, strings.Count(buf.String(), "stale data"))
+	require.Contains(t, buf.String(), "fedach data was last revised 2024-03-01, 96h0m0s ago which is older than the 72h0m0s allowed")
+	require.NotContains(t, buf.String(), "fedwire data was")
+	require.ErrorContains(t, m.readinessCheck("fedach")(), "older than the 72h0m0s allowed")
+	require.NoError(t, m.readinessCheck("fedwire")())
+
+	// Refreshed data is fresh again
+	s.Lock()
+	s.achStats.Latest = latest.Add(90 * time.Hour)
+	s.Unlock()
+	m.check()
+	require.Contains(t, buf.String(), "fedach data is no longer stale")
+	require.NoError(t, m.readinessCheck("fedach")())
+
+	// Directories which aren't loaded yet aren't stale
+	m = newFreshnessMonitor(logger, &searcher{}, m.cfg)
+	require.NoError(t, m.readinessCheck("fedach")())
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/admin"
+)
+
+// addHealthChecks reports each directory's status on the admin server, which isn't ready until
+// they're loaded
+func addHealthChecks(svc *admin.Server, s *searcher) {
+	for _, listName := range []string{"fedach", "fedwire"} {
+		svc.AddReadinessCheck(listName, func() error {
+			return s.checkReady(listName)
+		})
+	}
+	svc.AddLivenessCheck("searcher", s.checkLive)
+	svc.AddHandler("/status", s.statusHandler)
+}
+
+// loadError is a failed read of a directory
+type loadError struct {
+	err error
+	at  time.Time
+}
+
+// recordLoad saves the result of reading listName. Errors are kept until the directory loads.
+func (s *searcher) recordLoad(listName string, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	last := &s.achLastError
+	if listName == "fedwire" {
+		last = &s.wireLastError
+	}
+	if err != nil {
+		*last = loadError{err: err, at: time.Now()}
+		return
+	}
+	*last = loadError{}
+}
+
+// directoryStatus describes the data of a directory in health checks
+type directoryStatus struct {
+	Loaded   bool      `json:"loaded"`
+	Records  int       `json:"records"`
+	Source   string    `json:"source,omitempty"`
+	LoadedAt time.Time `json:"loadedAt,omitzero"`
+	Latest   time.Time `json:"latest,omitzero"`
+
+	// LastError is the last problem reading the directory, the loaded data is searched until it reads again
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt,omitzero"`
+}
+
+// directoryStatus returns the status of the fedach or fedwire directory
+func (s *searcher) directoryStatus(listName string) directoryStatus {
+	s.RLock()
+	defer s.RUnlock()
+
+	var status directoryStatus
+	var last loadError
+	switch listName {
+	case "fedach":
+		last = s.achLastError
+		status.LoadedAt = s.achLoadedAt
+		status.Source = s.achOrigin.source
+		status.Latest = s.achStats.Latest
+		if s.ACHDictionary != nil {
+			status.Loaded = true
+			status.Records = len(s.ACHDictionary.ACHParticipants)
+		}
+	case "fedwire":
+		last = s.wireLastError
+		status.LoadedAt = s.wireLoadedAt
+		status.Source = s.wireOrigin.source
+		status.Latest = s.wireStats.Latest
+		if s.WIREDictionary != nil {
+			status.Loaded = true
+			status.Records = len(s.WIREDictionary.WIREParticipants)
+		}
+	}
+	if last.err != nil {
+		status.LastError = last.err.Error()
+		status.LastErrorAt = last.at
+	}
+	return status
+}
+
+// checkReady returns an error until listName is loaded and passes the data bounds
+func (s *searcher) checkReady(listName string) error {
+	status := s.directoryStatus(listName)
+	if !status.Loaded {
+		if status.LastError != "" {
+			return fmt.Errorf("%s data isn't loaded: %s", listName, status.LastError)
+		}
+		return fmt.Errorf("%s data isn't loaded", listName)
+	}
+	if status.Records == 0 {
+		return fmt.Errorf("%s data has no records", listName)
+	}
+	return s.bounds.check(listName, 0, status.Records)
+}
+
+// checkLive returns an error when there's no searcher. Data is only loaded at startup, and a
+// failed load exits the server, so it's live whenever the admin server responds.
+func (s *searcher) checkLive() error {
+	if s == nil {
+		return errors.New("no searcher")
+	}
+	return nil
+}
+
+// healthStatus is the response of the admin server's GET /status
+type healthStatus struct {
+	Ready       bool                       `json:"ready"`
+	Directories map[string]directoryStatus `json:"directories"`
+}
+
+// statusHandler responds with the status of each directory, and 503 Service Unavailable until
+// they're ready
+func (s *searcher) statusHandler(w http.ResponseWriter, r *http.Request) {
+	resp := healthStatus{
+		Ready:       true,
+		Directories: make(map[string]directoryStatus),
+	}
+	for _, listName := range []string{"fedach", "fedwire"} {
+		resp.Directories[listName] = s.directoryStatus(listName)
+		if s.checkReady(listName) != nil {
+			resp.Ready = false
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
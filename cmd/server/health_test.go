@@ -0,0 +1,142 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/base/log"
+
+	"github.com/stretchr/testify/require"
+)
+
+func adminGet(t *testing.T, svc *admin.Server, path string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get("http://" + svc.BindAddr() + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestHealthChecks(t *testing.T) {
+	s := &searcher{logger: log.NewNopLogger()}
+
+	svc, err := admin.New(admin.Opts{Addr: ":0"})
+	require.NoError(t, err)
+	addHealthChecks(svc, s)
+	go svc.Listen()
+	t.Cleanup(svc.Shutdown)
+
+	// Nothing is ready while the data loads
+	code, body := adminGet(t, svc, "/ready")
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, body, "fedach data isn't loaded")
+
+	code, body = adminGet(t, svc, "/live")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `"searcher":"good"`)
+
+	code, body = adminGet(t, svc, "/status")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	var status healthStatus
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	require.False(t, status.Ready)
+	require.False(t, status.Directories["fedwire"].Loaded)
+
+	// Failed loads leave the server unready
+	require.Error(t, s.readFEDACHData(strings.NewReader("<html></html>")))
+	code, body = adminGet(t, svc, "/ready")
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, body, "fedach data isn't loaded: ERROR: reading FedACHdir.txt")
+
+	code, body = adminGet(t, svc, "/status")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	status = healthStatus{}
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	require.Contains(t, status.Directories["fedach"].LastError, "ERROR: reading FedACHdir.txt")
+	require.False(t, status.Directories["fedach"].LastErrorAt.IsZero())
+
+	// Loading both directories makes the server ready
+	t.Setenv("FEDACH_DATA_PATH", filepath.Join("..", "..", "data", "FedACHdir.txt"))
+	t.Setenv("FEDWIRE_DATA_PATH", filepath.Join("..", "..", "data", "fpddir.txt"))
+	sources := []DataSource{newFileSource()}
+	achFile, err := fetchDataFile(context.Background(), s.logger, sources, "fedach")
+	require.NoError(t, err)
+	wireFile, err := fetchDataFile(context.Background(), s.logger, sources, "fedwire")
+	require.NoError(t, err)
+	require.NoError(t, setupSearcher(s.logger, s, achFile, wireFile))
+
+	code, body = adminGet(t, svc, "/ready")
+	require.Equal(t, http.StatusOK, code, body)
+
+	code, body = adminGet(t, svc, "/status")
+	require.Equal(t, http.StatusOK, code)
+	status = healthStatus{}
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	require.True(t, status.Ready)
+
+	ach := status.Directories["fedach"]
+	require.True(t, ach.Loaded)
+	require.Equal(t, 18198, ach.Records)
+	require.Equal(t, "file", ach.Source)
+	require.False(t, ach.LoadedAt.IsZero())
+	require.False(t, ach.Latest.IsZero())
+	require.Empty(t, ach.LastError)
+	require.True(t, ach.LastErrorAt.IsZero())
+	require.Equal(t, 7693, status.Directories["fedwire"].Records)
+
+	// Rejected data keeps the loaded dictionary, so the server stays ready
+	fpddir, err := os.ReadFile(filepath.Join("..", "..", "data", "fpddir.txt"))
+	require.NoError(t, err)
+	firstRecord, _, _ := bytes.Cut(fpddir, []byte("\n"))
+
+	s.bounds = dataBounds{minRecords: map[string]int{"fedwire": 100}}
+	require.ErrorIs(t, s.readFEDWIREData(bytes.NewReader(firstRecord)), errIntegrity)
+	code, _ = adminGet(t, svc, "/ready")
+	require.Equal(t, http.StatusOK, code)
+
+	code, body = adminGet(t, svc, "/status")
+	require.Equal(t, http.StatusOK, code)
+	status = healthStatus{}
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	wire := status.Directories["fedwire"]
+	require.Equal(t, 7693, wire.Records)
+	require.Contains(t, wire.LastError, "rejecting FedWire data: integrity check failed")
+	require.False(t, wire.LastErrorAt.IsZero())
+
+	// and reading it again clears the error
+	s.bounds = dataBounds{}
+	wireFile, err = fetchDataFile(context.Background(), s.logger, sources, "fedwire")
+	require.NoError(t, err)
+	require.NoError(t, s.readFEDWIREData(wireFile))
+	require.Empty(t, s.directoryStatus("fedwire").LastError)
+}
+
+func TestSearcher__checkReady(t *testing.T) {
+	s := &searcher{logger: log.NewNopLogger()}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+	require.NoError(t, s.checkReady("fedach"))
+	require.ErrorContains(t, s.checkReady("fedwire"), "fedwire data isn't loaded")
+
+	s.bounds = dataBounds{minRecords: map[string]int{"fedach": 20000}}
+	require.ErrorContains(t, s.checkReady("fedach"), "at least 20000 are required")
+
+	var nilSearcher *searcher
+	require.Error(t, nilSearcher.checkLive())
+	require.NoError(t, s.checkLive())
+}
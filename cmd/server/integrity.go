@@ -0,0 +1,238 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/moov-io/fed"
+)
+
+// errIntegrity is returned when a list fails its integrity checks
+var errIntegrity = errors.New("integrity check failed")
+
+// integrityChecks verify each list read from a DataSource before it's parsed
+type integrityChecks struct {
+	// digests are the pinned SHA-256 digests accepted for each list, keyed by list name
+	digests map[string][]string
+
+	// publicKey verifies detached signatures (e.g. FedACHdir.txt.sig) of every list. Lists from
+	// sources which can't provide signatures (see signatureSource) are rejected when it's set.
+	publicKey crypto.PublicKey
+}
+
+// readIntegrityChecks reads FEDACH_SHA256, FEDWIRE_SHA256 and DATA_SIGNING_PUBLIC_KEY. It returns
+// nil when no checks are configured.
+func readIntegrityChecks() (*integrityChecks, error) {
+	checks := &integrityChecks{
+		digests: make(map[string][]string),
+	}
+	for listName, env := range map[string]string{"fedach": "FEDACH_SHA256", "fedwire": "FEDWIRE_SHA256"} {
+		for _, digest := range strings.Split(os.Getenv(env), ",") {
+			digest = strings.ToLower(strings.TrimSpace(digest))
+			if digest == "" {
+				continue
+			}
+			if bs, err := hex.DecodeString(digest); err != nil || len(bs) != sha256.Size {
+				return nil, fmt.Errorf("%s: invalid SHA-256 digest %q", env, digest)
+			}
+			checks.digests[listName] = append(checks.digests[listName], digest)
+		}
+	}
+
+	if path := os.Getenv("DATA_SIGNING_PUBLIC_KEY"); path != "" {
+		key, err := readPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("DATA_SIGNING_PUBLIC_KEY: %w", err)
+		}
+		checks.publicKey = key
+	}
+
+	if len(checks.digests) == 0 && checks.publicKey == nil {
+		return nil, nil
+	}
+	return checks, nil
+}
+
+// readPublicKey reads a PEM encoded Ed25519, ECDSA or RSA public key
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(bs)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s doesn't hold a PEM encoded public key", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported %T public key", key)
+}
+
+// wrap returns sources with every list they return verified
+func (c *integrityChecks) wrap(sources []DataSource) []DataSource {
+	if c == nil {
+		return sources
+	}
+	out := make([]DataSource, len(sources))
+	for i := range sources {
+		// Required sources stay outermost so fetchDataFile still stops when their lists are rejected
+		if req, ok := sources[i].(*requiredSource); ok {
+			out[i] = &requiredSource{&verifiedSource{DataSource: req.DataSource, checks: c}}
+			continue
+		}
+		out[i] = &verifiedSource{DataSource: sources[i], checks: c}
+	}
+	return out
+}
+
+func (c *integrityChecks) verify(ctx context.Context, src DataSource, listName string, data []byte) error {
+	if pinned := c.digests[listName]; len(pinned) > 0 {
+		if sum := fed.SourceChecksum(data); !slices.Contains(pinned, sum) {
+			return fmt.Errorf("%w: %s SHA-256 %s isn't pinned", errIntegrity, listName, sum)
+		}
+	}
+
+	if c.publicKey == nil {
+		return nil
+	}
+	// FRB downloads and embedded files have no signatures, so they can't be trusted
+	signed, ok := src.(signatureSource)
+	if !ok {
+		return fmt.Errorf("%w: %s can't provide a signature of %s", errIntegrity, src.Name(), listName)
+	}
+	sig, err := signed.FetchSignature(ctx, listName)
+	if err != nil {
+		return fmt.Errorf("%w: reading %s signature: %v", errIntegrity, listName, err)
+	}
+	if err := verifySignature(c.publicKey, data, sig); err != nil {
+		return fmt.Errorf("%w: %s signature: %v", errIntegrity, listName, err)
+	}
+	return nil
+}
+
+// verifySignature checks sig, which is raw or base64 encoded, is a signature of data by key.
+// ECDSA and RSA (PKCS #1 v1.5) signatures are of the data's SHA-256 digest.
+func verifySignature(key crypto.PublicKey, data, sig []byte) error {
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig))); err == nil {
+		sig = decoded
+	}
+	digest := sha256.Sum256(data)
+
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return errors.New("invalid ed25519 signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return errors.New("invalid ecdsa signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	}
+	return fmt.Errorf("unsupported %T public key", key)
+}
+
+// signatureSource is implemented by sources which can read the detached signature of a list
+type signatureSource interface {
+	FetchSignature(ctx context.Context, listName string) ([]byte, error)
+}
+
+// verifiedSource checks every list read from a DataSource with integrityChecks
+type verifiedSource struct {
+	DataSource
+	checks *integrityChecks
+}
+
+func (src *verifiedSource) Fetch(ctx context.Context, listName string) (io.Reader, error) {
+	file, err := src.DataSource.Fetch(ctx, listName)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := file.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", listName, err)
+	}
+	if err := src.checks.verify(ctx, src.DataSource, listName, data); err != nil {
+		return nil, err
+	}
+	return &originFile{Reader: bytes.NewReader(data), origin: readDataOrigin(file)}, nil
+}
+
+// dataBounds reject new data with an unexpected number of records
+type dataBounds struct {
+	// minRecords is the fewest records accepted for each list, keyed by list name
+	minRecords map[string]int
+
+	// maxShrink is the fraction of the previously loaded records new data can drop, zero disables the check
+	maxShrink float64
+}
+
+// readDataBounds reads FEDACH_MIN_RECORDS, FEDWIRE_MIN_RECORDS and DATA_MAX_SHRINK
+func readDataBounds() (dataBounds, error) {
+	bounds := dataBounds{
+		minRecords: make(map[string]int),
+	}
+	for listName, env := range map[string]string{"fedach": "FEDACH_MIN_RECORDS", "fedwire": "FEDWIRE_MIN_RECORDS"} {
+		if v := os.Getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return bounds, fmt.Errorf("invalid %s: %q", env, v)
+			}
+			bounds.minRecords[listName] = n
+		}
+	}
+	if v := os.Getenv("DATA_MAX_SHRINK"); v != "" {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		if err != nil || pct < 0 || pct > 100 {
+			return bounds, fmt.Errorf("invalid DATA_MAX_SHRINK: %q", v)
+		}
+		bounds.maxShrink = pct / 100
+	}
+	return bounds, nil
+}
+
+// check returns an error when records are too few for listName, given the number previously loaded
+func (b dataBounds) check(listName string, loaded, records int) error {
+	if minimum := b.minRecords[listName]; records < minimum {
+		return fmt.Errorf("%w: %s has %d records and at least %d are required", errIntegrity, listName, records, minimum)
+	}
+	if b.maxShrink > 0 && loaded > 0 && records < loaded {
+		if shrink := float64(loaded-records) / float64(loaded); shrink > b.maxShrink {
+			return fmt.Errorf("%w: %s has %d records, %.1f%% fewer than the %d previously loaded", errIntegrity, listName, records, 100*shrink, loaded)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePublicKey(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
+
+func TestReadIntegrityChecks(t *testing.T) {
+	t.Setenv("FEDACH_SHA256", "")
+	t.Setenv("FEDWIRE_SHA256", "")
+	t.Setenv("DATA_SIGNING_PUBLIC_KEY", "")
+
+	checks, err := readIntegrityChecks()
+	require.NoError(t, err)
+	require.Nil(t, checks)
+	require.Len(t, checks.wrap([]DataSource{newFileSource()}), 1)
+
+	digest := strings.Repeat("ab", sha256.Size)
+	t.Setenv("FEDACH_SHA256", strings.ToUpper(digest)+", "+strings.Repeat("cd", sha256.Size))
+	checks, err = readIntegrityChecks()
+	require.NoError(t, err)
+	require.Equal(t, []string{digest, strings.Repeat("cd", sha256.Size)}, checks.digests["fedach"])
+	require.Empty(t, checks.digests["fedwire"])
+
+	t.Setenv("FEDWIRE_SHA256", "abc")
+	_, err = readIntegrityChecks()
+	require.ErrorContains(t, err, "FEDWIRE_SHA256: invalid SHA-256 digest")
+	t.Setenv("FEDWIRE_SHA256", "")
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	t.Setenv("DATA_SIGNING_PUBLIC_KEY", writePublicKey(t, pub))
+	checks, err = readIntegrityChecks()
+	require.NoError(t, err)
+	require.Equal(t, pub, checks.publicKey)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+	t.Setenv("DATA_SIGNING_PUBLIC_KEY", path)
+	_, err = readIntegrityChecks()
+	require.ErrorContains(t, err, "doesn't hold a PEM encoded public key")
+}
+
+func TestVerifySignature(t *testing.T) {
+	data := []byte("011000015O0110000151122415000000000FEDERAL RESERVE BANK")
+	digest := sha256.Sum256(data)
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sig := ed25519.Sign(edPrivate, data)
+	require.NoError(t, verifySignature(edPublic, data, sig))
+	require.NoError(t, verifySignature(edPublic, data, []byte(base64.StdEncoding.EncodeToString(sig)+"\n")))
+	require.Error(t, verifySignature(edPublic, append(data, '1'), sig))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	sig, err = ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	require.NoError(t, err)
+	require.NoError(t, verifySignature(&ecKey.PublicKey, data, sig))
+	require.Error(t, verifySignature(&ecKey.PublicKey, data[1:], sig))
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	require.NoError(t, verifySignature(&rsaKey.PublicKey, data, sig))
+	require.Error(t, verifySignature(&rsaKey.PublicKey, data[1:], sig))
+
+	// Signatures from another key
+	require.Error(t, verifySignature(edPublic, data, sig))
+}
+
+func TestVerifiedSource(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewTestLogger()
+
+	achFile, err := os.ReadFile(filepath.Join("..", "..", "data", "FedACHdir.txt"))
+	require.NoError(t, err)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// A mirror with signatures, one of which is wrong
+	mirror := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, "FedACHdir.txt"), achFile, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, "FedACHdir.txt.sig"), ed25519.Sign(private, achFile), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, "fpddir.txt"), []byte("tampered"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, "fpddir.txt.sig"), ed25519.Sign(private, []byte("original")), 0600))
+
+	checks := &integrityChecks{publicKey: public}
+	sources := checks.wrap([]DataSource{
+		&dirSource{dir: mirror, logger: logger},
+		&stubSource{name: "frb", file: "unsigned frb data"},
+	})
+	require.Equal(t, []string{"dir:" + mirror, "frb"}, dataSourceNames(sources))
+
+	r, err := fetchDataFile(ctx, logger, sources, "fedach")
+	require.NoError(t, err)
+	bs, _ := io.ReadAll(r)
+	require.Equal(t, achFile, bs)
+
+	// Rejected lists aren't read from sources which can't be signed either
+	_, err = fetchDataFile(ctx, logger, sources, "fedwire")
+	require.ErrorIs(t, err, errIntegrity)
+	require.ErrorContains(t, err, "fedwire signature")
+	require.ErrorContains(t, err, "frb can't provide a signature of fedwire")
+
+	// Missing signatures
+	require.NoError(t, os.Remove(filepath.Join(mirror, "FedACHdir.txt.sig")))
+	_, err = sources[0].Fetch(ctx, "fedach")
+	require.ErrorIs(t, err, errIntegrity)
+	require.ErrorContains(t, err, "reading fedach signature")
+
+	// Pinned digests
+	checks = &integrityChecks{
+		digests: map[string][]string{"fedach": {fed.SourceChecksum(achFile)}},
+	}
+	sources = checks.wrap([]DataSource{&dirSource{dir: mirror, logger: logger}})
+	_, err = sources[0].Fetch(ctx, "fedach")
+	require.NoError(t, err)
+
+	checks.digests["fedach"] = []string{fed.SourceChecksum([]byte("other"))}
+	_, err = sources[0].Fetch(ctx, "fedach")
+	require.ErrorIs(t, err, errIntegrity)
+	require.ErrorContains(t, err, "isn't pinned")
+
+	// Where data came from is kept
+	setEmbeddedData(t, os.DirFS(filepath.Join("..", "..", "data")))
+	checks.digests["fedach"] = []string{fed.SourceChecksum(achFile)}
+	r, err = checks.wrap([]DataSource{&embeddedSource{logger: logger}})[0].Fetch(ctx, "fedach")
+	require.NoError(t, err)
+	require.True(t, readDataOrigin(r).embedded)
+}
+
+func TestDataBounds(t *testing.T) {
+	t.Setenv("FEDACH_MIN_RECORDS", "")
+	t.Setenv("FEDWIRE_MIN_RECORDS", "")
+	t.Setenv("DATA_MAX_SHRINK", "")
+
+	bounds, err := readDataBounds()
+	require.NoError(t, err)
+	require.NoError(t, bounds.check("fedach", 1000, 1))
+
+	t.Setenv("FEDACH_MIN_RECORDS", "100")
+	t.Setenv("DATA_MAX_SHRINK", "10%")
+	bounds, err = readDataBounds()
+	require.NoError(t, err)
+	require.InDelta(t, 0.1, bounds.maxShrink, 0.0001)
+
+	require.ErrorIs(t, bounds.check("fedach", 0, 99), errIntegrity)
+	require.NoError(t, bounds.check("fedach", 0, 100))
+	require.NoError(t, bounds.check("fedwire", 0, 1))
+	require.NoError(t, bounds.check("fedach", 1000, 900))
+	require.ErrorContains(t, bounds.check("fedach", 1000, 899), "10.1% fewer than the 1000 previously loaded")
+	require.NoError(t, bounds.check("fedach", 1000, 2000))
+
+	for env, v := range map[string]string{"FEDWIRE_MIN_RECORDS": "-1", "DATA_MAX_SHRINK": "200%"} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, v)
+			_, err := readDataBounds()
+			require.ErrorContains(t, err, "invalid "+env)
+		})
+	}
+}
+
+func TestReader__rejectShrinkingData(t *testing.T) {
+	achFile, err := os.ReadFile(filepath.Join("..", "..", "data", "FedACHdir.txt"))
+	require.NoError(t, err)
+
+	s := &searcher{logger: log.NewNopLogger(), bounds: dataBounds{maxShrink: 0.5}}
+	require.NoError(t, s.readFEDACHData(bytes.NewReader(achFile)))
+	loaded := len(s.ACHDictionary.ACHParticipants)
+
+	// A partially written file is compared with the dictionary being searched
+	lines := bytes.SplitAfter(achFile, []byte("\n"))
+	partial := bytes.Join(lines[:len(lines)/3], nil)
+
+	err = s.readFEDACHData(bytes.NewReader(partial))
+	require.ErrorIs(t, err, errIntegrity)
+	require.Len(t, s.ACHDictionary.ACHParticipants, loaded)
+
+	// Snapshots of the previous data aren't compared with, only what's loaded
+	dir := t.TempDir()
+	s = &searcher{logger: log.NewNopLogger(), snapshotDir: dir}
+	require.NoError(t, s.readFEDACHData(bytes.NewReader(achFile)))
+
+	s = &searcher{logger: log.NewNopLogger(), snapshotDir: dir, bounds: dataBounds{maxShrink: 0.5}}
+	require.NoError(t, s.readFEDACHData(bytes.NewReader(partial)))
+	require.Less(t, len(s.ACHDictionary.ACHParticipants), loaded)
+}
@@ -0,0 +1,260 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtSigningMethods are the asymmetric algorithms accepted in JWTs. Symmetric (HS*) tokens
+// are never accepted since a JWKS only holds public keys.
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var (
+	// jwksRefreshInterval is the shortest time between reading the JWKS again for an unknown key ID
+	jwksRefreshInterval = time.Minute
+
+	errUnknownJWK = errors.New("unknown signing key")
+)
+
+// jwks holds the public keys of a JSON Web Key Set (RFC 7517) which are read again when tokens
+// are signed by a key it doesn't have, so keys can be rotated without a restart.
+type jwks struct {
+	read func() ([]byte, error)
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey // keyed by kid, replaced rather than modified
+	lastRefresh time.Time
+	inflight    *jwksRefresh // the read in progress, nil when there isn't one
+}
+
+// jwksRefresh is one read of the key set, err is set before done is closed
+type jwksRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+func newFileJWKS(path string) *jwks {
+	return &jwks{
+		read: func() ([]byte, error) {
+			return os.ReadFile(path)
+		},
+	}
+}
+
+func newURLJWKS(where string) *jwks {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &jwks{
+		read: func() ([]byte, error) {
+			resp, err := client.Get(where)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, where)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		},
+	}
+}
+
+// refresh reads the key set again, keeping the previous keys if that fails
+func (k *jwks) refresh() error {
+	return k.refreshOlderThan(0)
+}
+
+// refreshOlderThan reads the key set again unless it was read less than age ago. Callers wait for a read
+// already in progress rather than starting another. The mutex isn't held while reading, so tokens signed
+// by known keys are still verified while a slow JWKS server is read.
+func (k *jwks) refreshOlderThan(age time.Duration) error {
+	k.mu.Lock()
+	if call := k.inflight; call != nil {
+		k.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	if age > 0 && time.Since(k.lastRefresh) < age {
+		k.mu.Unlock()
+		return nil
+	}
+	call := &jwksRefresh{done: make(chan struct{})}
+	k.inflight, k.lastRefresh = call, time.Now()
+	k.mu.Unlock()
+
+	var keys map[string]crypto.PublicKey
+	bs, err := k.read()
+	if err == nil {
+		keys, err = parseJWKS(bs)
+	}
+
+	k.mu.Lock()
+	if err == nil {
+		k.keys = keys
+	}
+	k.inflight = nil
+	k.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+	return err
+}
+
+// keyfunc returns the public key matching the kid header of token
+func (k *jwks) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+	if err := k.refreshOlderThan(jwksRefreshInterval); err != nil {
+		return nil, fmt.Errorf("reading JWKS: %w", err)
+	}
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, errUnknownJWK
+}
+
+// lookup returns the key with kid, tokens without a kid can only use a set of one key
+func (k *jwks) lookup(kid string) (crypto.PublicKey, bool) {
+	k.mu.Lock()
+	keys := k.keys
+	k.mu.Unlock()
+
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC and OKP
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+// parseJWKS returns the signing keys of a JSON Web Key Set keyed by kid
+func parseJWKS(bs []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(bs, &set); err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %w", err)
+	}
+
+	out := make(map[string]crypto.PublicKey)
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%s): %w", i, jwk.Kid, err)
+		}
+		out[jwk.Kid] = key
+	}
+	if len(out) == 0 {
+		return nil, errors.New("JWKS has no signing keys")
+	}
+	return out, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := decodeJWKInt(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeJWKInt(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeJWKInt(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) { //nolint:staticcheck
+			return nil, errors.New("point isn't on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decodeJWKInt(v string) (*big.Int, error) {
+	if v == "" {
+		return nil, errors.New("missing")
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bs), nil
+}
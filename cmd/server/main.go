@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -22,6 +23,7 @@ import (
 	moovhttp "github.com/moov-io/base/http"
 	"github.com/moov-io/base/http/bind"
 	"github.com/moov-io/base/log"
+	"github.com/moov-io/base/strx"
 	"github.com/moov-io/fed"
 	"github.com/moov-io/fed/webui"
 
@@ -58,8 +60,42 @@ func main() {
 		errs <- fmt.Errorf("%s", <-c)
 	}()
 
+	// Export traces of requests, searches and data loads
+	tracing, err := readTracingConfig()
+	if err != nil {
+		logger.LogErrorf("problem reading tracing config: %v", err)
+		os.Exit(1)
+	}
+	shutdownTracing, err := setupTracing(context.Background(), tracing)
+	if err != nil {
+		logger.LogErrorf("problem setting up tracing: %v", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing()
+
+	// Require API keys or JWTs for searches when configured
+	authConfig, err := readAuthConfig()
+	if err != nil {
+		logger.LogErrorf("problem reading auth config: %v", err)
+		os.Exit(1)
+	}
+	auth, err := newAuthenticator(logger, authConfig)
+	if err != nil {
+		logger.LogErrorf("problem setting up authentication: %v", err)
+		os.Exit(1)
+	}
+
+	// Limit how often each client can search
+	rateLimits, err := readRateLimitConfig()
+	if err != nil {
+		logger.LogErrorf("problem reading rate limit config: %v", err)
+		os.Exit(1)
+	}
+	limiter := newRateLimiter(logger, rateLimits)
+
 	// Setup business HTTP routes
 	router := mux.NewRouter()
+	router.Use(tracingMiddleware, auth.middleware, limiter.middleware)
 	moovhttp.AddCORSHandler(router)
 	addPingRoute(router)
 
@@ -92,6 +128,55 @@ func main() {
 		}
 	}
 
+	// Score fuzzy searches across this many goroutines
+	if v := os.Getenv("SEARCH_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.LogErrorf("invalid SEARCH_WORKERS: %q", v)
+			os.Exit(1)
+		}
+		fed.SearchWorkers = n
+	}
+
+	// Start our searcher
+	searchTimeout, err := readSearchTimeout(os.Getenv("SEARCH_TIMEOUT"))
+	if err != nil {
+		logger.LogErrorf("problem reading SEARCH_TIMEOUT: %v", err)
+		os.Exit(1)
+	}
+	cacheSize, cacheTTL, err := readSearchCacheConfig(os.Getenv("SEARCH_CACHE_SIZE"), os.Getenv("SEARCH_CACHE_TTL"))
+	if err != nil {
+		logger.LogErrorf("problem reading search cache config: %v", err)
+		os.Exit(1)
+	}
+	bounds, err := readDataBounds()
+	if err != nil {
+		logger.LogErrorf("problem reading data bounds: %v", err)
+		os.Exit(1)
+	}
+	auditConfig, err := readAuditConfig()
+	if err != nil {
+		logger.LogErrorf("problem reading audit log config: %v", err)
+		os.Exit(1)
+	}
+	audit, err := newAuditLogger(logger, auditConfig)
+	if err != nil {
+		logger.LogErrorf("problem creating audit log: %v", err)
+		os.Exit(1)
+	}
+
+	searcher := &searcher{
+		logger:        logger,
+		searchTimeout: searchTimeout,
+		bounds:        bounds,
+		describeCodes: strx.Yes(os.Getenv("INCLUDE_CODE_DESCRIPTIONS")),
+		compact:       strx.Yes(os.Getenv("COMPACT_DICTIONARIES")),
+		snapshotDir:   os.Getenv("SNAPSHOT_DIRECTORY"),
+		achCache:      newSearchCache[[]*fed.ACHParticipant]("ach", cacheSize, cacheTTL),
+		wireCache:     newSearchCache[[]*fed.WIREParticipant]("wire", cacheSize, cacheTTL),
+		audit:         audit,
+	}
+
 	// Check to see if our -admin.addr flag has been overridden
 	if v := os.Getenv("HTTP_ADMIN_BIND_ADDRESS"); v != "" {
 		*adminAddr = v
@@ -106,6 +191,20 @@ func main() {
 		os.Exit(1)
 	}
 	adminServer.AddVersionHandler(fed.Version) // Setup 'GET /version'
+	addHealthChecks(adminServer, searcher)
+
+	// Report how old the data is and optionally fail readiness while it's stale
+	freshness, err := readFreshnessConfig()
+	if err != nil {
+		logger.LogErrorf("problem reading data freshness config: %v", err)
+		os.Exit(1)
+	}
+	freshnessMonitor := newFreshnessMonitor(logger, searcher, freshness)
+	if freshness.failReadiness {
+		for _, listName := range []string{"fedach", "fedwire"} {
+			adminServer.AddReadinessCheck(listName+"-data-age", freshnessMonitor.readinessCheck(listName))
+		}
+	}
 	go func() {
 		logger.Info().Logf(fmt.Sprintf("listening on %s", adminServer.BindAddr()))
 		if err := adminServer.Listen(); err != nil {
@@ -116,15 +215,23 @@ func main() {
 	}()
 	defer adminServer.Shutdown()
 
-	// Start our searcher
-	searcher := &searcher{logger: logger}
-
-	fedACHData, err := fedACHDataFile(logger)
+	sources, err := readDataSources(logger, os.Getenv("DATA_SOURCES"))
+	if err != nil {
+		logger.LogErrorf("problem reading DATA_SOURCES: %v", err)
+		os.Exit(1)
+	}
+	integrity, err := readIntegrityChecks()
+	if err != nil {
+		logger.LogErrorf("problem reading integrity checks: %v", err)
+		os.Exit(1)
+	}
+	sources = integrity.wrap(sources)
+	fedACHData, err := fedACHDataFile(logger, sources)
 	if err != nil {
 		logger.LogErrorf("problem downloading FedACH: %v", err)
 		os.Exit(1)
 	}
-	fedWireData, err := fedWireDataFile(logger)
+	fedWireData, err := fedWireDataFile(logger, sources)
 	if err != nil {
 		logger.LogErrorf("problem downloading FedWire: %v", err)
 		os.Exit(1)
@@ -135,8 +242,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	monitorDone := make(chan struct{})
+	defer close(monitorDone)
+	go freshnessMonitor.run(freshnessCheckInterval, monitorDone)
+
 	// Add searcher for HTTP routes
 	addSearchRoutes(logger, router, searcher)
+	addChangesRoutes(logger, router, searcher)
 
 	// Add webui routes
 	webuiController := webui.NewController(logger)
@@ -160,6 +272,10 @@ func main() {
 	// Block/Wait for an error
 	if err := <-errs; err != nil {
 		shutdownServer()
+		// Deferred calls don't run before os.Exit, so close the audit log once searches have finished
+		if err := audit.Close(); err != nil {
+			logger.LogErrorf("problem closing audit log: %v", err)
+		}
 		logger.Logf("exit: %v", err)
 		os.Exit(1)
 	}
@@ -189,5 +305,6 @@ func setupSearcher(logger log.Logger, s *searcher, achFile, wireFile io.Reader)
 		return fmt.Errorf("error reading wire data: %v", err)
 	}
 
-	return s.precompute()
+	s.precompute()
+	return nil
 }
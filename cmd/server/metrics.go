@@ -0,0 +1,86 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	searchDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name: "search_duration_seconds",
+		Help: "Histogram of how long searches not answered from the search cache took",
+	}, []string{"list", "type"})
+
+	searchResults = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name:    "search_results",
+		Help:    "Histogram of the number of participants returned by searches",
+		Buckets: []float64{0, 1, 2, 5, 10, 25, 50, 100, 250, 500},
+	}, []string{"list", "type"})
+
+	searchZeroResults = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "search_zero_results_total",
+		Help: "Counter of searches which returned no participants",
+	}, []string{"list", "type"})
+
+	searchFuzzyCandidates = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name:    "search_fuzzy_candidates",
+		Help:    "Histogram of participants matching fuzzy name and routing number searches before the limit is applied",
+		Buckets: []float64{0, 1, 10, 100, 1000, 10000, 100000},
+	}, []string{"list", "type"})
+
+	dataParseDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name: "fed_data_parse_duration_seconds",
+		Help: "Histogram of how long parsing (or loading the snapshot of) each directory took",
+	}, []string{"list"})
+
+	dataRefreshDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name: "fed_data_refresh_duration_seconds",
+		Help: "Histogram of how long reading, checking and swapping in each directory took",
+	}, []string{"list"})
+)
+
+// searchType names the shape of req in metrics: name, routing, city, state, postal or combined
+func (req fedSearchRequest) searchType() string {
+	switch {
+	case req.nameOnly():
+		return "name"
+	case req.routingNumberOnly():
+		return "routing"
+	case req.cityOnly():
+		return "city"
+	case req.stateOnly():
+		return "state"
+	case req.postalCodeOnly():
+		return "postal"
+	}
+	return "combined"
+}
+
+// fuzzy returns true if searching for req scores participants rather than only filtering them
+func (req fedSearchRequest) fuzzy() bool {
+	return req.Name != "" || req.routingNumberOnly()
+}
+
+// observeSearch records a search which was run rather than answered from the search cache
+func observeSearch(listName string, req fedSearchRequest, took time.Duration, candidates int) {
+	searchType := req.searchType()
+	searchDuration.With("list", listName, "type", searchType).Observe(took.Seconds())
+	if req.fuzzy() {
+		searchFuzzyCandidates.With("list", listName, "type", searchType).Observe(float64(candidates))
+	}
+}
+
+// observeSearchResults records the number of participants a search responded with
+func observeSearchResults(listName string, req fedSearchRequest, results int) {
+	searchType := req.searchType()
+	searchResults.With("list", listName, "type", searchType).Observe(float64(results))
+	if results == 0 {
+		searchZeroResults.With("list", listName, "type", searchType).Add(1)
+	}
+}
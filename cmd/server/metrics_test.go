@@ -0,0 +1,143 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// findMetric returns the metric from the default Prometheus registry with exactly the labels given
+func findMetric(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+
+	families, err := stdprometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			matched := 0
+			for _, label := range m.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(labels) && len(m.GetLabel()) == len(labels) {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// histogramCount returns how many observations a histogram has, zero if it has none yet
+func histogramCount(t *testing.T, name string, labels map[string]string) uint64 {
+	t.Helper()
+
+	if m := findMetric(t, name, labels); m != nil {
+		return m.GetHistogram().GetSampleCount()
+	}
+	return 0
+}
+
+func TestFedSearchRequest__searchType(t *testing.T) {
+	cases := map[string]string{
+		"name=farmers":                  "name",
+		"routingNumber=044112187":       "routing",
+		"city=ANYTOWN":                  "city",
+		"state=OH":                      "state",
+		"postalCode=43724":              "postal",
+		"name=farmers&state=OH":         "combined",
+		"revisedAfter=2024-01-01":       "combined",
+		"routingNumber=0441&city=SOMEP": "combined",
+	}
+	for query, expected := range cases {
+		u, err := url.Parse("/fed/ach/search?" + query)
+		require.NoError(t, err)
+
+		req := readFEDSearchRequest(u)
+		req.RevisedAfter, req.RevisedBefore, err = readRevisionDates(u)
+		require.NoError(t, err)
+		require.Equal(t, expected, req.searchType(), query)
+	}
+}
+
+func TestSearchMetrics(t *testing.T) {
+	s := searcher{achCache: newSearchCache[[]*fed.ACHParticipant]("ach", 10, time.Minute)}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+	search := func(query string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/fed/ach/search?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	name := map[string]string{"list": "ach", "type": "name"}
+	state := map[string]string{"list": "ach", "type": "state"}
+	searches := histogramCount(t, "search_duration_seconds", name)
+	results := histogramCount(t, "search_results", name)
+	candidates := histogramCount(t, "search_fuzzy_candidates", name)
+	stateCandidates := histogramCount(t, "search_fuzzy_candidates", state)
+
+	search("name=Farmers")
+	require.Equal(t, searches+1, histogramCount(t, "search_duration_seconds", name))
+	require.Equal(t, results+1, histogramCount(t, "search_results", name))
+	require.Equal(t, candidates+1, histogramCount(t, "search_fuzzy_candidates", name))
+
+	m := findMetric(t, "search_fuzzy_candidates", name)
+	require.NotNil(t, m)
+	require.Greater(t, m.GetHistogram().GetSampleSum(), float64(0))
+
+	// Filters don't score participants
+	search("state=ZZ")
+	require.Equal(t, stateCandidates, histogramCount(t, "search_fuzzy_candidates", state))
+
+	zero := findMetric(t, "search_zero_results_total", state)
+	require.NotNil(t, zero)
+	zeroResults := zero.GetCounter().GetValue()
+	require.GreaterOrEqual(t, zeroResults, float64(1))
+
+	// Cached searches count their results but aren't run again
+	stateSearches := histogramCount(t, "search_duration_seconds", state)
+	search("state=ZZ")
+	require.Equal(t, zeroResults+1, findMetric(t, "search_zero_results_total", state).GetCounter().GetValue())
+	require.Equal(t, stateSearches, histogramCount(t, "search_duration_seconds", state))
+}
+
+func TestDataLoadMetrics(t *testing.T) {
+	list := map[string]string{"list": "fedwire"}
+	parses := histogramCount(t, "fed_data_parse_duration_seconds", list)
+	refreshes := histogramCount(t, "fed_data_refresh_duration_seconds", list)
+
+	fd, err := os.Open(filepath.Join("..", "..", "data", "fpddir.txt"))
+	require.NoError(t, err)
+
+	s := searcher{logger: log.NewNopLogger()}
+	require.NoError(t, s.readFEDWIREData(fd))
+	require.Equal(t, parses+1, histogramCount(t, "fed_data_parse_duration_seconds", list))
+	require.Equal(t, refreshes+1, histogramCount(t, "fed_data_refresh_duration_seconds", list))
+
+	// Failed refreshes are timed too
+	require.Error(t, s.readFEDWIREData(strings.NewReader("")))
+	require.Equal(t, refreshes+2, histogramCount(t, "fed_data_refresh_duration_seconds", list))
+}
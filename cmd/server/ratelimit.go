@@ -0,0 +1,269 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	rateLimitedRequests = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Counter of requests rejected for exceeding their client's rate limit",
+	}, []string{"class"})
+
+	rateLimitClients = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "http_rate_limit_clients",
+		Help: "Clients with a token bucket for each class of endpoint",
+	}, []string{"class"})
+
+	errRateLimited = errors.New("rate limit exceeded")
+
+	// rateLimitSweepInterval is how often buckets of idle clients are dropped
+	rateLimitSweepInterval = time.Minute
+)
+
+// Classes of endpoints which are limited separately
+const (
+	rateClassLookup = "lookup" // routing number searches
+	rateClassSearch = "search" // name, location and combined searches
+	rateClassBulk   = "bulk"   // change feeds
+)
+
+// rateLimitClass returns the class of endpoint r calls, routes without one aren't limited
+func rateLimitClass(r *http.Request) string {
+	switch r.URL.Path {
+	case "/fed/ach/search", "/fed/wire/search":
+		if readFEDSearchRequest(r.URL).routingNumberOnly() {
+			return rateClassLookup
+		}
+		return rateClassSearch
+	case "/fed/ach/changes", "/fed/wire/changes":
+		return rateClassBulk
+	}
+	return ""
+}
+
+// rateLimit is a token bucket holding up to burst tokens which refills burst tokens every period
+type rateLimit struct {
+	burst  int
+	period time.Duration
+}
+
+// perSecond is how many tokens are added each second
+func (l rateLimit) perSecond() float64 {
+	return float64(l.burst) / l.period.Seconds()
+}
+
+// parseRateLimit reads limits like 10/s, 600/m or 1000/1h
+func parseRateLimit(v string) (rateLimit, error) {
+	count, per, ok := strings.Cut(strings.TrimSpace(v), "/")
+	if !ok {
+		return rateLimit{}, fmt.Errorf("%q isn't like 10/s", v)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return rateLimit{}, fmt.Errorf("%q doesn't start with a positive number of requests", v)
+	}
+
+	var period time.Duration
+	switch per {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		period, err = time.ParseDuration(per)
+		if err != nil || period <= 0 {
+			return rateLimit{}, fmt.Errorf("%q has an invalid period", v)
+		}
+	}
+	return rateLimit{burst: n, period: period}, nil
+}
+
+// rateLimitConfig is read from the RATE_LIMIT_* environment variables
+type rateLimitConfig struct {
+	// limits are keyed by endpoint class, classes without one aren't limited
+	limits map[string]rateLimit
+
+	// clientIPHeader is read for the client IP of unauthenticated requests instead of their
+	// remote address, the last address in it is used
+	clientIPHeader string
+}
+
+// readRateLimitConfig reads RATE_LIMIT_LOOKUP, RATE_LIMIT_SEARCH, RATE_LIMIT_BULK and RATE_LIMIT_CLIENT_IP_HEADER
+func readRateLimitConfig() (rateLimitConfig, error) {
+	cfg := rateLimitConfig{
+		limits:         make(map[string]rateLimit),
+		clientIPHeader: os.Getenv("RATE_LIMIT_CLIENT_IP_HEADER"),
+	}
+	for class, env := range map[string]string{
+		rateClassLookup: "RATE_LIMIT_LOOKUP",
+		rateClassSearch: "RATE_LIMIT_SEARCH",
+		rateClassBulk:   "RATE_LIMIT_BULK",
+	} {
+		if v := os.Getenv(env); v != "" {
+			limit, err := parseRateLimit(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", env, err)
+			}
+			cfg.limits[class] = limit
+		}
+	}
+	return cfg, nil
+}
+
+// rateLimiter keeps a token bucket for each client and class of endpoint. Clients are
+// authenticated users, or client IPs when authentication is disabled. Idle buckets are swept
+// so clients which stop calling don't hold memory.
+type rateLimiter struct {
+	cfg    rateLimitConfig
+	logger log.Logger
+
+	mu        sync.Mutex
+	buckets   map[string]map[string]*tokenBucket // keyed by class then client
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns nil unless a RATE_LIMIT_* class has a limit, so requests skip the
+// bucket lookups entirely when rate limiting is off.
+func newRateLimiter(logger log.Logger, cfg rateLimitConfig) *rateLimiter {
+	if len(cfg.limits) == 0 {
+		return nil
+	}
+	l := &rateLimiter{
+		cfg:     cfg,
+		logger:  logger,
+		buckets: make(map[string]map[string]*tokenBucket),
+		now:     time.Now,
+	}
+	for class := range cfg.limits {
+		l.buckets[class] = make(map[string]*tokenBucket)
+	}
+	l.lastSweep = l.now()
+	return l
+}
+
+// allow takes a token from the client's bucket for class. When it's empty allow returns false
+// and how long until a token is added.
+func (l *rateLimiter) allow(class, client string) (bool, time.Duration) {
+	limit, ok := l.cfg.limits[class]
+	if !ok {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[class][client]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.burst), last: now}
+		l.buckets[class][client] = bucket
+		rateLimitClients.With("class", class).Set(float64(len(l.buckets[class])))
+	}
+	bucket.tokens = min(float64(limit.burst), bucket.tokens+now.Sub(bucket.last).Seconds()*limit.perSecond())
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / limit.perSecond() * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops the buckets which have refilled, since new buckets start full anyway
+func (l *rateLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for class, buckets := range l.buckets {
+		limit := l.cfg.limits[class]
+		for client, bucket := range buckets {
+			if now.Sub(bucket.last) >= limit.period {
+				delete(buckets, client)
+			}
+		}
+		rateLimitClients.With("class", class).Set(float64(len(buckets)))
+	}
+}
+
+// client identifies who made r, which is run after authentication
+func (l *rateLimiter) client(r *http.Request) string {
+	if p, ok := principalFromContext(r.Context()); ok {
+		return "user:" + p.userID
+	}
+	if l.cfg.clientIPHeader != "" {
+		if v := r.Header.Get(l.cfg.clientIPHeader); v != "" {
+			addrs := strings.Split(v, ",")
+			return "ip:" + strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// middleware responds with 429 Too Many Requests, and a Retry-After header, once a client
+// has used its tokens for the endpoint's class
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := rateLimitClass(r)
+		if class == "" || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := l.client(r)
+		if ok, wait := l.allow(class, client); !ok {
+			rateLimitedRequests.With("class", class).Add(1)
+			l.logger.With(log.Fields{
+				"requestID": log.String(moovhttp.GetRequestID(r)),
+				"client":    log.String(client),
+			}).Logf("rate limited %s request to %s", class, r.URL.Path)
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": errRateLimited.Error(),
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
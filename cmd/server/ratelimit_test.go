@@ -0,0 +1,168 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	cases := map[string]rateLimit{
+		"10/s":    {burst: 10, period: time.Second},
+		"600/m":   {burst: 600, period: time.Minute},
+		"1000/h":  {burst: 1000, period: time.Hour},
+		"50/30s":  {burst: 50, period: 30 * time.Second},
+		" 5/24h ": {burst: 5, period: 24 * time.Hour},
+	}
+	for v, expected := range cases {
+		limit, err := parseRateLimit(v)
+		require.NoError(t, err, v)
+		require.Equal(t, expected, limit, v)
+	}
+	require.Equal(t, 10.0, rateLimit{burst: 600, period: time.Minute}.perSecond())
+
+	for _, v := range []string{"10", "0/s", "x/s", "10/d", "10/-1s"} {
+		_, err := parseRateLimit(v)
+		require.Error(t, err, v)
+	}
+}
+
+func TestReadRateLimitConfig(t *testing.T) {
+	cfg, err := readRateLimitConfig()
+	require.NoError(t, err)
+	require.Empty(t, cfg.limits)
+	require.Nil(t, newRateLimiter(log.NewNopLogger(), cfg))
+
+	t.Setenv("RATE_LIMIT_LOOKUP", "100/s")
+	t.Setenv("RATE_LIMIT_SEARCH", "600/m")
+	t.Setenv("RATE_LIMIT_CLIENT_IP_HEADER", "X-Forwarded-For")
+	cfg, err = readRateLimitConfig()
+	require.NoError(t, err)
+	require.Equal(t, map[string]rateLimit{
+		rateClassLookup: {burst: 100, period: time.Second},
+		rateClassSearch: {burst: 600, period: time.Minute},
+	}, cfg.limits)
+	require.Equal(t, "X-Forwarded-For", cfg.clientIPHeader)
+
+	t.Setenv("RATE_LIMIT_BULK", "lots")
+	_, err = readRateLimitConfig()
+	require.ErrorContains(t, err, "invalid RATE_LIMIT_BULK")
+}
+
+func TestRateLimitClass(t *testing.T) {
+	cases := map[string]string{
+		"/fed/ach/search?routingNumber=044112187": rateClassLookup,
+		"/fed/wire/search?routingNumber=0441":     rateClassLookup,
+		"/fed/ach/search?name=farmers":            rateClassSearch,
+		"/fed/wire/search?state=OH":               rateClassSearch,
+		"/fed/ach/search?routingNumber=1&state=O": rateClassSearch,
+		"/fed/ach/changes?since=2024-01-01":       rateClassBulk,
+		"/fed/wire/changes":                       rateClassBulk,
+		"/ping":                                   "",
+	}
+	for path, expected := range cases {
+		require.Equal(t, expected, rateLimitClass(httptest.NewRequest("GET", path, nil)), path)
+	}
+}
+
+func TestRateLimiter__allow(t *testing.T) {
+	l := newRateLimiter(log.NewNopLogger(), rateLimitConfig{
+		limits: map[string]rateLimit{rateClassSearch: {burst: 2, period: time.Second}},
+	})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.allow(rateClassSearch, "a")
+	require.True(t, ok)
+	ok, _ = l.allow(rateClassSearch, "a")
+	require.True(t, ok)
+	ok, wait := l.allow(rateClassSearch, "a")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	// Clients and classes have their own buckets
+	ok, _ = l.allow(rateClassSearch, "b")
+	require.True(t, ok)
+	ok, _ = l.allow(rateClassLookup, "a")
+	require.True(t, ok)
+
+	// Tokens are added over time, up to the burst
+	now = now.Add(250 * time.Millisecond)
+	ok, wait = l.allow(rateClassSearch, "a")
+	require.False(t, ok)
+	require.Equal(t, 250*time.Millisecond, wait)
+
+	now = now.Add(250 * time.Millisecond)
+	ok, _ = l.allow(rateClassSearch, "a")
+	require.True(t, ok)
+
+	// Idle clients are dropped once their bucket refills
+	now = now.Add(rateLimitSweepInterval)
+	l.allow(rateClassSearch, "c")
+	require.Len(t, l.buckets[rateClassSearch], 1)
+}
+
+func TestRateLimiter__middleware(t *testing.T) {
+	l := newRateLimiter(log.NewNopLogger(), rateLimitConfig{
+		limits:         map[string]rateLimit{rateClassSearch: {burst: 1, period: 10 * time.Second}},
+		clientIPHeader: "X-Forwarded-For",
+	})
+
+	router := mux.NewRouter()
+	router.Use(l.middleware)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	router.Methods("GET").Path("/fed/ach/search").HandlerFunc(handler)
+	router.Methods("GET").Path("/ping").HandlerFunc(handler)
+
+	get := func(path string, modify func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if modify != nil {
+			modify(req)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/fed/ach/search?name=farmers", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = get("/fed/ach/search?name=farmers", nil)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "10", w.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"error":"rate limit exceeded"}`, w.Body.String())
+
+	// Unlimited routes and classes pass through
+	require.Equal(t, http.StatusOK, get("/ping", nil).Code)
+	require.Equal(t, http.StatusOK, get("/fed/ach/search?routingNumber=044112187", nil).Code)
+
+	// The last forwarded address is the client
+	forwarded := func(addrs string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("X-Forwarded-For", addrs) }
+	}
+	require.Equal(t, http.StatusOK, get("/fed/ach/search?name=farmers", forwarded("10.0.0.1, 192.168.1.2")).Code)
+	require.Equal(t, http.StatusTooManyRequests, get("/fed/ach/search?name=farmers", forwarded("10.0.0.9, 192.168.1.2")).Code)
+
+	// Authenticated users are limited instead of their address
+	user := func(userID string) func(*http.Request) {
+		return func(r *http.Request) {
+			*r = *r.WithContext(context.WithValue(r.Context(), principalKey{}, principal{userID: userID}))
+		}
+	}
+	require.Equal(t, http.StatusOK, get("/fed/ach/search?name=farmers", user("batch-job")).Code)
+	require.Equal(t, http.StatusTooManyRequests, get("/fed/ach/search?name=farmers", user("batch-job")).Code)
+	require.Equal(t, http.StatusOK, get("/fed/ach/search?name=farmers", user("ops")).Code)
+}
@@ -5,120 +5,175 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/moov-io/base/log"
 	"github.com/moov-io/fed"
-	"github.com/moov-io/fed/pkg/download"
+	"github.com/moov-io/fed/data"
+	"github.com/moov-io/fed/pkg/archive"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
 	fedachFilenames  = []string{"FedACHdir.txt", "fedachdir.json", "fedach.txt", "fedach.json"}
 	fedwireFilenames = []string{"fpddir.json", "fpddir.txt", "fedwire.txt", "fedwire.json"}
+
+	// embeddedData is the last resort source of directory files, it's only set in binaries
+	// built with the embeddata tag
+	embeddedData = data.FS
 )
 
-func fedACHDataFile(logger log.Logger) (io.Reader, error) {
-	initialDir := os.Getenv("INITIAL_DATA_DIRECTORY")
-	file, err := inspectInitialDataDirectory(logger, initialDir, fedachFilenames)
+func fedACHDataFile(logger log.Logger, sources []DataSource) (io.Reader, error) {
+	return fetchDataFile(context.Background(), logger, sources, "fedach")
+}
+
+func fedWireDataFile(logger log.Logger, sources []DataSource) (io.Reader, error) {
+	return fetchDataFile(context.Background(), logger, sources, "fedwire")
+}
+
+// inspectInitialDataDirectory looks for a file in dir matching needles. Gzip compressed files (e.g. FedACHdir.txt.gz)
+// and zip archives which contain a matching file are also accepted.
+func inspectInitialDataDirectory(logger log.Logger, dir string, needles []string) (io.Reader, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("inspecting %s for FedACH file failed: %w", initialDir, err)
-	}
-	if file != nil {
-		logger.Info().Logf("found FedACH file in %s", initialDir)
-		return file, nil
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("readdir on %s failed: %w", dir, err)
 	}
 
-	file, err = attemptFileDownload(logger, "fedach")
-	if err != nil && !errors.Is(err, download.ErrMissingConfigValue) {
-		return nil, fmt.Errorf("problem downloading fedach: %v", err)
-	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		_, filename := filepath.Split(entry.Name())
+		if !matchesDataFilename(filename, needles) {
+			continue
+		}
 
-	if file != nil {
-		logger.Info().Log("search: downloaded ACH file")
-		return file, nil
+		where := filepath.Join(dir, entry.Name())
+		fd, err := archive.Open(where, needles)
+		if err != nil {
+			if errors.Is(err, archive.ErrNoMatchingFile) {
+				// zip archives are allowed to hold only the other directory
+				continue
+			}
+			return nil, fmt.Errorf("opening %s failed: %w", where, err)
+		}
+		return fd, nil
 	}
 
-	path := readDataFilepath("FEDACH_DATA_PATH", "./data/FedACHdir.txt")
-	logger.Logf("search: loading %s for ACH data", path)
+	return nil, nil
+}
+
+func matchesDataFilename(filename string, needles []string) bool {
+	if strings.EqualFold(filepath.Ext(filename), ".zip") {
+		return true
+	}
+	for idx := range needles {
+		if strings.EqualFold(filename, needles[idx]) || strings.EqualFold(filename, needles[idx]+".gz") {
+			return true
+		}
+	}
+	return false
+}
 
-	file, err = os.Open(path)
+// openDataFile opens a configured data file, which may be compressed. Zip archives are searched for
+// a file matching needles and otherwise need to contain exactly one file.
+func openDataFile(where string, needles []string) (io.Reader, error) {
+	fd, err := archive.Open(where, needles)
+	if errors.Is(err, archive.ErrNoMatchingFile) {
+		fd, err = archive.Open(where, nil)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("problem opening %s: %v", path, err)
+		return nil, err
 	}
-	return file, nil
+	return fd, nil
 }
 
-func fedWireDataFile(logger log.Logger) (io.Reader, error) {
-	initialDir := os.Getenv("INITIAL_DATA_DIRECTORY")
-	file, err := inspectInitialDataDirectory(logger, initialDir, fedwireFilenames)
+func decompressDownload(file io.Reader, needles []string) (io.Reader, error) {
+	bs, err := io.ReadAll(file)
 	if err != nil {
-		return nil, fmt.Errorf("inspecting %s for FedWire file failed: %w", initialDir, err)
+		return nil, fmt.Errorf("reading download: %w", err)
 	}
-	if file != nil {
-		logger.Info().Logf("found FedWire file in %s", initialDir)
-		return file, nil
+	r, err := archive.NewReader(bytes.NewReader(bs), needles)
+	if errors.Is(err, archive.ErrNoMatchingFile) {
+		r, err = archive.NewReader(bytes.NewReader(bs), nil)
 	}
-
-	file, err = attemptFileDownload(logger, "fedwire")
-	if err != nil && !errors.Is(err, download.ErrMissingConfigValue) {
-		return nil, fmt.Errorf("problem downloading fedwire: %v", err)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing download: %w", err)
 	}
+	return r, nil
+}
 
-	if file != nil {
-		logger.Info().Log("search: downloaded Wire file")
-		return file, nil
-	}
+// dataOrigin describes where a list was read from for its ListStats
+type dataOrigin struct {
+	source       string // name of the DataSource
+	embedded     bool
+	downloadedAt time.Time // set for lists read from the download cache
 
-	path := readDataFilepath("FEDWIRE_DATA_PATH", "./data/fpddir.txt")
-	logger.Logf("search: loading %s for Wire data", path)
+	// commit is called once the list is loaded, see accept
+	commit func() error
+}
 
-	file, err = os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("problem opening %s: %v", path, err)
-	}
-	return file, nil
+// originFile is a list annotated with where it was read from
+type originFile struct {
+	io.Reader
+	origin dataOrigin
 }
 
-func inspectInitialDataDirectory(logger log.Logger, dir string, needles []string) (io.Reader, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("readdir on %s failed: %w", dir, err)
+func (f *originFile) Close() error {
+	if closer, ok := f.Reader.(io.Closer); ok {
+		return closer.Close()
 	}
+	return nil
+}
 
-	for _, entry := range entries {
-		_, filename := filepath.Split(entry.Name())
-
-		for idx := range needles {
-			if strings.EqualFold(filename, needles[idx]) {
-				where := filepath.Join(dir, entry.Name())
+func readDataOrigin(r io.Reader) dataOrigin {
+	if file, ok := r.(*originFile); ok {
+		return file.origin
+	}
+	return dataOrigin{}
+}
 
-				fd, err := os.Open(where)
-				if err != nil {
-					return nil, fmt.Errorf("opening %s failed: %w", where, err)
-				}
-				return fd, nil
-			}
-		}
+// accept records that the list was loaded, which caches lists downloaded from the FRB
+func (o dataOrigin) accept() error {
+	if o.commit == nil {
+		return nil
 	}
+	return o.commit()
+}
 
-	return nil, nil
+func (o dataOrigin) stats() ListStats {
+	return ListStats{
+		Embedded:     o.embedded,
+		Cached:       !o.downloadedAt.IsZero(),
+		DownloadedAt: o.downloadedAt,
+	}
 }
 
-func attemptFileDownload(logger log.Logger, listName string) (io.Reader, error) {
-	logger.Logf("download: attempting %s", listName)
-	client, err := download.NewClient(nil)
+// openEmbeddedData returns the embedded directory file or nil when the binary was built without them
+func openEmbeddedData(logger log.Logger, filename string) io.Reader {
+	if embeddedData == nil {
+		return nil
+	}
+	fd, err := embeddedData.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("client setup: %w", err)
+		logger.Warn().Logf("problem opening embedded %s: %v", filename, err)
+		return nil
 	}
-	return client.GetList(listName)
+	logger.Error().With(log.Fields{
+		"file": log.String(filename),
+	}).Logf("USING EMBEDDED DATA: %s was built into this binary and is likely out of date, configure a data path or downloads for current data", filename)
+	return &originFile{Reader: fd, origin: dataOrigin{embedded: true}}
 }
 
 func readDataFilepath(env, fallback string) string {
@@ -128,61 +183,136 @@ func readDataFilepath(env, fallback string) string {
 	return fallback
 }
 
+// dictionary is implemented by fed.ACHDictionary and fed.WIREDictionary
+type dictionary interface {
+	snapshotter
+	Read(r io.Reader) error
+	Compact()
+}
+
+// directory describes how one list is parsed and named in logs and errors
+type directory[D dictionary] struct {
+	listName string // fedach or fedwire
+	filename string // file named in parsing errors
+	label    string // FedACH or FedWire
+
+	newDictionary func() D
+	records       func(D) int
+}
+
+var (
+	achDirectory = directory[*fed.ACHDictionary]{
+		listName:      "fedach",
+		filename:      "FedACHdir.txt",
+		label:         "FedACH",
+		newDictionary: fed.NewACHDictionary,
+		records:       func(dict *fed.ACHDictionary) int { return len(dict.ACHParticipants) },
+	}
+	wireDirectory = directory[*fed.WIREDictionary]{
+		listName:      "fedwire",
+		filename:      "fpddir.txt",
+		label:         "FedWire",
+		newDictionary: fed.NewWIREDictionary,
+		records:       func(dict *fed.WIREDictionary) int { return len(dict.WIREParticipants) },
+	}
+)
+
 // readFEDACHData opens and reads FedACHdir.txt then runs ACHDictionary.Read() to
 // parse and define ACHDictionary properties
 func (s *searcher) readFEDACHData(reader io.Reader) error {
+	return readDirectory(s, achDirectory, reader, func(dict *fed.ACHDictionary, origin dataOrigin) {
+		// Swap the new dictionary in whole so concurrent searches never observe a partial read
+		s.Lock()
+		s.ACHDictionary = dict
+		s.achOrigin = origin
+		s.achLoadedAt = time.Now()
+		s.Unlock()
+		s.achCache.flush()
+	})
+}
+
+// readFEDWIREData opens and reads fpddir.txt then runs WIREDictionary.Read() to
+// parse and define WIREDictionary properties
+func (s *searcher) readFEDWIREData(reader io.Reader) error {
+	return readDirectory(s, wireDirectory, reader, func(dict *fed.WIREDictionary, origin dataOrigin) {
+		// Swap the new dictionary in whole so concurrent searches never observe a partial read
+		s.Lock()
+		s.WIREDictionary = dict
+		s.wireOrigin = origin
+		s.wireLoadedAt = time.Now()
+		s.Unlock()
+		s.wireCache.flush()
+	})
+}
+
+// readDirectory parses reader, or loads its snapshot, and passes the checked dictionary to swap
+func readDirectory[D dictionary](s *searcher, dir directory[D], reader io.Reader, swap func(D, dataOrigin)) error {
+	span := startDataLoadSpan(dir.listName)
+	start := time.Now()
+	err := loadDirectory(s, dir, reader, swap)
+	dataRefreshDuration.With("list", dir.listName).Observe(time.Since(start).Seconds())
+	s.recordLoad(dir.listName, err)
+
+	status := s.directoryStatus(dir.listName)
+	endSpan(span, err, attribute.Int("fed.records", status.Records), attribute.String("fed.source", status.Source))
+	return err
+}
+
+func loadDirectory[D dictionary](s *searcher, dir directory[D], reader io.Reader, swap func(D, dataOrigin)) error {
 	if s.logger != nil {
-		s.logger.Logf("Read of FED ACH data from %T", reader)
+		s.logger.Logf("Read of %s data from %T", dir.label, reader)
 	}
 
 	if closer, ok := reader.(io.Closer); ok {
 		defer closer.Close()
 	}
+	origin := readDataOrigin(reader)
 
-	s.ACHDictionary = fed.NewACHDictionary()
-	if err := s.ACHDictionary.Read(reader); err != nil {
-		return fmt.Errorf("ERROR: reading FedACHdir.txt %v", err)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("ERROR: reading %s %v", dir.filename, err)
 	}
 
-	recordCount := len(s.ACHDictionary.ACHParticipants)
-	if recordCount <= 0 {
-		return errors.New("read zero records from FedACH file")
-	} else {
-		if s.logger != nil {
-			s.logger.With(log.Fields{
-				"records": log.Int(recordCount),
-			}).Logf("Finished refresh of ACH data")
+	parseStart := time.Now()
+	dict := dir.newDictionary()
+	fromSnapshot := loadSnapshot(s.snapshotLogger(), s.snapshotDir, dir.listName, data, dict)
+	if !fromSnapshot {
+		dict = dir.newDictionary()
+		if err := dict.Read(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("ERROR: reading %s %v", dir.filename, err)
 		}
 	}
+	dataParseDuration.With("list", dir.listName).Observe(time.Since(parseStart).Seconds())
 
-	return nil
-}
-
-// readFEDWIREData opens and reads fpddir.txt then runs WIREDictionary.Read() to
-// parse and define WIREDictionary properties
-func (s *searcher) readFEDWIREData(reader io.Reader) error {
+	recordCount := dir.records(dict)
+	if recordCount <= 0 {
+		return fmt.Errorf("read zero records from %s file", dir.label)
+	}
+	// Shrinking is measured against the dictionary being searched, which directoryStatus reads under s.RLock()
+	loadedRecords := s.directoryStatus(dir.listName).Records
+	if err := s.bounds.check(dir.listName, loadedRecords, recordCount); err != nil {
+		return fmt.Errorf("rejecting %s data: %w", dir.label, err)
+	}
 	if s.logger != nil {
-		s.logger.Logf("Read of FED Wire data from %T", reader)
+		s.logger.With(log.Fields{
+			"records": log.Int(recordCount),
+		}).Logf("Finished refresh of %s data", dir.label)
 	}
 
-	if closer, ok := reader.(io.Closer); ok {
-		defer closer.Close()
+	if !fromSnapshot {
+		if err := writeSnapshot(s.snapshotDir, dir.listName, dict); err != nil && s.logger != nil {
+			s.logger.Warn().Logf("problem writing %s snapshot: %v", dir.listName, err)
+		}
 	}
 
-	s.WIREDictionary = fed.NewWIREDictionary()
-	if err := s.WIREDictionary.Read(reader); err != nil {
-		return fmt.Errorf("ERROR: reading fpddir.txt %v", err)
+	if s.compact {
+		dict.Compact()
 	}
 
-	recordCount := len(s.WIREDictionary.WIREParticipants)
-	if recordCount <= 0 {
-		return errors.New("read zero records from FedWire file")
-	} else {
-		if s.logger != nil {
-			s.logger.With(log.Fields{
-				"records": log.Int(recordCount),
-			}).Logf("Finished refresh of WIRE data")
-		}
+	swap(dict, origin)
+
+	if err := origin.accept(); err != nil && s.logger != nil {
+		s.logger.Warn().Logf("problem caching %s download: %v", dir.listName, err)
 	}
 
 	return nil
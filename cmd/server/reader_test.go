@@ -5,6 +5,12 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
@@ -14,11 +20,21 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func testDataSources(t *testing.T, logger log.Logger) []DataSource {
+	t.Helper()
+
+	sources, err := defaultDataSources(logger)
+	require.NoError(t, err)
+	return sources
+}
+
 func TestReader__fedACHDataFile(t *testing.T) {
 	t.Setenv("FRB_ROUTING_NUMBER", "")
 	t.Setenv("FRB_DOWNLOAD_CODE", "")
+	setEmbeddedData(t, nil)
 
-	r, err := fedACHDataFile(log.NewTestLogger())
+	logger := log.NewTestLogger()
+	r, err := fedACHDataFile(logger, testDataSources(t, logger))
 	require.Nil(t, r)
 	require.ErrorContains(t, err, "no such file or directory")
 }
@@ -26,12 +42,58 @@ func TestReader__fedACHDataFile(t *testing.T) {
 func TestReader__fedWireDataFile(t *testing.T) {
 	t.Setenv("FRB_ROUTING_NUMBER", "")
 	t.Setenv("FRB_DOWNLOAD_CODE", "")
+	setEmbeddedData(t, nil)
 
-	r, err := fedWireDataFile(log.NewTestLogger())
+	logger := log.NewTestLogger()
+	r, err := fedWireDataFile(logger, testDataSources(t, logger))
 	require.Nil(t, r)
 	require.ErrorContains(t, err, "no such file or directory")
 }
 
+func setEmbeddedData(t *testing.T, fsys fs.FS) {
+	t.Helper()
+
+	prev := embeddedData
+	embeddedData = fsys
+	t.Cleanup(func() { embeddedData = prev })
+}
+
+func TestReader__embeddedData(t *testing.T) {
+	t.Setenv("FRB_ROUTING_NUMBER", "")
+	t.Setenv("FRB_DOWNLOAD_CODE", "")
+	setEmbeddedData(t, os.DirFS(filepath.Join("..", "..", "data")))
+
+	logger := log.NewTestLogger()
+	achFile, err := fedACHDataFile(logger, testDataSources(t, logger))
+	require.NoError(t, err)
+	require.True(t, readDataOrigin(achFile).embedded)
+
+	wireFile, err := fedWireDataFile(logger, testDataSources(t, logger))
+	require.NoError(t, err)
+	require.True(t, readDataOrigin(wireFile).embedded)
+
+	s := &searcher{logger: logger}
+	require.NoError(t, setupSearcher(logger, s, achFile, wireFile))
+
+	stats := s.ACHStats()
+	require.True(t, stats.Embedded)
+	require.Equal(t, "2018-12-04", stats.Latest.Format("2006-01-02"))
+	require.Greater(t, stats.AgeSeconds, int64(0))
+	require.True(t, s.WIREStats().Embedded)
+
+	// Configured paths are never replaced by embedded data
+	t.Setenv("FEDACH_DATA_PATH", filepath.Join(t.TempDir(), "missing.txt"))
+	_, err = fedACHDataFile(logger, testDataSources(t, logger))
+	require.ErrorContains(t, err, "no such file or directory")
+
+	// Data read from elsewhere isn't reported as embedded
+	fd, err := os.Open(filepath.Join("..", "..", "data", "FedACHdir.txt"))
+	require.NoError(t, err)
+	require.NoError(t, s.readFEDACHData(fd))
+	s.precompute()
+	require.False(t, s.ACHStats().Embedded)
+}
+
 func TestReader_inspectInitialDataDirectory(t *testing.T) {
 	logger := log.NewNopLogger()
 
@@ -59,6 +121,95 @@ func TestReader_inspectInitialDataDirectory(t *testing.T) {
 	require.Equal(t, filepath.Join(dir, "fedwire.txt"), file.Name())
 }
 
+func TestReader_inspectInitialDataDirectory__compressed(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	dir := t.TempDir()
+
+	// The FRB distributes both directories in one archive
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range map[string]string{"FedACHdir.txt": "ach", "fpddir.txt": "wire"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		w.Write([]byte(contents))
+	}
+	require.NoError(t, zw.Close())
+	err := os.WriteFile(filepath.Join(dir, "directories.zip"), buf.Bytes(), 0600)
+	require.NoError(t, err)
+
+	fd, err := inspectInitialDataDirectory(logger, dir, fedachFilenames)
+	require.NoError(t, err)
+	bs, _ := io.ReadAll(fd)
+	require.Equal(t, "ach", string(bs))
+
+	fd, err = inspectInitialDataDirectory(logger, dir, fedwireFilenames)
+	require.NoError(t, err)
+	bs, _ = io.ReadAll(fd)
+	require.Equal(t, "wire", string(bs))
+
+	// gzip files are preferred by name, zip archives without a match are skipped
+	dir = t.TempDir()
+	buf.Reset()
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("gzipped ach"))
+	require.NoError(t, gz.Close())
+	err = os.WriteFile(filepath.Join(dir, "fedach.json.gz"), buf.Bytes(), 0600)
+	require.NoError(t, err)
+
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	w, _ := zw.Create("fpddir.txt")
+	w.Write([]byte("wire"))
+	require.NoError(t, zw.Close())
+	err = os.WriteFile(filepath.Join(dir, "a-wire.zip"), buf.Bytes(), 0600)
+	require.NoError(t, err)
+
+	fd, err = inspectInitialDataDirectory(logger, dir, fedachFilenames)
+	require.NoError(t, err)
+	bs, _ = io.ReadAll(fd)
+	require.Equal(t, "gzipped ach", string(bs))
+}
+
+func TestReader__openDataFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// single file archives can use any name
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("FedACHdir-20250101.txt")
+	w.Write([]byte("ach"))
+	require.NoError(t, zw.Close())
+
+	where := filepath.Join(dir, "ach.zip")
+	require.NoError(t, os.WriteFile(where, buf.Bytes(), 0600))
+
+	fd, err := openDataFile(where, fedachFilenames)
+	require.NoError(t, err)
+	bs, _ := io.ReadAll(fd)
+	require.Equal(t, "ach", string(bs))
+
+	_, err = openDataFile(filepath.Join(dir, "missing.txt"), fedachFilenames)
+	require.ErrorContains(t, err, "no such file or directory")
+}
+
+func TestReader__decompressDownload(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"fedACHParticipants":{}}`))
+	require.NoError(t, gz.Close())
+
+	r, err := decompressDownload(&buf, fedachFilenames)
+	require.NoError(t, err)
+	bs, _ := io.ReadAll(r)
+	require.Equal(t, `{"fedACHParticipants":{}}`, string(bs))
+
+	r, err = decompressDownload(bytes.NewBufferString("plain"), fedachFilenames)
+	require.NoError(t, err)
+	bs, _ = io.ReadAll(r)
+	require.Equal(t, "plain", string(bs))
+}
+
 func TestReader__readFEDACHData(t *testing.T) {
 	s := &searcher{logger: log.NewNopLogger()}
 
@@ -109,6 +260,28 @@ func TestReader__readFEDWIREData(t *testing.T) {
 	}
 }
 
+func TestReader__readCompact(t *testing.T) {
+	s := &searcher{logger: log.NewNopLogger(), compact: true}
+
+	achFile, err := os.Open(filepath.Join("..", "..", "data", "FedACHdir.txt"))
+	require.NoError(t, err)
+	require.NoError(t, s.readFEDACHData(achFile))
+
+	wireFile, err := os.Open(filepath.Join("..", "..", "data", "fpddir.txt"))
+	require.NoError(t, err)
+	require.NoError(t, s.readFEDWIREData(wireFile))
+
+	achP, _, err := s.ACHFindRoutingNumberOnly(context.Background(), 1, "044112187")
+	require.NoError(t, err)
+	require.Len(t, achP, 1)
+	require.Same(t, achP[0], s.ACHDictionary.IndexACHRoutingNumber["044112187"])
+
+	wireP, _, err := s.WIREFindRoutingNumberOnly(context.Background(), 1, "091905114")
+	require.NoError(t, err)
+	require.Len(t, wireP, 1)
+	require.Same(t, wireP[0], s.WIREDictionary.IndexWIRERoutingNumber["091905114"])
+}
+
 func TestReader__readDataFilepath(t *testing.T) {
 	if v := readDataFilepath("MISSING", "value"); v != "value" {
 		t.Errorf("got %q", v)
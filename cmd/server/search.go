@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -20,87 +21,198 @@ import (
 var (
 	errNoSearchParams                  = errors.New("missing search parameter(s)")
 	softResultsLimit, hardResultsLimit = 100, 500
+
+	defaultSearchTimeout = 10 * time.Second
 )
 
+// readSearchTimeout parses the SEARCH_TIMEOUT value, an empty value is the default timeout
+// and zero disables the timeout.
+func readSearchTimeout(v string) (time.Duration, error) {
+	if v == "" {
+		return defaultSearchTimeout, nil
+	}
+	dur, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if dur < 0 {
+		return 0, fmt.Errorf("negative search timeout: %v", dur)
+	}
+	return dur, nil
+}
+
 // searcher defines a searcher struct
 type searcher struct {
 	ACHDictionary  *fed.ACHDictionary
 	WIREDictionary *fed.WIREDictionary
-	sync.RWMutex   // protects all above fields
+	sync.RWMutex   // protects all above fields and the stats below
 
 	achStats  ListStats
 	wireStats ListStats
 
+	// achOrigin and wireOrigin describe where the data was read from
+	achOrigin  dataOrigin
+	wireOrigin dataOrigin
+
+	// achLoadedAt and wireLoadedAt are when the data was loaded
+	achLoadedAt  time.Time
+	wireLoadedAt time.Time
+
+	// achLastError and wireLastError are the last problem reading each directory, cleared once it loads
+	achLastError  loadError
+	wireLastError loadError
+
+	// bounds are checked before new data replaces the loaded dictionaries
+	bounds dataBounds
+
+	// searchTimeout limits how long a single search can run, zero means no limit
+	searchTimeout time.Duration
+
+	// describeCodes encodes participant codes in responses with their descriptions
+	describeCodes bool
+
+	// compact is set to call Compact on dictionaries as they're read
+	compact bool
+
+	// snapshotDir holds snapshots of the dictionaries, which are loaded instead of reading
+	// the same data again. Snapshots are disabled when empty.
+	snapshotDir string
+
+	// achCache and wireCache hold recent search results, they're flushed when data is reloaded
+	achCache  *searchCache[[]*fed.ACHParticipant]
+	wireCache *searchCache[[]*fed.WIREParticipant]
+
+	// audit records who searched for what, it's nil unless AUDIT_LOG is set
+	audit *auditLogger
+
 	logger log.Logger
 }
 
 type ListStats struct {
 	Records int       `json:"records"`
 	Latest  time.Time `json:"latest"`
+
+	// InvalidDates is how many records have a revision date which couldn't be parsed, they're
+	// left out of Latest
+	InvalidDates int `json:"invalidDates,omitempty"`
+
+	// AgeSeconds is how long ago the latest record was revised
+	AgeSeconds int64 `json:"ageSeconds,omitempty"`
+
+	// Embedded is true when the data was built into the binary and is likely out of date
+	Embedded bool `json:"embedded,omitempty"`
+
+	// Cached is true when the data was read from the download cache, DownloadedAt is when
+	// it was downloaded
+	Cached       bool      `json:"cached,omitempty"`
+	DownloadedAt time.Time `json:"downloadedAt,omitzero"`
 }
 
-func (s *searcher) precompute() error {
-	if err := s.precomputeACHStats(); err != nil {
-		return fmt.Errorf("precomputing ACH stats: %w", err)
+// withAge returns a copy of stats with AgeSeconds set from Latest
+func (stats ListStats) withAge(now time.Time) *ListStats {
+	if !stats.Latest.IsZero() {
+		stats.AgeSeconds = int64(now.Sub(stats.Latest).Seconds())
 	}
-	if err := s.precomputeWireStats(); err != nil {
-		return fmt.Errorf("precomputing wire stats: %w", err)
+	return &stats
+}
+
+func (s *searcher) precompute() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.precomputeACHStats()
+	s.precomputeWireStats()
+	s.warnInvalidDates("ACH", s.achStats)
+	s.warnInvalidDates("Wire", s.wireStats)
+	s.warnEmbeddedData("ACH", s.achStats)
+	s.warnEmbeddedData("Wire", s.wireStats)
+}
+
+// warnInvalidDates logs how many records have revision dates which couldn't be parsed
+func (s *searcher) warnInvalidDates(listName string, stats ListStats) {
+	if s.logger == nil || stats.InvalidDates == 0 {
+		return
 	}
-	return nil
+	s.logger.Warn().With(log.Fields{
+		"invalid_dates": log.Int(stats.InvalidDates),
+	}).Logf("skipped %d of %d %s records with invalid revision dates", stats.InvalidDates, stats.Records, listName)
 }
 
-func (s *searcher) precomputeACHStats() error {
-	if s.ACHDictionary != nil {
-		s.achStats.Records = len(s.ACHDictionary.ACHParticipants)
+// warnEmbeddedData logs how old the data is when it was built into the binary
+func (s *searcher) warnEmbeddedData(listName string, stats ListStats) {
+	if s.logger == nil || !stats.Embedded {
+		return
 	}
+	age := stats.withAge(time.Now()).AgeSeconds
+	s.logger.Error().With(log.Fields{
+		"records":     log.Int(stats.Records),
+		"latest":      log.Time(stats.Latest),
+		"age_seconds": log.Int64(age),
+	}).Logf("searching embedded %s data last revised %s, results are likely out of date", listName, stats.Latest.Format("2006-01-02"))
+}
 
-	for idx := range s.ACHDictionary.ACHParticipants {
-		t, err := readDate(s.ACHDictionary.ACHParticipants[idx].Revised)
-		if err != nil {
-			return fmt.Errorf("parsing ACH record date: %w", err)
+func (s *searcher) precomputeACHStats() {
+	s.achStats = s.achOrigin.stats()
+	if s.ACHDictionary == nil {
+		return
+	}
+	s.achStats.Records = len(s.ACHDictionary.ACHParticipants)
+
+	for _, p := range s.ACHDictionary.ACHParticipants {
+		if p.RevisedAt == nil {
+			if strings.TrimSpace(p.Revised) != "" {
+				s.achStats.InvalidDates++
+			}
+			continue
 		}
-		if s.achStats.Latest.Before(t) {
-			s.achStats.Latest = t
+		if s.achStats.Latest.Before(*p.RevisedAt) {
+			s.achStats.Latest = *p.RevisedAt
 		}
 	}
-
-	return nil
 }
 
-func (s *searcher) precomputeWireStats() error {
-	if s.WIREDictionary != nil {
-		s.wireStats.Records = len(s.WIREDictionary.WIREParticipants)
+func (s *searcher) precomputeWireStats() {
+	s.wireStats = s.wireOrigin.stats()
+	if s.WIREDictionary == nil {
+		return
 	}
-
-	for idx := range s.WIREDictionary.WIREParticipants {
-		t, err := readDate(s.WIREDictionary.WIREParticipants[idx].Date)
-		if err != nil {
-			return fmt.Errorf("parsing WIRE record date: %w", err)
+	s.wireStats.Records = len(s.WIREDictionary.WIREParticipants)
+
+	for _, p := range s.WIREDictionary.WIREParticipants {
+		if p.RevisedAt == nil {
+			if strings.TrimSpace(p.Date) != "" {
+				s.wireStats.InvalidDates++
+			}
+			continue
 		}
-		if s.wireStats.Latest.Before(t) {
-			s.wireStats.Latest = t
+		if s.wireStats.Latest.Before(*p.RevisedAt) {
+			s.wireStats.Latest = *p.RevisedAt
 		}
 	}
+}
 
-	return nil
+// ACHStats returns a copy of the precomputed ACH list stats
+func (s *searcher) ACHStats() *ListStats {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.achStats.withAge(time.Now())
 }
 
-var (
-	acceptedDateFormats = []string{"060102", "010206", "20060102"}
-)
+// WIREStats returns a copy of the precomputed Wire list stats
+func (s *searcher) WIREStats() *ListStats {
+	s.RLock()
+	defer s.RUnlock()
 
-func readDate(value string) (tt time.Time, err error) {
-	value = strings.TrimSpace(value)
-	if value == "" {
-		return
-	}
-	for _, fmt := range acceptedDateFormats {
-		tt, err = time.Parse(fmt, value)
-		if err == nil {
-			return tt, nil
-		}
+	return s.wireStats.withAge(time.Now())
+}
+
+// searchContext returns the context a search for an HTTP request should run with
+func (s *searcher) searchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.searchTimeout > 0 {
+		return context.WithTimeout(ctx, s.searchTimeout)
 	}
-	return
+	return context.WithCancel(ctx)
 }
 
 // searchResponse defines a FEDACH search response
@@ -109,22 +221,50 @@ type searchResponse struct {
 	WIREParticipants []*fed.WIREParticipant `json:"wireParticipants,omitempty"`
 
 	Stats *ListStats `json:"stats"`
+
+	// Partial is true when the search timed out and only the results found so far are returned
+	Partial bool `json:"partial,omitempty"`
+}
+
+func (resp *searchResponse) described() interface{} {
+	return struct {
+		*searchResponse
+		ACHParticipants  []fed.DescribedACHParticipant  `json:"achParticipants,omitempty"`
+		WIREParticipants []fed.DescribedWIREParticipant `json:"wireParticipants,omitempty"`
+	}{
+		searchResponse:   resp,
+		ACHParticipants:  fed.DescribeACHParticipants(resp.ACHParticipants),
+		WIREParticipants: fed.DescribeWIREParticipants(resp.WIREParticipants),
+	}
+}
+
+// describer is a response which can encode its participants' codes with their descriptions
+type describer interface {
+	described() interface{}
+}
+
+// response returns resp for encoding, with participant codes described when describeCodes is set
+func (s *searcher) response(resp describer) interface{} {
+	if s.describeCodes {
+		return resp.described()
+	}
+	return resp
 }
 
 // ACHFindNameOnly finds ACH Participants by name only
-func (s *searcher) ACHFindNameOnly(limit int, participantName string) []*fed.ACHParticipant {
+func (s *searcher) ACHFindNameOnly(ctx context.Context, limit int, participantName string) ([]*fed.ACHParticipant, fed.SearchStats, error) {
 	s.RLock()
 	defer s.RUnlock()
 
-	return s.ACHDictionary.FinancialInstitutionSearch(participantName, limit)
+	return s.ACHDictionary.FinancialInstitutionSearchContext(ctx, participantName, limit)
 }
 
 // ACHFindRoutingNumberOnly finds ACH Participants by routing number only
-func (s *searcher) ACHFindRoutingNumberOnly(limit int, routingNumber string) ([]*fed.ACHParticipant, error) {
+func (s *searcher) ACHFindRoutingNumberOnly(ctx context.Context, limit int, routingNumber string) ([]*fed.ACHParticipant, fed.SearchStats, error) {
 	s.RLock()
 	defer s.RUnlock()
 
-	return s.ACHDictionary.RoutingNumberSearch(routingNumber, limit)
+	return s.ACHDictionary.RoutingNumberSearchContext(ctx, routingNumber, limit)
 }
 
 // ACHFindCityOnly finds ACH Participants by city only
@@ -151,17 +291,19 @@ func (s *searcher) ACHFindPostalCodeOnly(limit int, postalCode string) []*fed.AC
 	return achLimit(s.ACHDictionary.PostalCodeFilter(postalCode), limit)
 }
 
-// ACHFind finds ACH Participants based on multiple parameters
-func (s *searcher) ACHFind(limit int, req fedSearchRequest) ([]*fed.ACHParticipant, error) {
+// ACHFind finds ACH Participants based on multiple parameters. Participants are filtered before
+// their names are scored, so the limit applies to those matching every parameter. If ctx is done
+// while scoring names the matches found so far are returned along with ctx.Err().
+func (s *searcher) ACHFind(ctx context.Context, limit int, req fedSearchRequest) ([]*fed.ACHParticipant, fed.SearchStats, error) {
 	s.RLock()
 	defer s.RUnlock()
 	var err error
 
-	out := s.ACHDictionary.FinancialInstitutionSearch(req.Name, limit)
+	out := s.ACHDictionary.ACHParticipants
 	if req.RoutingNumber != "" {
 		out, err = s.ACHDictionary.ACHParticipantRoutingNumberFilter(out, req.RoutingNumber)
 		if err != nil {
-			return nil, err
+			return nil, fed.SearchStats{}, err
 		}
 	}
 	if req.State != "" {
@@ -173,30 +315,33 @@ func (s *searcher) ACHFind(limit int, req fedSearchRequest) ([]*fed.ACHParticipa
 	if req.PostalCode != "" {
 		out = s.ACHDictionary.ACHParticipantPostalCodeFilter(out, req.PostalCode)
 	}
-	return out, nil
+	if req.revisedFilter() {
+		out = s.ACHDictionary.ACHParticipantRevisedFilter(out, req.RevisedAfter, req.RevisedBefore)
+	}
+	if req.Name != "" {
+		return s.ACHDictionary.FinancialInstitutionSearchIn(ctx, out, req.Name, limit)
+	}
+	return achLimit(out, limit), fed.SearchStats{}, nil
 }
 
 // WIRE Searches
 
 // WIREFindNameOnly finds WIRE Participants by name only
-func (s *searcher) WIREFindNameOnly(limit int, participantName string) []*fed.WIREParticipant {
+func (s *searcher) WIREFindNameOnly(ctx context.Context, limit int, participantName string) ([]*fed.WIREParticipant, fed.SearchStats, error) {
 	s.RLock()
 	defer s.RUnlock()
-	fi := s.WIREDictionary.FinancialInstitutionSearch(participantName, limit)
+	fi, stats, err := s.WIREDictionary.FinancialInstitutionSearchContext(ctx, participantName, limit)
 	out := wireLimit(fi, limit)
-	return out
+	return out, stats, err
 }
 
 // WIREFindRoutingNumberOnly finds WIRE Participants by routing number only
-func (s *searcher) WIREFindRoutingNumberOnly(limit int, routingNumber string) ([]*fed.WIREParticipant, error) {
+func (s *searcher) WIREFindRoutingNumberOnly(ctx context.Context, limit int, routingNumber string) ([]*fed.WIREParticipant, fed.SearchStats, error) {
 	s.RLock()
 	defer s.RUnlock()
-	fi, err := s.WIREDictionary.RoutingNumberSearch(routingNumber, limit)
-	if err != nil {
-		return nil, err
-	}
+	fi, stats, err := s.WIREDictionary.RoutingNumberSearchContext(ctx, routingNumber, limit)
 	out := wireLimit(fi, limit)
-	return out, nil
+	return out, stats, err
 }
 
 // WIREFindCityOnly finds WIRE Participants by city only
@@ -217,17 +362,19 @@ func (s *searcher) WIREFindStateOnly(limit int, state string) []*fed.WIREPartici
 	return out
 }
 
-// WIRE Find finds WIRE Participants based on multiple parameters
-func (s *searcher) WIREFind(limit int, req fedSearchRequest) ([]*fed.WIREParticipant, error) {
+// WIRE Find finds WIRE Participants based on multiple parameters. Participants are filtered before
+// their names are scored, so the limit applies to those matching every parameter. If ctx is done
+// while scoring names the matches found so far are returned along with ctx.Err().
+func (s *searcher) WIREFind(ctx context.Context, limit int, req fedSearchRequest) ([]*fed.WIREParticipant, fed.SearchStats, error) {
 	s.RLock()
 	defer s.RUnlock()
 	var err error
-	fi := s.WIREDictionary.FinancialInstitutionSearch(req.Name, limit)
+	fi := s.WIREDictionary.WIREParticipants
 
 	if req.RoutingNumber != "" {
 		fi, err = s.WIREDictionary.WIREParticipantRoutingNumberFilter(fi, req.RoutingNumber)
 		if err != nil {
-			return nil, err
+			return nil, fed.SearchStats{}, err
 		}
 	}
 
@@ -239,8 +386,15 @@ func (s *searcher) WIREFind(limit int, req fedSearchRequest) ([]*fed.WIREPartici
 		fi = s.WIREDictionary.WIREParticipantCityFilter(fi, req.City)
 	}
 
+	if req.revisedFilter() {
+		fi = s.WIREDictionary.WIREParticipantRevisedFilter(fi, req.RevisedAfter, req.RevisedBefore)
+	}
+
+	if req.Name != "" {
+		return s.WIREDictionary.FinancialInstitutionSearchIn(ctx, fi, req.Name, limit)
+	}
 	out := wireLimit(fi, limit)
-	return out, nil
+	return out, fed.SearchStats{}, nil
 }
 
 // extractSearchLimit extracts the search limit from url query parameters
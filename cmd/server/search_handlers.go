@@ -5,15 +5,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	moovhttp "github.com/moov-io/base/http"
 	"github.com/moov-io/base/log"
+	"github.com/moov-io/base/telemetry"
 	"github.com/moov-io/fed"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func addSearchRoutes(logger log.Logger, r *mux.Router, searcher *searcher) {
@@ -28,6 +34,10 @@ type fedSearchRequest struct {
 	City          string `json:"city"`
 	State         string `json:"state"`
 	PostalCode    string `json:"postalCode"`
+
+	// RevisedAfter and RevisedBefore filter participants by revision date, zero values are not checked
+	RevisedAfter  time.Time `json:"revisedAfter"`
+	RevisedBefore time.Time `json:"revisedBefore"`
 }
 
 // readFEDSearchRequest returns a fedachSearchRequest based on url parameters for fed ach search
@@ -41,173 +51,323 @@ func readFEDSearchRequest(u *url.URL) fedSearchRequest {
 	}
 }
 
+var (
+	errInvalidRevisionDate = errors.New("invalid revision date, expected YYYY-MM-DD or RFC 3339")
+)
+
+// readRevisionDates returns the revisedAfter and revisedBefore url parameters
+func readRevisionDates(u *url.URL) (after, before time.Time, err error) {
+	if after, err = readQueryDate(u, "revisedAfter"); err != nil {
+		return
+	}
+	before, err = readQueryDate(u, "revisedBefore")
+	return
+}
+
+func readQueryDate(u *url.URL, key string) (time.Time, error) {
+	v := strings.TrimSpace(u.Query().Get(key))
+	if v == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: %w", key, errInvalidRevisionDate)
+}
+
 // empty returns true if all of the properties in fedachSearchRequest are empty
 func (req fedSearchRequest) empty() bool {
 	return req.Name == "" && req.RoutingNumber == "" && req.City == "" &&
-		req.State == "" && req.PostalCode == ""
+		req.State == "" && req.PostalCode == "" && !req.revisedFilter()
+}
+
+// revisedFilter returns true if either revision date is set
+func (req fedSearchRequest) revisedFilter() bool {
+	return !req.RevisedAfter.IsZero() || !req.RevisedBefore.IsZero()
 }
 
 // nameOnly returns true if only Name is not ""
 func (req fedSearchRequest) nameOnly() bool {
 	return req.Name != "" && req.RoutingNumber == "" && req.City == "" &&
-		req.State == "" && req.PostalCode == ""
+		req.State == "" && req.PostalCode == "" && !req.revisedFilter()
 }
 
 // routingNumberOnly returns true if only routingNumber is not ""
 func (req fedSearchRequest) routingNumberOnly() bool {
 	return req.Name == "" && req.RoutingNumber != "" && req.City == "" &&
-		req.State == "" && req.PostalCode == ""
+		req.State == "" && req.PostalCode == "" && !req.revisedFilter()
 }
 
 // cityOnly returns true if only city is not ""
 func (req fedSearchRequest) cityOnly() bool {
 	return req.Name == "" && req.RoutingNumber == "" && req.City != "" &&
-		req.State == "" && req.PostalCode == ""
+		req.State == "" && req.PostalCode == "" && !req.revisedFilter()
 }
 
 // stateOnly returns true if only state is not ""
 func (req fedSearchRequest) stateOnly() bool {
 	return req.Name == "" && req.RoutingNumber == "" && req.City == "" &&
-		req.State != "" && req.PostalCode == ""
+		req.State != "" && req.PostalCode == "" && !req.revisedFilter()
 }
 
 // postalCodeOnly returns true if only postal code is not ""
 func (req fedSearchRequest) postalCodeOnly() bool {
 	return req.Name == "" && req.RoutingNumber == "" && req.City == "" &&
-		req.State == "" && req.PostalCode != ""
+		req.State == "" && req.PostalCode != "" && !req.revisedFilter()
 }
 
 // searchFEDACH calls search functions based on the fed ach search request url parameters
 func searchFEDACH(logger log.Logger, searcher *searcher) http.HandlerFunc {
+	if logger == nil {
+		logger = log.NewDefaultLogger()
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		if logger == nil {
-			logger = log.NewDefaultLogger()
-		}
-
-		w = wrapResponseWriter(logger, w, r)
+		sw := &statusWriter{ResponseWriter: wrapResponseWriter(logger, w, r)}
+		w = sw
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 		requestID, userID := moovhttp.GetRequestID(r), moovhttp.GetUserID(r)
-		logger = logger.With(log.Fields{
+		logger := logger.With(log.Fields{
 			"requestID": log.String(requestID),
 			"userID":    log.String(userID),
 		})
 
+		// Searches are audited however they're answered
+		var audit auditResult
+		defer func() {
+			audit.status = sw.code
+			searcher.audit.record(r, "ach", audit)
+		}()
+
+		var err error
 		req := readFEDSearchRequest(r.URL)
+		req.RevisedAfter, req.RevisedBefore, err = readRevisionDates(r.URL)
+		if err != nil {
+			audit.err = err
+			moovhttp.Problem(w, err)
+			return
+		}
 		if req.empty() {
+			audit.err = errNoSearchParams
 			logger.Error().Logf("searchFedACH", log.String(errNoSearchParams.Error()))
 			moovhttp.Problem(w, errNoSearchParams)
 			return
 		}
 
-		searchLimit := extractSearchLimit(r)
+		ctx, cancel := searcher.searchContext(r.Context())
+		defer cancel()
 
-		var achParticipants []*fed.ACHParticipant
-		var err error
+		searchLimit := extractSearchLimit(r)
 
-		switch {
-		case req.nameOnly():
-			logger.Logf("searching FED ACH Dictionary by name only %s", req.Name)
-			achParticipants = searcher.ACHFindNameOnly(searchLimit, req.Name)
+		cacheKey, generation := req.cacheKey(searchLimit), searcher.achCache.generation()
 
-		case req.routingNumberOnly():
-			logger.Logf("searching FED ACH Dictionary by routing number only %s", req.RoutingNumber)
-			achParticipants, err = searcher.ACHFindRoutingNumberOnly(searchLimit, req.RoutingNumber)
-			if err != nil {
-				moovhttp.Problem(w, err)
+		var achParticipants []*fed.ACHParticipant
+		var found, partial bool
+		readCache, writeCache := searchCacheControl(r)
+		if readCache {
+			achParticipants, found = searcher.achCache.get(cacheKey)
+		}
+		telemetry.SetAttributes(r.Context(), attribute.Bool("fed.search.cached", found))
+		if !found {
+			start := time.Now()
+			ctx, span := startSearchSpan(ctx, "ach", req, searchLimit)
+			var stats fed.SearchStats
+			achParticipants, stats, err = findACHParticipants(ctx, logger, searcher, req, searchLimit)
+			endSpan(span, err, attribute.Int("fed.results", len(achParticipants)), attribute.Int("fed.candidates", stats.Candidates))
+			observeSearch("ach", req, time.Since(start), stats.Candidates)
+
+			var ok bool
+			partial, ok = checkSearchErr(logger, w, err, len(achParticipants))
+			if !ok {
+				audit.err = err
 				return
 			}
-
-		case req.stateOnly():
-			logger.Logf("searching FED ACH Dictionary by state only %s", req.State)
-			achParticipants = searcher.ACHFindStateOnly(searchLimit, req.State)
-
-		case req.cityOnly():
-			logger.Logf("searching FED ACH Dictionary by city only %s", req.City)
-			achParticipants = searcher.ACHFindCityOnly(searchLimit, req.City)
-
-		case req.postalCodeOnly():
-			logger.Logf("searching FED ACH Dictionary by postal code only %s", req.PostalCode)
-			achParticipants = searcher.ACHFindPostalCodeOnly(searchLimit, req.PostalCode)
-
-		default:
-			logger.Logf("searching FED ACH Dictionary by parameters %v", req.RoutingNumber)
-			achParticipants, err = searcher.ACHFind(searchLimit, req)
-			if err != nil {
-				moovhttp.Problem(w, err)
-				return
+			if writeCache && !partial {
+				searcher.achCache.add(cacheKey, generation, achParticipants)
 			}
 		}
 
+		observeSearchResults("ach", req, len(achParticipants))
+		audit.routingNumbers, audit.partial = achRoutingNumbers(achParticipants), partial
+
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(&searchResponse{
+		json.NewEncoder(w).Encode(searcher.response(&searchResponse{
 			ACHParticipants: achParticipants,
-			Stats:           &searcher.achStats,
-		})
+			Stats:           searcher.ACHStats(),
+			Partial:         partial,
+		}))
+	}
+}
+
+// findACHParticipants runs the search matching the parameters in req
+func findACHParticipants(ctx context.Context, logger log.Logger, searcher *searcher, req fedSearchRequest, searchLimit int) ([]*fed.ACHParticipant, fed.SearchStats, error) {
+	switch {
+	case req.nameOnly():
+		logger.Logf("searching FED ACH Dictionary by name only %s", req.Name)
+		return searcher.ACHFindNameOnly(ctx, searchLimit, req.Name)
+
+	case req.routingNumberOnly():
+		logger.Logf("searching FED ACH Dictionary by routing number only %s", req.RoutingNumber)
+		return searcher.ACHFindRoutingNumberOnly(ctx, searchLimit, req.RoutingNumber)
+
+	case req.stateOnly():
+		logger.Logf("searching FED ACH Dictionary by state only %s", req.State)
+		return searcher.ACHFindStateOnly(searchLimit, req.State), fed.SearchStats{}, nil
+
+	case req.cityOnly():
+		logger.Logf("searching FED ACH Dictionary by city only %s", req.City)
+		return searcher.ACHFindCityOnly(searchLimit, req.City), fed.SearchStats{}, nil
+
+	case req.postalCodeOnly():
+		logger.Logf("searching FED ACH Dictionary by postal code only %s", req.PostalCode)
+		return searcher.ACHFindPostalCodeOnly(searchLimit, req.PostalCode), fed.SearchStats{}, nil
+
+	default:
+		logger.Logf("searching FED ACH Dictionary by parameters %v", req.RoutingNumber)
+		return searcher.ACHFind(ctx, searchLimit, req)
 	}
 }
 
 // searchFEDWIRE calls search functions based on the fed wire search request url parameters
 func searchFEDWIRE(logger log.Logger, searcher *searcher) http.HandlerFunc {
+	if logger == nil {
+		logger = log.NewDefaultLogger()
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		if logger == nil {
-			logger = log.NewDefaultLogger()
-		}
-
-		w = wrapResponseWriter(logger, w, r)
+		sw := &statusWriter{ResponseWriter: wrapResponseWriter(logger, w, r)}
+		w = sw
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 		requestID, userID := moovhttp.GetRequestID(r), moovhttp.GetUserID(r)
-		logger = logger.With(log.Fields{
+		logger := logger.With(log.Fields{
 			"requestID": log.String(requestID),
 			"userID":    log.String(userID),
 		})
 
+		// Searches are audited however they're answered
+		var audit auditResult
+		defer func() {
+			audit.status = sw.code
+			searcher.audit.record(r, "wire", audit)
+		}()
+
+		var err error
 		req := readFEDSearchRequest(r.URL)
+		req.RevisedAfter, req.RevisedBefore, err = readRevisionDates(r.URL)
+		if err != nil {
+			audit.err = err
+			moovhttp.Problem(w, err)
+			return
+		}
 		if req.empty() {
+			audit.err = errNoSearchParams
 			logger.Error().Logf("searchFEDWIRE: %v", errNoSearchParams)
 			moovhttp.Problem(w, errNoSearchParams)
 			return
 		}
 
-		searchLimit := extractSearchLimit(r)
+		ctx, cancel := searcher.searchContext(r.Context())
+		defer cancel()
 
-		var wireParticipants []*fed.WIREParticipant
-		var err error
+		searchLimit := extractSearchLimit(r)
 
-		switch {
-		case req.nameOnly():
-			logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by name only %s", req.Name)
-			wireParticipants = searcher.WIREFindNameOnly(searchLimit, req.Name)
+		cacheKey, generation := req.cacheKey(searchLimit), searcher.wireCache.generation()
 
-		case req.routingNumberOnly():
-			logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by routing number only %s", req.RoutingNumber)
-			wireParticipants, err = searcher.WIREFindRoutingNumberOnly(searchLimit, req.RoutingNumber)
-			if err != nil {
-				moovhttp.Problem(w, err)
+		var wireParticipants []*fed.WIREParticipant
+		var found, partial bool
+		readCache, writeCache := searchCacheControl(r)
+		if readCache {
+			wireParticipants, found = searcher.wireCache.get(cacheKey)
+		}
+		telemetry.SetAttributes(r.Context(), attribute.Bool("fed.search.cached", found))
+		if !found {
+			start := time.Now()
+			ctx, span := startSearchSpan(ctx, "wire", req, searchLimit)
+			var stats fed.SearchStats
+			wireParticipants, stats, err = findWIREParticipants(ctx, logger, searcher, req, searchLimit)
+			endSpan(span, err, attribute.Int("fed.results", len(wireParticipants)), attribute.Int("fed.candidates", stats.Candidates))
+			observeSearch("wire", req, time.Since(start), stats.Candidates)
+
+			var ok bool
+			partial, ok = checkSearchErr(logger, w, err, len(wireParticipants))
+			if !ok {
+				audit.err = err
 				return
 			}
-
-		case req.stateOnly():
-			logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by state only %s", req.State)
-			wireParticipants = searcher.WIREFindStateOnly(searchLimit, req.State)
-
-		case req.cityOnly():
-			logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by city only %s", req.City)
-			wireParticipants = searcher.WIREFindCityOnly(searchLimit, req.City)
-
-		default:
-			logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by parameters %v", req.RoutingNumber)
-			wireParticipants, err = searcher.WIREFind(searchLimit, req)
-			if err != nil {
-				moovhttp.Problem(w, err)
+			if writeCache && !partial {
+				searcher.wireCache.add(cacheKey, generation, wireParticipants)
 			}
 		}
 
+		observeSearchResults("wire", req, len(wireParticipants))
+		audit.routingNumbers, audit.partial = wireRoutingNumbers(wireParticipants), partial
+
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(&searchResponse{
+		json.NewEncoder(w).Encode(searcher.response(&searchResponse{
 			WIREParticipants: wireParticipants,
-			Stats:            &searcher.wireStats,
+			Stats:            searcher.WIREStats(),
+			Partial:          partial,
+		}))
+	}
+}
+
+// findWIREParticipants runs the search matching the parameters in req
+func findWIREParticipants(ctx context.Context, logger log.Logger, searcher *searcher, req fedSearchRequest, searchLimit int) ([]*fed.WIREParticipant, fed.SearchStats, error) {
+	switch {
+	case req.nameOnly():
+		logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by name only %s", req.Name)
+		return searcher.WIREFindNameOnly(ctx, searchLimit, req.Name)
+
+	case req.routingNumberOnly():
+		logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by routing number only %s", req.RoutingNumber)
+		return searcher.WIREFindRoutingNumberOnly(ctx, searchLimit, req.RoutingNumber)
+
+	case req.stateOnly():
+		logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by state only %s", req.State)
+		return searcher.WIREFindStateOnly(searchLimit, req.State), fed.SearchStats{}, nil
+
+	case req.cityOnly():
+		logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by city only %s", req.City)
+		return searcher.WIREFindCityOnly(searchLimit, req.City), fed.SearchStats{}, nil
+
+	default:
+		logger.Logf("searchFEDWIRE: searching FED WIRE Dictionary by parameters %v", req.RoutingNumber)
+		return searcher.WIREFind(ctx, searchLimit, req)
+	}
+}
+
+var (
+	errSearchTimeout = errors.New("search timed out before finding any results")
+)
+
+// checkSearchErr writes a problem response for err and returns false if the response is finished.
+// Searches cut short by the search timeout return whatever they found so far as partial results,
+// or 503 Service Unavailable if nothing was found.
+func checkSearchErr(logger log.Logger, w http.ResponseWriter, err error, found int) (partial bool, ok bool) {
+	switch {
+	case err == nil:
+		return false, true
+
+	case errors.Is(err, context.DeadlineExceeded):
+		if found > 0 {
+			logger.Warn().Logf("search timed out, returning %d partial results", found)
+			return true, true
+		}
+		logger.Warn().Logf("search timed out without results")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": errSearchTimeout.Error(),
 		})
+		return false, false
+
+	case errors.Is(err, context.Canceled):
+		// The client went away, so there's no one to respond to
+		logger.Logf("search cancelled: %v", err)
+		return false, false
 	}
+
+	moovhttp.Problem(w, err)
+	return false, false
 }
@@ -5,14 +5,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/moov-io/base/log"
 	"github.com/moov-io/fed"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/gorilla/mux"
@@ -592,3 +598,242 @@ func TestSearch__WireEmpty(t *testing.T) {
 
 	require.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestSearch__ACHCodeDescriptions(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/fed/ach/search?routingNumber=011000015&limit=1", nil)
+
+	s := searcher{describeCodes: true}
+	err := s.helperLoadFEDACHFile(t)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"officeCode":{"code":"O","description":"Main office"}`)
+	require.Contains(t, w.Body.String(), `"routingNumber":"011000015"`)
+	require.Contains(t, w.Body.String(), `"stats":{`)
+
+	var wrapper struct {
+		ACHParticipants []*fed.ACHParticipant `json:"achParticipants"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&wrapper)
+	require.NoError(t, err)
+	require.Len(t, wrapper.ACHParticipants, 1)
+	require.True(t, wrapper.ACHParticipants[0].IsMainOffice())
+}
+
+func TestSearch__RevisedFilters(t *testing.T) {
+	s := searcher{}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+	require.NoError(t, s.helperLoadFEDWIREFile(t))
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+
+	after := time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/fed/ach/search?name=Cinfed+Federal+Credit+Union&state=OH&revisedAfter=2018-01-01&revisedBefore=2018-02-01", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var achWrapper struct {
+		ACHParticipants []*fed.ACHParticipant `json:"achParticipants"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&achWrapper))
+	require.NotEmpty(t, achWrapper.ACHParticipants)
+	for _, p := range achWrapper.ACHParticipants {
+		require.Equal(t, "OH", p.State)
+		require.False(t, p.RevisedAt.Before(after))
+		require.True(t, p.RevisedAt.Before(before))
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/fed/wire/search?name=Bank&revisedAfter=2018-01-01T00:00:00Z", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var wireWrapper struct {
+		WIREParticipants []*fed.WIREParticipant `json:"wireParticipants"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&wireWrapper))
+	require.NotEmpty(t, wireWrapper.WIREParticipants)
+	for _, p := range wireWrapper.WIREParticipants {
+		require.False(t, p.RevisedAt.Before(after))
+	}
+
+	// Revision dates alone search every participant
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/fed/ach/search?revisedAfter=2018-01-01&limit=50", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	achWrapper.ACHParticipants = nil
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&achWrapper))
+	require.Len(t, achWrapper.ACHParticipants, 50)
+	for _, p := range achWrapper.ACHParticipants {
+		require.False(t, p.RevisedAt.Before(after))
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/fed/wire/search?revisedAfter=2018-01-01&state=IA", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	wireWrapper.WIREParticipants = nil
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&wireWrapper))
+	require.NotEmpty(t, wireWrapper.WIREParticipants)
+	for _, p := range wireWrapper.WIREParticipants {
+		require.Equal(t, "IA", p.State)
+		require.False(t, p.RevisedAt.Before(after))
+	}
+
+	// Names are scored after filtering, so the limit doesn't drop participants matching every filter
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/fed/ach/search?name=Peoples+Bank&state=IA&revisedAfter=2018-01-01&limit=1", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	achWrapper.ACHParticipants = nil
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&achWrapper))
+	require.Len(t, achWrapper.ACHParticipants, 1)
+	require.Equal(t, "IA", achWrapper.ACHParticipants[0].State)
+
+	// invalid dates
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/fed/ach/search?name=Farmers&revisedBefore=01022018", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "revisedBefore")
+}
+
+func TestSearch__Concurrent(t *testing.T) {
+	s := searcher{}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+	require.NoError(t, s.helperLoadFEDWIREFile(t))
+	s.precompute()
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+	addChangesRoutes(log.NewNopLogger(), router, &s)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		w.Flush()
+		return w
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			w := get("/fed/ach/search?name=Farmers&limit=5")
+			assert.Equal(t, http.StatusOK, w.Code)
+			w = get("/fed/wire/search?state=IA&limit=5")
+			assert.Equal(t, http.StatusOK, w.Code)
+			w = get("/fed/ach/changes?since=2018-01-01")
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			// Each invalid request must only see its own error
+			routingNumber := strings.Repeat("1", 10+i%5)
+			expected := fed.NewRecordWrongLengthErr(fed.MaximumRoutingNumberDigits, len(routingNumber)).Error()
+			for _, path := range []string{"/fed/ach/search", "/fed/wire/search"} {
+				w = get(path + "?routingNumber=" + routingNumber)
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+
+				var problem struct {
+					Error string `json:"error"`
+				}
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+				assert.Equal(t, expected, problem.Error)
+			}
+		}(i)
+	}
+
+	// Refresh the data while searches are running
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 3; i++ {
+			fd, err := os.Open(filepath.Join("..", "..", "data", "FedACHdir.txt"))
+			if assert.NoError(t, err) {
+				assert.NoError(t, s.readFEDACHData(fd))
+			}
+			fd, err = os.Open(filepath.Join("..", "..", "data", "fpddir.txt"))
+			if assert.NoError(t, err) {
+				assert.NoError(t, s.readFEDWIREData(fd))
+			}
+			s.precompute()
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestSearch__Timeout(t *testing.T) {
+	s := searcher{searchTimeout: time.Minute}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+	require.NoError(t, s.helperLoadFEDWIREFile(t))
+
+	router := mux.NewRouter()
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+
+	for _, path := range []string{"/fed/ach/search?name=Farmers", "/fed/wire/search?name=MIDWEST"} {
+		// Expire the request before the search starts
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil).WithContext(ctx)
+		router.ServeHTTP(w, req)
+		w.Flush()
+
+		require.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+		require.Contains(t, w.Body.String(), errSearchTimeout.Error())
+	}
+}
+
+func TestSearch__checkSearchErr(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	w := httptest.NewRecorder()
+	partial, ok := checkSearchErr(logger, w, nil, 0)
+	require.False(t, partial)
+	require.True(t, ok)
+
+	w = httptest.NewRecorder()
+	partial, ok = checkSearchErr(logger, w, context.DeadlineExceeded, 3)
+	require.True(t, partial)
+	require.True(t, ok)
+
+	w = httptest.NewRecorder()
+	partial, ok = checkSearchErr(logger, w, context.DeadlineExceeded, 0)
+	require.False(t, partial)
+	require.False(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = httptest.NewRecorder()
+	_, ok = checkSearchErr(logger, w, context.Canceled, 3)
+	require.False(t, ok)
+	require.Empty(t, w.Body.String())
+
+	w = httptest.NewRecorder()
+	_, ok = checkSearchErr(logger, w, fed.ErrRoutingNumberNumeric, 0)
+	require.False(t, ok)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
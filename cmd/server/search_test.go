@@ -5,14 +5,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/moov-io/base/log"
 	"github.com/moov-io/fed"
+	"github.com/moov-io/fed/pkg/synthetic"
+
+	"github.com/stretchr/testify/require"
 )
 
 func (s *searcher) helperLoadFEDACHFile(t *testing.T) error {
@@ -142,7 +148,10 @@ func TestSearcher_ACHFindNameOnly(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	achP := s.ACHFindNameOnly(hardResultsLimit, "Farmers")
+	achP, _, err := s.ACHFindNameOnly(context.Background(), hardResultsLimit, "Farmers")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(achP) == 0 {
 		t.Fatalf("%s", "No matches found for name")
@@ -161,7 +170,7 @@ func TestSearcher_ACHFindRoutingNumberOnly(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	achP, err := s.ACHFindRoutingNumberOnly(10, "044112187")
+	achP, _, err := s.ACHFindRoutingNumberOnly(context.Background(), 10, "044112187")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -252,7 +261,7 @@ func TestSearcher_ACHFind(t *testing.T) {
 		PostalCode:    "43724",
 	}
 
-	achP, err := s.ACHFind(hardResultsLimit, req)
+	achP, _, err := s.ACHFind(context.Background(), hardResultsLimit, req)
 
 	if err != nil {
 		t.Fatal(err)
@@ -368,7 +377,10 @@ func TestSearcher_WIREFindNameOnly(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	wireP := s.WIREFindNameOnly(hardResultsLimit, "MIDWEST")
+	wireP, _, err := s.WIREFindNameOnly(context.Background(), hardResultsLimit, "MIDWEST")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(wireP) == 0 {
 		t.Fatalf("%s", "No matches found for name")
@@ -387,7 +399,7 @@ func TestSearcher_WIREFindRoutingNumberOnly(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	wireP, err := s.WIREFindRoutingNumberOnly(hardResultsLimit, "091905114")
+	wireP, _, err := s.WIREFindRoutingNumberOnly(context.Background(), hardResultsLimit, "091905114")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -453,7 +465,7 @@ func TestSearcher_WIREFind(t *testing.T) {
 		State:         "IA",
 	}
 
-	wireP, err := s.WIREFind(hardResultsLimit, req)
+	wireP, _, err := s.WIREFind(context.Background(), hardResultsLimit, req)
 
 	if err != nil {
 		t.Fatal(err)
@@ -505,3 +517,67 @@ func TestSearch__extractSearchLimit(t *testing.T) {
 		t.Errorf("got limit of %d", limit)
 	}
 }
+
+func TestSearcher_precompute(t *testing.T) {
+	s := searcher{}
+	if err := s.helperLoadFEDACHFile(t); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.helperLoadFEDWIREFile(t); err != nil {
+		t.Fatal(err)
+	}
+	s.precompute()
+
+	if s.achStats.Records != len(s.ACHDictionary.ACHParticipants) {
+		t.Errorf("ACH records=%d", s.achStats.Records)
+	}
+	if v := s.achStats.Latest.Format("2006-01-02"); v != "2018-12-04" {
+		t.Errorf("ACH latest=%s", v)
+	}
+	if v := s.wireStats.Latest.Format("2006-01-02"); v == "0001-01-01" {
+		t.Errorf("Wire latest=%s", v)
+	}
+}
+
+func TestSearcher_precomputeInvalidDates(t *testing.T) {
+	dir, err := synthetic.Generate(synthetic.Options{
+		Seed:        1,
+		ACHRecords:  200,
+		WireRecords: 200,
+		DefectRate:  0.1,
+		Defects:     []synthetic.Defect{synthetic.DefectInvalidDate},
+	})
+	require.NoError(t, err)
+
+	s := searcher{
+		ACHDictionary:  &fed.ACHDictionary{ACHParticipants: dir.ACHParticipants},
+		WIREDictionary: &fed.WIREDictionary{WIREParticipants: dir.WIREParticipants},
+		logger:         log.NewTestLogger(),
+	}
+	s.precompute()
+
+	// Records with invalid dates are counted and left out of the latest revision
+	require.Equal(t, 200, s.achStats.Records)
+	require.Positive(t, s.achStats.InvalidDates)
+	require.Positive(t, s.wireStats.InvalidDates)
+	require.False(t, s.achStats.Latest.IsZero())
+	require.False(t, s.wireStats.Latest.IsZero())
+}
+
+func TestSearcher_readSearchTimeout(t *testing.T) {
+	if dur, err := readSearchTimeout(""); err != nil || dur != defaultSearchTimeout {
+		t.Errorf("dur=%v error=%v", dur, err)
+	}
+	if dur, err := readSearchTimeout("250ms"); err != nil || dur != 250*time.Millisecond {
+		t.Errorf("dur=%v error=%v", dur, err)
+	}
+	if dur, err := readSearchTimeout("0"); err != nil || dur != 0 {
+		t.Errorf("dur=%v error=%v", dur, err)
+	}
+	if _, err := readSearchTimeout("-1s"); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := readSearchTimeout("soon"); err == nil {
+		t.Error("expected error")
+	}
+}
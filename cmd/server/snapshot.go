@@ -0,0 +1,93 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+)
+
+// snapshotter is implemented by fed.ACHDictionary and fed.WIREDictionary
+type snapshotter interface {
+	SourceChecksum() string
+	WriteSnapshot(w io.Writer) error
+	LoadSnapshot(r io.Reader) error
+}
+
+func snapshotPath(dir, listName string) string {
+	return filepath.Join(dir, listName+".snapshot")
+}
+
+// loadSnapshot loads the snapshot of listName from dir into dict if it was created from data. Missing,
+// stale and corrupt snapshots are logged and false is returned, dict must then be discarded. A stale
+// snapshot is left in dict so the previously loaded data can be compared with data.
+func loadSnapshot(logger log.Logger, dir, listName string, data []byte, dict snapshotter) bool {
+	if dir == "" {
+		return false
+	}
+	path := snapshotPath(dir, listName)
+	logger = logger.With(log.Fields{
+		"snapshot": log.String(path),
+	})
+
+	fd, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn().Logf("problem opening snapshot: %v", err)
+		}
+		return false
+	}
+	defer fd.Close()
+
+	if err := dict.LoadSnapshot(fd); err != nil {
+		logger.Warn().Logf("ignoring snapshot: %v", err)
+		return false
+	}
+	if dict.SourceChecksum() != fed.SourceChecksum(data) {
+		logger.Info().Logf("ignoring stale snapshot of %s", listName)
+		return false
+	}
+
+	logger.Info().Logf("loaded %s from snapshot", listName)
+	return true
+}
+
+// writeSnapshot replaces the snapshot of listName in dir with dict
+func writeSnapshot(dir, listName string, dict snapshotter) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+
+	// Write to a temporary file first so a partial snapshot is never loaded
+	fd, err := os.CreateTemp(dir, listName+".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer os.Remove(fd.Name())
+
+	if err := dict.WriteSnapshot(fd); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return fmt.Errorf("closing snapshot: %w", err)
+	}
+	return os.Rename(fd.Name(), snapshotPath(dir, listName))
+}
+
+func (s *searcher) snapshotLogger() log.Logger {
+	if s.logger == nil {
+		return log.NewNopLogger()
+	}
+	return s.logger
+}
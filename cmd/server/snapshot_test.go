@@ -0,0 +1,82 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/fed"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot__loadAndWrite(t *testing.T) {
+	dir, logger := t.TempDir(), log.NewNopLogger()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "data", "fpddir.txt"))
+	require.NoError(t, err)
+
+	// Disabled and missing snapshots
+	require.False(t, loadSnapshot(logger, "", "fedwire", data, fed.NewWIREDictionary()))
+	require.False(t, loadSnapshot(logger, dir, "fedwire", data, fed.NewWIREDictionary()))
+
+	dict := fed.NewWIREDictionary()
+	require.NoError(t, dict.Read(bytes.NewReader(data)))
+	require.NoError(t, writeSnapshot(dir, "fedwire", dict))
+
+	loaded := fed.NewWIREDictionary()
+	require.True(t, loadSnapshot(logger, dir, "fedwire", data, loaded))
+	require.Len(t, loaded.WIREParticipants, len(dict.WIREParticipants))
+
+	// Stale snapshot
+	changed := append(bytes.Clone(data), data[:fed.WIRELineLength+1]...)
+	require.False(t, loadSnapshot(logger, dir, "fedwire", changed, fed.NewWIREDictionary()))
+
+	// Corrupt snapshot
+	path := snapshotPath(dir, "fedwire")
+	snap, err := os.ReadFile(path)
+	require.NoError(t, err)
+	snap[len(snap)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(path, snap, 0600))
+	require.False(t, loadSnapshot(logger, dir, "fedwire", data, fed.NewWIREDictionary()))
+
+	// Only the snapshot is left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestSnapshot__readData(t *testing.T) {
+	dir := t.TempDir()
+	s := &searcher{logger: log.NewNopLogger(), snapshotDir: dir}
+
+	read := func() {
+		t.Helper()
+
+		achFile, err := os.Open(filepath.Join("..", "..", "data", "FedACHdir.txt"))
+		require.NoError(t, err)
+		require.NoError(t, s.readFEDACHData(achFile))
+
+		wireFile, err := os.Open(filepath.Join("..", "..", "data", "fpddir.txt"))
+		require.NoError(t, err)
+		require.NoError(t, s.readFEDWIREData(wireFile))
+	}
+
+	read()
+	require.FileExists(t, snapshotPath(dir, "fedach"))
+	require.FileExists(t, snapshotPath(dir, "fedwire"))
+	achRecords, wireRecords := len(s.ACHDictionary.ACHParticipants), len(s.WIREDictionary.WIREParticipants)
+
+	// Read again, this time from the snapshots
+	read()
+	require.Len(t, s.ACHDictionary.ACHParticipants, achRecords)
+	require.Len(t, s.WIREDictionary.WIREParticipants, wireRecords)
+	require.NotNil(t, s.ACHDictionary.RoutingNumberSearchSingle("044112187"))
+	s.precompute()
+}
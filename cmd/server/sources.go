@@ -0,0 +1,537 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/base/telemetry"
+	"github.com/moov-io/fed/data"
+	"github.com/moov-io/fed/pkg/download"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// errListNotFound is returned by a DataSource which doesn't hold the requested list
+	errListNotFound = errors.New("list not found")
+
+	// listFilenames are the accepted filenames for each list a DataSource is asked for
+	listFilenames = map[string][]string{
+		"fedach":  fedachFilenames,
+		"fedwire": fedwireFilenames,
+	}
+)
+
+// DataSource provides FedACH and Fedwire directory files. Sources are configured as an ordered
+// list with DATA_SOURCES and each list is read from the first source which returns it.
+type DataSource interface {
+	// Name identifies the source in logs
+	Name() string
+
+	// Fetch returns the decompressed directory file for listName ("fedach" or "fedwire"). Sources
+	// return errListNotFound when they don't hold the list.
+	Fetch(ctx context.Context, listName string) (io.Reader, error)
+
+	// LastModified returns when the list was last changed, or the zero time when the source can't tell.
+	LastModified(ctx context.Context, listName string) (time.Time, error)
+}
+
+// readDataSources parses the DATA_SOURCES value, a comma separated list of sources tried in order:
+//
+//	dir, dir:<path>       files in INITIAL_DATA_DIRECTORY or path, see inspectInitialDataDirectory
+//	frb                   downloads from the FRB configured with FRB_* variables
+//	file                  files at FEDACH_DATA_PATH and FEDWIRE_DATA_PATH
+//	embedded              files built into the binary, see package data
+//	http(s)://...         a URL where %s is replaced by the list name
+//	s3://bucket/key       an object in S3 compatible storage where %s in the key is replaced by the list name
+//
+// An empty value is the order used before sources were configurable, see defaultDataSources.
+func readDataSources(logger log.Logger, value string) ([]DataSource, error) {
+	if strings.TrimSpace(value) == "" {
+		return defaultDataSources(logger)
+	}
+
+	var sources []DataSource
+	for _, spec := range strings.Split(value, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		src, err := parseDataSource(logger, spec)
+		if err != nil {
+			return nil, fmt.Errorf("data source %q: %w", spec, err)
+		}
+		sources = append(sources, src)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no data sources in %q", value)
+	}
+	return sources, nil
+}
+
+func parseDataSource(logger log.Logger, spec string) (DataSource, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch strings.ToLower(kind) {
+	case "dir":
+		if arg == "" {
+			arg = os.Getenv("INITIAL_DATA_DIRECTORY")
+		}
+		if arg == "" {
+			return nil, errors.New("missing directory, set INITIAL_DATA_DIRECTORY or use dir:<path>")
+		}
+		return &dirSource{dir: arg, logger: logger}, nil
+
+	case "frb":
+		return newFRBSource(logger)
+
+	case "file":
+		return newFileSource(), nil
+
+	case "embedded":
+		if embeddedData == nil {
+			return nil, errors.New("binary was built without the embeddata tag")
+		}
+		return &embeddedSource{logger: logger}, nil
+
+	case "http", "https":
+		if _, err := url.Parse(strings.ReplaceAll(spec, "%s", "fedach")); err != nil {
+			return nil, err
+		}
+		return &httpSource{client: newSourceHTTPClient(), urlTemplate: spec}, nil
+
+	case "s3":
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(arg, "//"), "/")
+		if bucket == "" || key == "" {
+			return nil, errors.New("expected s3://<bucket>/<key>")
+		}
+		return newS3Source(bucket, key)
+	}
+	return nil, errors.New("unknown data source")
+}
+
+// defaultDataSources are INITIAL_DATA_DIRECTORY, FRB downloads when configured, then the
+// FEDACH_DATA_PATH / FEDWIRE_DATA_PATH files. Embedded files are used when neither path is set.
+//
+// As before sources were configurable, failing to read INITIAL_DATA_DIRECTORY or download from
+// the FRB stops startup instead of falling through to the (likely outdated) files after them.
+func defaultDataSources(logger log.Logger) ([]DataSource, error) {
+	var sources []DataSource
+	if dir := os.Getenv("INITIAL_DATA_DIRECTORY"); dir != "" {
+		sources = append(sources, &requiredSource{&dirSource{dir: dir, logger: logger}})
+	}
+	src, err := newFRBSource(logger)
+	switch {
+	case err == nil:
+		sources = append(sources, &requiredSource{src})
+	case !errors.Is(err, download.ErrMissingConfigValue):
+		return nil, fmt.Errorf("frb: %w", err)
+	}
+	sources = append(sources, newFileSource())
+	if embeddedData != nil && os.Getenv("FEDACH_DATA_PATH") == "" && os.Getenv("FEDWIRE_DATA_PATH") == "" {
+		sources = append(sources, &embeddedSource{logger: logger})
+	}
+	return sources, nil
+}
+
+// requiredSource is a source whose errors, other than not holding a list, stop fetchDataFile
+// from trying the sources after it
+type requiredSource struct {
+	DataSource
+}
+
+// fetchDataFile returns listName from the first source holding it
+func fetchDataFile(ctx context.Context, logger log.Logger, sources []DataSource, listName string) (io.Reader, error) {
+	ctx, span := telemetry.StartSpan(ctx, "fed.fetchData", trace.WithAttributes(
+		attribute.String("fed.list", listName),
+	))
+
+	var errs []error
+	for _, src := range sources {
+		file, err := src.Fetch(ctx, listName)
+		if err == nil {
+			logger.Info().With(log.Fields{
+				"source": log.String(src.Name()),
+			}).Logf("found %s data in %s", listName, src.Name())
+
+			origin, ok := file.(*originFile)
+			if !ok {
+				origin = &originFile{Reader: file}
+			}
+			origin.origin.source = src.Name()
+			endSpan(span, nil, attribute.String("fed.source", src.Name()))
+			return origin, nil
+		}
+
+		if errors.Is(err, errListNotFound) {
+			logger.Logf("%s data not found in %s", listName, src.Name())
+			errs = append(errs, fmt.Errorf("%s: %w", src.Name(), err))
+			continue
+		}
+		if _, ok := src.(*requiredSource); ok {
+			err = fmt.Errorf("problem reading %s data from %s: %w", listName, src.Name(), err)
+			endSpan(span, err)
+			return nil, err
+		}
+		logger.Warn().Logf("problem reading %s data from %s: %v", listName, src.Name(), err)
+		errs = append(errs, fmt.Errorf("%s: %w", src.Name(), err))
+	}
+	err := fmt.Errorf("no data source returned %s: %w", listName, errors.Join(errs...))
+	endSpan(span, err)
+	return nil, err
+}
+
+// fileSource reads the files at FEDACH_DATA_PATH and FEDWIRE_DATA_PATH
+type fileSource struct {
+	paths map[string]string
+}
+
+func newFileSource() *fileSource {
+	return &fileSource{
+		paths: map[string]string{
+			"fedach":  readDataFilepath("FEDACH_DATA_PATH", "./data/FedACHdir.txt"),
+			"fedwire": readDataFilepath("FEDWIRE_DATA_PATH", "./data/fpddir.txt"),
+		},
+	}
+}
+
+func (src *fileSource) Name() string {
+	return "file"
+}
+
+func (src *fileSource) Fetch(_ context.Context, listName string) (io.Reader, error) {
+	path, ok := src.paths[listName]
+	if !ok {
+		return nil, errListNotFound
+	}
+	file, err := openDataFile(path, listFilenames[listName])
+	if err != nil {
+		return nil, fmt.Errorf("problem opening %s: %w", path, err)
+	}
+	return file, nil
+}
+
+func (src *fileSource) FetchSignature(_ context.Context, listName string) ([]byte, error) {
+	path, ok := src.paths[listName]
+	if !ok {
+		return nil, errListNotFound
+	}
+	return os.ReadFile(path + ".sig")
+}
+
+func (src *fileSource) LastModified(_ context.Context, listName string) (time.Time, error) {
+	path, ok := src.paths[listName]
+	if !ok {
+		return time.Time{}, errListNotFound
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// dirSource reads files from a directory, see inspectInitialDataDirectory
+type dirSource struct {
+	dir    string
+	logger log.Logger
+}
+
+func (src *dirSource) Name() string {
+	return "dir:" + src.dir
+}
+
+func (src *dirSource) Fetch(_ context.Context, listName string) (io.Reader, error) {
+	file, err := inspectInitialDataDirectory(src.logger, src.dir, listFilenames[listName])
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, errListNotFound
+	}
+	return file, nil
+}
+
+// FetchSignature reads the .sig file next to the first file holding listName
+func (src *dirSource) FetchSignature(_ context.Context, listName string) ([]byte, error) {
+	entries, err := os.ReadDir(src.dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !matchesDataFilename(entry.Name(), listFilenames[listName]) {
+			continue
+		}
+		sig, err := os.ReadFile(filepath.Join(src.dir, entry.Name()+".sig"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		return sig, err
+	}
+	return nil, fmt.Errorf("no signature in %s", src.dir)
+}
+
+func (src *dirSource) LastModified(_ context.Context, listName string) (time.Time, error) {
+	entries, err := os.ReadDir(src.dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !matchesDataFilename(entry.Name(), listFilenames[listName]) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(src.dir, entry.Name()))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return info.ModTime(), nil
+	}
+	return time.Time{}, errListNotFound
+}
+
+// frbSource downloads files from the FRB, or the proxy at FRB_DOWNLOAD_URL_TEMPLATE. Downloads are
+// saved in DOWNLOAD_CACHE_DIRECTORY when it's set and the newest copy is read when downloads fail.
+type frbSource struct {
+	client *download.Client
+	logger log.Logger
+}
+
+func newFRBSource(logger log.Logger) (*frbSource, error) {
+	client, err := download.NewClient(&download.ClientOpts{
+		CacheDir: os.Getenv("DOWNLOAD_CACHE_DIRECTORY"),
+		Logger:   logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Download lists again only when they've changed since the cached copy
+	for listName := range listFilenames {
+		if _, entry, err := client.LatestCached(listName); err == nil {
+			client.SetValidators(listName, entry.Validators)
+		}
+	}
+	return &frbSource{client: client, logger: logger}, nil
+}
+
+func (src *frbSource) Name() string {
+	return "frb"
+}
+
+func (src *frbSource) Fetch(ctx context.Context, listName string) (io.Reader, error) {
+	dl, err := src.client.FetchList(ctx, listName)
+	if err == nil {
+		file, err := readDownload(dl, listName)
+		if err != nil {
+			return nil, err
+		}
+		// Downloads are cached once they're loaded, so rejected lists aren't read from the cache
+		return &originFile{Reader: file, origin: dataOrigin{commit: dl.Commit}}, nil
+	}
+
+	data, entry, cacheErr := src.client.LatestCached(listName)
+	if cacheErr != nil {
+		return nil, err
+	}
+	logger := src.logger.With(log.Fields{
+		"downloaded_at": log.Time(entry.DownloadedAt),
+		"age":           log.String(time.Since(entry.DownloadedAt).Round(time.Second).String()),
+	})
+	if errors.Is(err, download.ErrNotModified) {
+		logger.Logf("%s hasn't changed since it was cached", listName)
+	} else {
+		logger.Warn().Logf("problem downloading %s, using cached copy downloaded %s: %v",
+			listName, entry.DownloadedAt.Format(time.RFC3339), err)
+	}
+
+	file, err := readDownload(bytes.NewReader(data), listName)
+	if err != nil {
+		return nil, fmt.Errorf("reading cached %s: %w", listName, err)
+	}
+	return &originFile{Reader: file, origin: dataOrigin{downloadedAt: entry.DownloadedAt}}, nil
+}
+
+func (src *frbSource) LastModified(_ context.Context, _ string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+// embeddedSource reads the files built into binaries with the embeddata tag
+type embeddedSource struct {
+	logger log.Logger
+}
+
+func (src *embeddedSource) Name() string {
+	return "embedded"
+}
+
+func (src *embeddedSource) Fetch(_ context.Context, listName string) (io.Reader, error) {
+	filename := data.FedACHFilename
+	if listName == "fedwire" {
+		filename = data.FedWireFilename
+	}
+	if file := openEmbeddedData(src.logger, filename); file != nil {
+		return file, nil
+	}
+	return nil, errListNotFound
+}
+
+func (src *embeddedSource) LastModified(_ context.Context, _ string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+// httpSource downloads files from a URL template where %s is replaced by the list name
+type httpSource struct {
+	client      *http.Client
+	urlTemplate string
+}
+
+func newSourceHTTPClient() *http.Client {
+	return &http.Client{
+		// Directory files are large and mirrors may be slow, as with the FRB download client
+		Timeout: 90 * time.Second,
+	}
+}
+
+func (src *httpSource) Name() string {
+	return redactURL(src.urlTemplate)
+}
+
+func (src *httpSource) url(listName string) string {
+	return strings.ReplaceAll(src.urlTemplate, "%s", listName)
+}
+
+func (src *httpSource) Fetch(ctx context.Context, listName string) (io.Reader, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", src.url(listName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building %s request: %w", listName, err)
+	}
+	return fetchHTTPDataFile(src.client, req, listName)
+}
+
+// FetchSignature downloads the list's URL with .sig added to its path
+func (src *httpSource) FetchSignature(ctx context.Context, listName string) ([]byte, error) {
+	u, err := url.Parse(src.url(listName))
+	if err != nil {
+		return nil, err
+	}
+	u.Path += ".sig"
+	u.RawPath = ""
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building %s signature request: %w", listName, err)
+	}
+	return fetchHTTPSignature(src.client, req)
+}
+
+func (src *httpSource) LastModified(ctx context.Context, listName string) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", src.url(listName), nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("building %s request: %w", listName, err)
+	}
+	return headLastModified(src.client, req)
+}
+
+// fetchHTTPDataFile performs req and returns the decompressed response body
+func fetchHTTPDataFile(client *http.Client, req *http.Request, listName string) (io.Reader, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http get: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errListNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected http status: %d", resp.StatusCode)
+	}
+	bs, err := io.ReadAll(io.LimitReader(resp.Body, download.DefaultMaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", listName, err)
+	}
+	if len(bs) > download.DefaultMaxBodySize {
+		return nil, fmt.Errorf("%w: over %d bytes", download.ErrBodyTooLarge, download.DefaultMaxBodySize)
+	}
+	return readDownload(bytes.NewReader(bs), listName)
+}
+
+// readDownload decompresses a downloaded file and checks it's a directory before it reaches the parser.
+// The decompressed file has the same size limit as downloads, so small archives can't expand without bound.
+func readDownload(file io.Reader, listName string) (io.Reader, error) {
+	r, err := decompressDownload(file, listFilenames[listName])
+	if err != nil {
+		return nil, err
+	}
+	bs, err := io.ReadAll(io.LimitReader(r, download.DefaultMaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", listName, err)
+	}
+	if len(bs) > download.DefaultMaxBodySize {
+		return nil, fmt.Errorf("%w: %s is over %d bytes decompressed", download.ErrBodyTooLarge, listName, download.DefaultMaxBodySize)
+	}
+	if err := download.ValidatePayload(bs); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(bs), nil
+}
+
+// maxSignatureSize is larger than any supported signature, even base64 encoded
+const maxSignatureSize = 64 * 1024
+
+// fetchHTTPSignature performs req and returns the response body
+func fetchHTTPSignature(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http get: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected http status: %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+}
+
+// headLastModified performs a HEAD request and returns the Last-Modified response header
+func headLastModified(client *http.Client, req *http.Request) (time.Time, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("http head: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return time.Time{}, errListNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return time.Time{}, fmt.Errorf("unexpected http status: %d", resp.StatusCode)
+	}
+	if v := resp.Header.Get("Last-Modified"); v != "" {
+		return http.ParseTime(v)
+	}
+	return time.Time{}, nil
+}
+
+// redactURL removes any password from a URL template so it can be logged
+func redactURL(template string) string {
+	const placeholder = "LISTNAME"
+
+	u, err := url.Parse(strings.ReplaceAll(template, "%s", placeholder))
+	if err != nil {
+		return template
+	}
+	return strings.ReplaceAll(u.Redacted(), placeholder, "%s")
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Source downloads objects from S3 compatible storage (AWS S3, MinIO, etc) where %s in the
+// key is replaced by the list name. Requests are path-style and signed with AWS Signature
+// Version 4 when credentials are configured, otherwise they're anonymous.
+type s3Source struct {
+	client *http.Client
+
+	endpoint    *url.URL
+	bucket      string
+	keyTemplate string
+	region      string
+
+	accessKeyID, secretAccessKey, sessionToken string
+
+	now func() time.Time
+}
+
+// newS3Source reads the S3_ENDPOINT, AWS_REGION and AWS credential variables
+func newS3Source(bucket, keyTemplate string) (*s3Source, error) {
+	region := readDataFilepath("AWS_REGION", os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint, err := url.Parse(readDataFilepath("S3_ENDPOINT", fmt.Sprintf("https://s3.%s.amazonaws.com", region)))
+	if err != nil {
+		return nil, fmt.Errorf("parsing S3_ENDPOINT: %w", err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT %q needs a scheme and host", endpoint)
+	}
+	return &s3Source{
+		client:          newSourceHTTPClient(),
+		endpoint:        endpoint,
+		bucket:          bucket,
+		keyTemplate:     keyTemplate,
+		region:          region,
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		now:             time.Now,
+	}, nil
+}
+
+func (src *s3Source) Name() string {
+	return fmt.Sprintf("s3://%s/%s", src.bucket, src.keyTemplate)
+}
+
+func (src *s3Source) key(listName string) string {
+	return strings.ReplaceAll(src.keyTemplate, "%s", listName)
+}
+
+func (src *s3Source) Fetch(ctx context.Context, listName string) (io.Reader, error) {
+	req, err := src.newRequest(ctx, "GET", src.key(listName))
+	if err != nil {
+		return nil, err
+	}
+	return fetchHTTPDataFile(src.client, req, listName)
+}
+
+// FetchSignature downloads the list's key with .sig appended
+func (src *s3Source) FetchSignature(ctx context.Context, listName string) ([]byte, error) {
+	req, err := src.newRequest(ctx, "GET", src.key(listName)+".sig")
+	if err != nil {
+		return nil, err
+	}
+	return fetchHTTPSignature(src.client, req)
+}
+
+func (src *s3Source) LastModified(ctx context.Context, listName string) (time.Time, error) {
+	req, err := src.newRequest(ctx, "HEAD", src.key(listName))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return headLastModified(src.client, req)
+}
+
+func (src *s3Source) newRequest(ctx context.Context, method, key string) (*http.Request, error) {
+	u := *src.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + src.bucket + "/" + strings.TrimPrefix(key, "/")
+	u.RawPath = ""
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building %s request: %w", key, err)
+	}
+	if src.accessKeyID != "" {
+		src.sign(req, src.now())
+	}
+	return req, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req, which has no body.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (src *s3Source) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := strings.Join([]string{now.Format("20060102"), src.region, "s3", "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if src.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", src.sessionToken)
+	}
+
+	// Every header set on req is signed along with the host
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i := range values {
+			trimmed[i] = strings.Join(strings.Fields(values[i]), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEscape(path, false),
+		canonicalQueryString(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + src.secretAccessKey)
+	for _, part := range []string{now.Format("20060102"), src.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		src.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQueryString(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, awsURIEscape(name, true)+"="+awsURIEscape(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEscape percent-encodes every byte except unreserved characters, and '/' unless encodeSlash is set
+func awsURIEscape(s string, encodeSlash bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			buf.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/strx"
+	"github.com/moov-io/base/telemetry"
+	"github.com/moov-io/fed"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultTracingOTLPEndpoint = "localhost:4317"
+
+// readTracingConfig reads TRACING_EXPORTER, TRACING_OTLP_ENDPOINT and TRACING_OTLP_TLS.
+//
+// The OpenTelemetry SDK's OTEL_EXPORTER_OTLP_* variables take precedence when
+// OTEL_EXPORTER_OTLP_ENDPOINT is set, and spans are discarded when no exporter is configured.
+func readTracingConfig() (telemetry.Config, error) {
+	cfg := telemetry.Config{
+		ServiceName: "fed",
+	}
+	switch exporter := strings.ToLower(strings.TrimSpace(os.Getenv("TRACING_EXPORTER"))); exporter {
+	case "", "none":
+	case "stdout":
+		cfg.Stdout = true
+	case "otlp":
+		endpoint := os.Getenv("TRACING_OTLP_ENDPOINT")
+		if endpoint == "" {
+			endpoint = defaultTracingOTLPEndpoint
+		}
+		cfg.OpenTelemetryCollector = &telemetry.OtelConfig{
+			Host: endpoint,
+			TLS:  strx.Yes(os.Getenv("TRACING_OTLP_TLS")),
+		}
+	default:
+		return cfg, fmt.Errorf("unknown TRACING_EXPORTER %q, expected stdout or otlp", exporter)
+	}
+	return cfg, nil
+}
+
+// setupTracing installs the global tracer provider and W3C trace context propagation
+func setupTracing(ctx context.Context, cfg telemetry.Config) (telemetry.ShutdownFunc, error) {
+	return telemetry.SetupTelemetry(ctx, cfg, fed.Version)
+}
+
+// tracingMiddleware continues the trace of incoming W3C traceparent headers, or starts one, with a
+// span for each request named after its route
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if tmpl, err := mux.CurrentRoute(r).GetPathTemplate(); err == nil {
+			route = tmpl
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := telemetry.StartSpan(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("request.id", moovhttp.GetRequestID(r)),
+		))
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.code))
+		if sw.code >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(sw.code))
+		}
+	})
+}
+
+// statusWriter remembers the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// startSearchSpan starts a span for searching listName (ach or wire) which is ended by endSpan
+func startSearchSpan(ctx context.Context, listName string, req fedSearchRequest, limit int) (context.Context, trace.Span) {
+	return telemetry.StartSpan(ctx, "fed.search", trace.WithAttributes(
+		attribute.String("fed.list", listName),
+		attribute.String("fed.search.type", req.searchType()),
+		attribute.Int("fed.limit", limit),
+	))
+}
+
+// startDataLoadSpan starts a span for reading, checking and swapping in listName which is ended by endSpan
+func startDataLoadSpan(listName string) trace.Span {
+	_, span := telemetry.StartSpan(context.Background(), "fed.loadData", trace.WithAttributes(
+		attribute.String("fed.list", listName),
+	))
+	return span
+}
+
+// endSpan marks span as failed if err isn't nil, then ends it
+func endSpan(span trace.Span, err error, kv ...attribute.KeyValue) {
+	span.SetAttributes(kv...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
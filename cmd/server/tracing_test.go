@@ -0,0 +1,84 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/base/telemetry"
+	"go.opentelemetry.io/otel"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTracingConfig(t *testing.T) {
+	cfg, err := readTracingConfig()
+	require.NoError(t, err)
+	require.Equal(t, "fed", cfg.ServiceName)
+	require.False(t, cfg.Stdout)
+	require.Nil(t, cfg.OpenTelemetryCollector)
+
+	t.Setenv("TRACING_EXPORTER", "stdout")
+	cfg, err = readTracingConfig()
+	require.NoError(t, err)
+	require.True(t, cfg.Stdout)
+
+	t.Setenv("TRACING_EXPORTER", "OTLP")
+	cfg, err = readTracingConfig()
+	require.NoError(t, err)
+	require.Equal(t, &telemetry.OtelConfig{Host: defaultTracingOTLPEndpoint}, cfg.OpenTelemetryCollector)
+
+	t.Setenv("TRACING_OTLP_ENDPOINT", "collector:4317")
+	t.Setenv("TRACING_OTLP_TLS", "yes")
+	cfg, err = readTracingConfig()
+	require.NoError(t, err)
+	require.Equal(t, &telemetry.OtelConfig{Host: "collector:4317", TLS: true}, cfg.OpenTelemetryCollector)
+
+	t.Setenv("TRACING_EXPORTER", "zipkin")
+	_, err = readTracingConfig()
+	require.ErrorContains(t, err, `unknown TRACING_EXPORTER "zipkin"`)
+}
+
+func TestTracing(t *testing.T) {
+	prev, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	var buf bytes.Buffer
+	shutdown, err := setupTracing(context.Background(), telemetry.TestConfig(&buf))
+	require.NoError(t, err)
+
+	s := searcher{}
+	require.NoError(t, s.helperLoadFEDACHFile(t))
+
+	router := mux.NewRouter()
+	router.Use(tracingMiddleware)
+	addSearchRoutes(log.NewNopLogger(), router, &s)
+
+	req := httptest.NewRequest("GET", "/fed/ach/search?name=Farmers", nil)
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("X-Request-Id", "tracing-test")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, shutdown())
+
+	// Spans continue the caller's trace
+	out := buf.String()
+	require.Contains(t, out, `"TraceID":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	require.Contains(t, out, `"Name":"GET /fed/ach/search"`)
+	require.Contains(t, out, `"Name":"fed.search"`)
+	require.Contains(t, out, `"Name":"fed.scoreParticipants"`)
+	require.Contains(t, out, `"Value":"tracing-test"`)
+	require.Contains(t, out, `"Key":"fed.candidates"`)
+}
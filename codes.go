@@ -0,0 +1,326 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"encoding/json"
+)
+
+// OfficeCode is a FedACH participant's main office or branch indicator
+type OfficeCode string
+
+const (
+	// MainOffice is a participant's main (head) office
+	MainOffice OfficeCode = "O"
+	// BranchOffice is one of a participant's branches
+	BranchOffice OfficeCode = "B"
+)
+
+func (c OfficeCode) String() string {
+	return string(c)
+}
+
+// Description returns a human readable value for the code, or an empty string if the code is unknown
+func (c OfficeCode) Description() string {
+	switch c {
+	case MainOffice:
+		return "Main office"
+	case BranchOffice:
+		return "Branch"
+	}
+	return ""
+}
+
+// IsMainOffice returns true for main (head) offices
+func (c OfficeCode) IsMainOffice() bool {
+	return c == MainOffice
+}
+
+func (c *OfficeCode) UnmarshalJSON(data []byte) error {
+	code, err := unmarshalCode(data)
+	*c = OfficeCode(code)
+	return err
+}
+
+// RecordTypeCode indicates the routing number to be used to send ACH items to a FedACH participant
+type RecordTypeCode string
+
+const (
+	// RecordTypeFederalReserveBank is used when the institution is a Federal Reserve Bank
+	RecordTypeFederalReserveBank RecordTypeCode = "0"
+	// RecordTypeCustomerRoutingNumber sends items to the customer routing number
+	RecordTypeCustomerRoutingNumber RecordTypeCode = "1"
+	// RecordTypeNewRoutingNumber sends items to the customer using the new routing number field
+	RecordTypeNewRoutingNumber RecordTypeCode = "2"
+)
+
+func (c RecordTypeCode) String() string {
+	return string(c)
+}
+
+// Description returns a human readable value for the code, or an empty string if the code is unknown
+func (c RecordTypeCode) Description() string {
+	switch c {
+	case RecordTypeFederalReserveBank:
+		return "Institution is a Federal Reserve Bank"
+	case RecordTypeCustomerRoutingNumber:
+		return "Send items to customer routing number"
+	case RecordTypeNewRoutingNumber:
+		return "Send items to customer using new routing number field"
+	}
+	return ""
+}
+
+// IsRedirect returns true when items are sent to the participant's new routing number
+func (c RecordTypeCode) IsRedirect() bool {
+	return c == RecordTypeNewRoutingNumber
+}
+
+func (c *RecordTypeCode) UnmarshalJSON(data []byte) error {
+	code, err := unmarshalCode(data)
+	*c = RecordTypeCode(code)
+	return err
+}
+
+// StatusCode is based on a FedACH participant's receiver code
+type StatusCode string
+
+const (
+	// StatusReceivesGovComm is set for participants which receive government and commercial items
+	StatusReceivesGovComm StatusCode = "1"
+)
+
+func (c StatusCode) String() string {
+	return string(c)
+}
+
+// Description returns a human readable value for the code, or an empty string if the code is unknown
+func (c StatusCode) Description() string {
+	if c == StatusReceivesGovComm {
+		return "Receives Gov/Comm"
+	}
+	return ""
+}
+
+func (c *StatusCode) UnmarshalJSON(data []byte) error {
+	code, err := unmarshalCode(data)
+	*c = StatusCode(code)
+	return err
+}
+
+// ViewCode is the FedACH directory view a participant belongs to
+type ViewCode string
+
+const (
+	// ViewCurrent is the current view of the directory
+	ViewCurrent ViewCode = "1"
+)
+
+func (c ViewCode) String() string {
+	return string(c)
+}
+
+// Description returns a human readable value for the code, or an empty string if the code is unknown
+func (c ViewCode) Description() string {
+	if c == ViewCurrent {
+		return "Current view"
+	}
+	return ""
+}
+
+func (c *ViewCode) UnmarshalJSON(data []byte) error {
+	code, err := unmarshalCode(data)
+	*c = ViewCode(code)
+	return err
+}
+
+// FundsTransferStatus designates if a Fedwire participant is eligible for funds transfers
+type FundsTransferStatus string
+
+const (
+	// FundsTransferEligible participants can receive Fedwire funds transfers
+	FundsTransferEligible FundsTransferStatus = "Y"
+	// FundsTransferIneligible participants can't receive Fedwire funds transfers
+	FundsTransferIneligible FundsTransferStatus = "N"
+)
+
+func (c FundsTransferStatus) String() string {
+	return string(c)
+}
+
+// Description returns a human readable value for the code, or an empty string if the code is unknown
+func (c FundsTransferStatus) Description() string {
+	switch c {
+	case FundsTransferEligible:
+		return "Eligible"
+	case FundsTransferIneligible:
+		return "Ineligible"
+	}
+	return ""
+}
+
+// IsWireEligible returns true when the participant can receive Fedwire funds transfers
+func (c FundsTransferStatus) IsWireEligible() bool {
+	return c == FundsTransferEligible
+}
+
+func (c *FundsTransferStatus) UnmarshalJSON(data []byte) error {
+	code, err := unmarshalCode(data)
+	*c = FundsTransferStatus(code)
+	return err
+}
+
+// FundsSettlementOnlyStatus designates if a Fedwire participant is settlement-only
+type FundsSettlementOnlyStatus string
+
+const (
+	// SettlementOnly participants only use Fedwire for settlement
+	SettlementOnly FundsSettlementOnlyStatus = "S"
+	// NotSettlementOnly participants aren't restricted to settlement, the directory leaves the column blank
+	NotSettlementOnly FundsSettlementOnlyStatus = " "
+)
+
+func (c FundsSettlementOnlyStatus) String() string {
+	return string(c)
+}
+
+// Description returns a human readable value for the code, or an empty string if the code is unknown
+func (c FundsSettlementOnlyStatus) Description() string {
+	switch c {
+	case SettlementOnly:
+		return "Settlement-Only"
+	case NotSettlementOnly, "":
+		return "Not Settlement-Only"
+	}
+	return ""
+}
+
+// IsSettlementOnly returns true for settlement-only participants
+func (c FundsSettlementOnlyStatus) IsSettlementOnly() bool {
+	return c == SettlementOnly
+}
+
+func (c *FundsSettlementOnlyStatus) UnmarshalJSON(data []byte) error {
+	code, err := unmarshalCode(data)
+	*c = FundsSettlementOnlyStatus(code)
+	return err
+}
+
+// BookEntrySecuritiesTransferStatus designates if a Fedwire participant is eligible for book entry securities transfers
+type BookEntrySecuritiesTransferStatus string
+
+const (
+	// SecuritiesTransferEligible participants can receive book entry securities transfers
+	SecuritiesTransferEligible BookEntrySecuritiesTransferStatus = "Y"
+	// SecuritiesTransferIneligible participants can't receive book entry securities transfers
+	SecuritiesTransferIneligible BookEntrySecuritiesTransferStatus = "N"
+)
+
+func (c BookEntrySecuritiesTransferStatus) String() string {
+	return string(c)
+}
+
+// Description returns a human readable value for the code, or an empty string if the code is unknown
+func (c BookEntrySecuritiesTransferStatus) Description() string {
+	switch c {
+	case SecuritiesTransferEligible:
+		return "Eligible"
+	case SecuritiesTransferIneligible:
+		return "Ineligible"
+	}
+	return ""
+}
+
+// IsEligible returns true when the participant can receive book entry securities transfers
+func (c BookEntrySecuritiesTransferStatus) IsEligible() bool {
+	return c == SecuritiesTransferEligible
+}
+
+func (c *BookEntrySecuritiesTransferStatus) UnmarshalJSON(data []byte) error {
+	code, err := unmarshalCode(data)
+	*c = BookEntrySecuritiesTransferStatus(code)
+	return err
+}
+
+// CodeDescription is a participant code along with a human readable description of it
+type CodeDescription struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// DescribedACHParticipant encodes an ACHParticipant in JSON with each code as a CodeDescription,
+// for example {"code":"O","description":"Main office"}, instead of only the raw code.
+type DescribedACHParticipant struct {
+	*ACHParticipant
+
+	OfficeCode     CodeDescription `json:"officeCode"`
+	RecordTypeCode CodeDescription `json:"recordTypeCode"`
+	StatusCode     CodeDescription `json:"statusCode"`
+	ViewCode       CodeDescription `json:"viewCode"`
+}
+
+// DescribeACHParticipants returns participants with their codes described, or nil if there are none
+func DescribeACHParticipants(participants []*ACHParticipant) []DescribedACHParticipant {
+	if len(participants) == 0 {
+		return nil
+	}
+	out := make([]DescribedACHParticipant, len(participants))
+	for i, p := range participants {
+		out[i] = DescribedACHParticipant{
+			ACHParticipant: p,
+			OfficeCode:     CodeDescription{Code: p.OfficeCode.String(), Description: p.OfficeCode.Description()},
+			RecordTypeCode: CodeDescription{Code: p.RecordTypeCode.String(), Description: p.RecordTypeCode.Description()},
+			StatusCode:     CodeDescription{Code: p.StatusCode.String(), Description: p.StatusCode.Description()},
+			ViewCode:       CodeDescription{Code: p.ViewCode.String(), Description: p.ViewCode.Description()},
+		}
+	}
+	return out
+}
+
+// DescribedWIREParticipant encodes a WIREParticipant in JSON with each code as a CodeDescription,
+// for example {"code":"Y","description":"Eligible"}, instead of only the raw code.
+type DescribedWIREParticipant struct {
+	*WIREParticipant
+
+	FundsTransferStatus               CodeDescription `json:"fundsTransferStatus"`
+	FundsSettlementOnlyStatus         CodeDescription `json:"fundsSettlementOnlyStatus"`
+	BookEntrySecuritiesTransferStatus CodeDescription `json:"bookEntrySecuritiesTransferStatus"`
+}
+
+// DescribeWIREParticipants returns participants with their codes described, or nil if there are none
+func DescribeWIREParticipants(participants []*WIREParticipant) []DescribedWIREParticipant {
+	if len(participants) == 0 {
+		return nil
+	}
+	out := make([]DescribedWIREParticipant, len(participants))
+	for i, p := range participants {
+		out[i] = DescribedWIREParticipant{
+			WIREParticipant: p,
+			FundsTransferStatus: CodeDescription{
+				Code: p.FundsTransferStatus.String(), Description: p.FundsTransferStatus.Description(),
+			},
+			FundsSettlementOnlyStatus: CodeDescription{
+				Code: p.FundsSettlementOnlyStatus.String(), Description: p.FundsSettlementOnlyStatus.Description(),
+			},
+			BookEntrySecuritiesTransferStatus: CodeDescription{
+				Code: p.BookEntrySecuritiesTransferStatus.String(), Description: p.BookEntrySecuritiesTransferStatus.Description(),
+			},
+		}
+	}
+	return out
+}
+
+// unmarshalCode accepts either the raw code or a CodeDescription
+func unmarshalCode(data []byte) (string, error) {
+	var code string
+	if err := json.Unmarshal(data, &code); err == nil {
+		return code, nil
+	}
+	var described CodeDescription
+	if err := json.Unmarshal(data, &described); err != nil {
+		return "", err
+	}
+	return described.Code, nil
+}
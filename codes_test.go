@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodes__predicates(t *testing.T) {
+	require.True(t, MainOffice.IsMainOffice())
+	require.False(t, BranchOffice.IsMainOffice())
+
+	require.True(t, RecordTypeNewRoutingNumber.IsRedirect())
+	require.False(t, RecordTypeCustomerRoutingNumber.IsRedirect())
+
+	require.True(t, FundsTransferEligible.IsWireEligible())
+	require.False(t, FundsTransferIneligible.IsWireEligible())
+	require.False(t, FundsTransferStatus("").IsWireEligible())
+
+	require.True(t, SettlementOnly.IsSettlementOnly())
+	require.False(t, NotSettlementOnly.IsSettlementOnly())
+
+	require.True(t, SecuritiesTransferEligible.IsEligible())
+	require.False(t, SecuritiesTransferIneligible.IsEligible())
+}
+
+func TestCodes__descriptions(t *testing.T) {
+	require.Equal(t, "Branch", BranchOffice.Description())
+	require.Equal(t, "Send items to customer using new routing number field", RecordTypeNewRoutingNumber.Description())
+	require.Equal(t, "Receives Gov/Comm", StatusReceivesGovComm.Description())
+	require.Equal(t, "Current view", ViewCurrent.Description())
+	require.Equal(t, "Ineligible", FundsTransferIneligible.Description())
+	require.Equal(t, "Not Settlement-Only", NotSettlementOnly.Description())
+	require.Equal(t, "Eligible", SecuritiesTransferEligible.Description())
+
+	require.Equal(t, "", OfficeCode("Z").Description())
+	require.Equal(t, "O", MainOffice.String())
+}
+
+func TestCodes__JSON(t *testing.T) {
+	p := &ACHParticipant{
+		OfficeCode:     BranchOffice,
+		RecordTypeCode: RecordTypeNewRoutingNumber,
+	}
+
+	bs, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.Contains(t, string(bs), `"officeCode":"B"`)
+	require.Contains(t, string(bs), `"recordTypeCode":"2"`)
+
+	bs, err = json.Marshal(DescribeACHParticipants([]*ACHParticipant{p})[0])
+	require.NoError(t, err)
+	require.Contains(t, string(bs), `"officeCode":{"code":"B","description":"Branch"}`)
+	require.Contains(t, string(bs), `"viewCode":{"code":"","description":""}`)
+	require.Contains(t, string(bs), `"achLocation":{`)
+
+	// Both encodings can be read back
+	var out ACHParticipant
+	require.NoError(t, json.Unmarshal(bs, &out))
+	require.Equal(t, BranchOffice, out.OfficeCode)
+	require.True(t, out.IsRedirect())
+
+	bs, err = json.Marshal(DescribeWIREParticipants([]*WIREParticipant{{FundsTransferStatus: FundsTransferEligible}})[0])
+	require.NoError(t, err)
+	require.Contains(t, string(bs), `"fundsTransferStatus":{"code":"Y","description":"Eligible"}`)
+	require.Contains(t, string(bs), `"fundsSettlementOnlyStatus":{"code":"","description":"Not Settlement-Only"}`)
+	require.Nil(t, DescribeWIREParticipants(nil))
+
+	var wire WIREParticipant
+	err = json.Unmarshal([]byte(`{"fundsTransferStatus":"Y","fundsSettlementOnlyStatus":{"code":"S"}}`), &wire)
+	require.NoError(t, err)
+	require.True(t, wire.IsWireEligible())
+	require.True(t, wire.IsSettlementOnly())
+
+	err = json.Unmarshal([]byte(`{"fundsTransferStatus":1}`), &wire)
+	require.Error(t, err)
+}
+
+func TestCodes__Directory(t *testing.T) {
+	_, plainDict := loadTestACHFiles(t)
+
+	p := plainDict.RoutingNumberSearchSingle("011000015")
+	require.NotNil(t, p)
+	require.True(t, p.IsMainOffice())
+	require.Equal(t, RecordTypeFederalReserveBank, p.RecordTypeCode)
+	require.Equal(t, StatusReceivesGovComm, p.StatusCode)
+	require.Equal(t, ViewCurrent, p.ViewCode)
+
+	var redirects int
+	for _, p := range plainDict.ACHParticipants {
+		if p.IsRedirect() {
+			redirects++
+			require.NotEqual(t, "000000000", p.NewRoutingNumber)
+		}
+	}
+	require.Greater(t, redirects, 0)
+}
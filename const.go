@@ -15,3 +15,6 @@ const (
 	// Based on https://www.frbservices.org/EPaymentsDirectory/search.html
 	MaximumRoutingNumberDigits = 9
 )
+
+// contextCheckInterval is how many participants are scored between checks for a cancelled context
+const contextCheckInterval = 256
@@ -0,0 +1,24 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package data holds the FedACH and Fedwire directory files from this repository. They're outdated
+// and only built into binaries compiled with the embeddata tag:
+//
+//	go build -tags embeddata ./cmd/server
+package data
+
+import (
+	"io/fs"
+)
+
+const (
+	// FedACHFilename is the embedded FedACH directory
+	FedACHFilename = "FedACHdir.txt"
+
+	// FedWireFilename is the embedded Fedwire directory
+	FedWireFilename = "fpddir.txt"
+)
+
+// FS holds the embedded directory files, it's nil unless built with the embeddata tag
+var FS fs.FS
@@ -0,0 +1,18 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+//go:build embeddata
+
+package data
+
+import (
+	"embed"
+)
+
+//go:embed FedACHdir.txt fpddir.txt
+var embedded embed.FS
+
+func init() {
+	FS = embedded
+}
@@ -0,0 +1,24 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+//go:build embeddata
+
+package data
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedded(t *testing.T) {
+	require.NotNil(t, FS)
+
+	for _, name := range []string{FedACHFilename, FedWireFilename} {
+		bs, err := fs.ReadFile(FS, name)
+		require.NoError(t, err)
+		require.NotEmpty(t, bs, name)
+	}
+}
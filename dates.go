@@ -0,0 +1,61 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseRevisionDate parses the revision date of a FedACH or Fedwire participant. FedACH files use
+// MMDDYY and Fedwire files use YYYYMMDD, the layout is picked by length so a date is never read in
+// another layout. Dates in JSON files may also be YYYY-MM-DD or RFC 3339.
+// A blank value returns the zero time and no error.
+func ParseRevisionDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	var layout string
+	switch len(value) {
+	case 0:
+		return time.Time{}, nil
+	case len("010206"):
+		layout = "010206"
+	case len("20060102"):
+		layout = "20060102"
+	case len("2006-01-02"):
+		layout = "2006-01-02"
+	default:
+		layout = time.RFC3339
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown revision date format: %q", value)
+	}
+	return t, nil
+}
+
+// revisedAt returns the parsed revision date, or nil if value is blank or can't be parsed.
+func revisedAt(value string) *time.Time {
+	t, err := ParseRevisionDate(value)
+	if err != nil || t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// revisedBetween returns true if t is on or after after and before before. Zero values are not checked.
+func revisedBetween(t *time.Time, after, before time.Time) bool {
+	if t == nil {
+		return false
+	}
+	if !after.IsZero() && t.Before(after) {
+		return false
+	}
+	if !before.IsZero() && !t.Before(before) {
+		return false
+	}
+	return true
+}
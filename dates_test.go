@@ -0,0 +1,107 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRevisionDate(t *testing.T) {
+	cases := map[string]time.Time{
+		"122415":               time.Date(2015, time.December, 24, 0, 0, 0, 0, time.UTC),
+		"110310":               time.Date(2010, time.November, 3, 0, 0, 0, 0, time.UTC), // not 2011-03-10
+		"20040910":             time.Date(2004, time.September, 10, 0, 0, 0, 0, time.UTC),
+		"2019-05-01":           time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC),
+		"2019-05-01T12:00:00Z": time.Date(2019, time.May, 1, 12, 0, 0, 0, time.UTC),
+		" 072811 ":             time.Date(2011, time.July, 28, 0, 0, 0, 0, time.UTC),
+	}
+	for value, expected := range cases {
+		got, err := ParseRevisionDate(value)
+		require.NoError(t, err, value)
+		require.True(t, expected.Equal(got), "%s: got %v", value, got)
+	}
+
+	got, err := ParseRevisionDate("        ")
+	require.NoError(t, err)
+	require.True(t, got.IsZero())
+
+	// Six digit dates are only MMDDYY
+	for _, value := range []string{"yesterday", "181204", "131224", "2019-13-01"} {
+		_, err = ParseRevisionDate(value)
+		require.ErrorContains(t, err, "unknown revision date format", value)
+	}
+}
+
+func TestRevisedAt__Directory(t *testing.T) {
+	jsonDict, plainDict := loadTestACHFiles(t)
+
+	for _, dict := range []*ACHDictionary{jsonDict, plainDict} {
+		p := dict.RoutingNumberSearchSingle("011000015")
+		require.NotNil(t, p)
+		require.NotNil(t, p.RevisedAt)
+		require.Equal(t, "2015-12-24", p.RevisedAt.Format("2006-01-02"))
+	}
+
+	wireDict := NewWIREDictionary()
+	err := wireDict.Read(strings.NewReader("011000028STATE ST BOS      STATE STREET BOSTON                 MABOSTON                   Y Y        "))
+	require.NoError(t, err)
+	require.Nil(t, wireDict.WIREParticipants[0].RevisedAt)
+}
+
+func TestRevisedFilter(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	after := time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	out := dict.ACHParticipantRevisedFilter(dict.ACHParticipants, after, before)
+	require.NotEmpty(t, out)
+	for _, p := range out {
+		require.False(t, p.RevisedAt.Before(after))
+		require.True(t, p.RevisedAt.Before(before))
+	}
+
+	// open ended ranges
+	require.Greater(t, len(dict.ACHParticipantRevisedFilter(dict.ACHParticipants, after, time.Time{})), len(out))
+	require.Len(t, dict.ACHParticipantRevisedFilter(dict.ACHParticipants, time.Time{}, time.Time{}), len(dict.ACHParticipants))
+
+	_, wireDict := loadTestWireFiles(t)
+	wires := wireDict.WIREParticipantRevisedFilter(wireDict.WIREParticipants, after, time.Time{})
+	require.NotEmpty(t, wires)
+	for _, p := range wires {
+		require.False(t, p.RevisedAt.Before(after))
+	}
+}
+
+func TestRevisedSince(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	since := time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC)
+	out := dict.RevisedSince(since)
+	require.NotEmpty(t, out)
+	require.Len(t, out, len(dict.ACHParticipantRevisedFilter(dict.ACHParticipants, since, time.Time{})))
+
+	for i := range out {
+		require.False(t, out[i].RevisedAt.Before(since))
+		if i > 0 {
+			prev := out[i-1]
+			require.False(t, out[i].RevisedAt.Before(*prev.RevisedAt))
+			if out[i].RevisedAt.Equal(*prev.RevisedAt) {
+				require.Less(t, prev.RoutingNumber, out[i].RoutingNumber)
+			}
+		}
+	}
+
+	_, wireDict := loadTestWireFiles(t)
+	wires := wireDict.RevisedSince(since)
+	require.NotEmpty(t, wires)
+	for i := 1; i < len(wires); i++ {
+		require.False(t, wires[i].RevisedAt.Before(*wires[i-1].RevisedAt))
+	}
+}
@@ -14,6 +14,8 @@ var (
 	ErrFileTooLong = errors.New("file exceeds maximum possible number of lines")
 	// Similar to FEDACH site
 	ErrRoutingNumberNumeric = errors.New("the routing number entered is not numeric")
+	// ErrRoutingNumberCheckDigit is returned when the ABA check digit of a routing number does not match
+	ErrRoutingNumberCheckDigit = errors.New("the routing number check digit is invalid")
 )
 
 // RecordWrongLengthErr is the error given when a record is the wrong length
@@ -0,0 +1,97 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unique"
+)
+
+// internStrings can be disabled to compare memory usage without intern and internRecord
+var internStrings = true
+
+// intern returns a canonical copy of s so values repeated across thousands of participants (cities, states,
+// servicing FRB numbers, codes) share one allocation rather than each holding on to its source line.
+func intern[T ~string](s T) T {
+	if !internStrings {
+		return s
+	}
+	return unique.Make(s).Value()
+}
+
+// internRecord copies the mostly unique values of one participant into a single allocation. Parsed values
+// otherwise share memory with their whole source line, or are allocated one by one when decoding JSON.
+func internRecord(fields ...*string) {
+	if internStrings {
+		packStrings(fields)
+	}
+}
+
+// compactParticipants moves participants into a single allocation, then packs each string field returned by
+// fields into one buffer per field and the revision dates into one slice. The first field must be the routing
+// number, which byRoutingNumber is rebuilt with. Callers rebuild their name index from the returned participants.
+func compactParticipants[T any](participants []*T, byRoutingNumber map[string]*T, fields func(p *T) ([]*string, **time.Time)) []*T {
+	slab := make([]T, len(participants))
+	var columns [][]*string
+	dates := make([]**time.Time, len(slab))
+	for i, participant := range participants {
+		slab[i] = *participant
+		strs, revisedAt := fields(&slab[i])
+		if columns == nil {
+			columns = make([][]*string, len(strs))
+		}
+		for c, field := range strs {
+			columns[c] = append(columns[c], field)
+		}
+		dates[i] = revisedAt
+	}
+	for _, column := range columns {
+		packStrings(column)
+	}
+	packTimes(dates)
+
+	participants = slices.Clip(participants)
+	clear(byRoutingNumber)
+	for i := range slab {
+		participants[i] = &slab[i]
+		if len(columns) > 0 {
+			byRoutingNumber[*columns[0][i]] = &slab[i]
+		}
+	}
+	return participants
+}
+
+// packStrings copies every string in a column into a single allocation and points the column's
+// values at substrings of it. This releases the much larger buffers they were parsed from.
+func packStrings(column []*string) {
+	size := 0
+	for _, s := range column {
+		size += len(*s)
+	}
+	var buf strings.Builder
+	buf.Grow(size)
+	for _, s := range column {
+		buf.WriteString(*s)
+	}
+	arena, offset := buf.String(), 0
+	for _, s := range column {
+		n := len(*s)
+		*s = arena[offset : offset+n]
+		offset += n
+	}
+}
+
+// packTimes copies each non-nil time into a single slice and points the values at its elements.
+func packTimes(column []**time.Time) {
+	times := make([]time.Time, 0, len(column))
+	for _, t := range column {
+		if *t != nil {
+			times = append(times, **t)
+			*t = &times[len(times)-1]
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntern(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	// Participants in the same city share one copy of its name
+	seen := make(map[string]*byte)
+	for _, p := range dict.ACHParticipants {
+		if ptr, ok := seen[p.City]; ok {
+			require.Equal(t, ptr, unsafe.StringData(p.City), p.City)
+		} else {
+			seen[p.City] = unsafe.StringData(p.City)
+		}
+	}
+}
+
+func TestACHDictionary_Compact(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	before, err := json.Marshal(dict.ACHParticipants)
+	require.NoError(t, err)
+	expected := dict.FinancialInstitutionSearch("FARMERS", 25)
+
+	dict.Compact()
+
+	after, err := json.Marshal(dict.ACHParticipants)
+	require.NoError(t, err)
+	require.JSONEq(t, string(before), string(after))
+	require.Equal(t, expected, dict.FinancialInstitutionSearch("FARMERS", 25))
+
+	p := dict.RoutingNumberSearchSingle("044112187")
+	require.NotNil(t, p)
+	require.Same(t, p, dict.IndexACHRoutingNumber[p.RoutingNumber])
+	require.Contains(t, dict.FinancialInstitutionSearchSingle(p.CustomerName), p)
+}
+
+func TestWIREDictionary_Compact(t *testing.T) {
+	_, dict := loadTestWireFiles(t)
+
+	before, err := json.Marshal(dict.WIREParticipants)
+	require.NoError(t, err)
+	expected := dict.FinancialInstitutionSearch("MIDWEST", 25)
+
+	dict.Compact()
+
+	after, err := json.Marshal(dict.WIREParticipants)
+	require.NoError(t, err)
+	require.JSONEq(t, string(before), string(after))
+	require.Equal(t, expected, dict.FinancialInstitutionSearch("MIDWEST", 25))
+
+	p := dict.RoutingNumberSearchSingle("091905114")
+	require.NotNil(t, p)
+	require.Same(t, p, dict.IndexWIRERoutingNumber[p.RoutingNumber])
+	require.Contains(t, dict.FinancialInstitutionSearchSingle(p.CustomerName), p)
+}
+
+// heapInUse returns the bytes of live heap objects. Collections are repeated so the unique package
+// can release its entries for interned values.
+func heapInUse() int64 {
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
+
+// benchmarkDictionaryMemory reports the heap retained by a dictionary read from path with each representation
+func benchmarkDictionaryMemory(b *testing.B, path string, read func(f *os.File) (compact func(), keep any)) {
+	variants := []struct {
+		name    string
+		intern  bool
+		compact bool
+	}{
+		{name: "plain"},
+		{name: "interned", intern: true},
+		{name: "compact", intern: true, compact: true},
+	}
+	for _, v := range variants {
+		b.Run(v.name, func(b *testing.B) {
+			prev := internStrings
+			internStrings = v.intern
+			b.Cleanup(func() { internStrings = prev })
+
+			var total int64
+			for i := 0; i < b.N; i++ {
+				start := heapInUse()
+
+				fd, err := os.Open(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+				compact, dict := read(fd)
+				fd.Close()
+				if v.compact {
+					compact()
+				}
+
+				total += heapInUse() - start
+				runtime.KeepAlive(dict)
+			}
+			b.ReportMetric(float64(total)/float64(b.N), "heap-bytes")
+		})
+	}
+}
+
+func BenchmarkACHDictionaryMemory(b *testing.B) {
+	benchmarkDictionaryMemory(b, filepath.Join("data", "FedACHdir.txt"), func(fd *os.File) (func(), any) {
+		dict := NewACHDictionary()
+		if err := dict.Read(fd); err != nil {
+			b.Fatal(err)
+		}
+		return dict.Compact, dict
+	})
+}
+
+func BenchmarkWIREDictionaryMemory(b *testing.B) {
+	benchmarkDictionaryMemory(b, filepath.Join("data", "fpddir.txt"), func(fd *os.File) (func(), any) {
+		dict := NewWIREDictionary()
+		if err := dict.Read(fd); err != nil {
+			b.Fatal(err)
+		}
+		return dict.Compact, dict
+	})
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package archive transparently decompresses gzip and zip encoded directory files.
+//
+// Compression is detected from the leading magic bytes rather than file extensions, so
+// uncompressed data passes through untouched.
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Format is the compression detected on some data
+type Format int
+
+const (
+	Plain Format = iota
+	Gzip
+	Zip
+)
+
+func (f Format) String() string {
+	switch f {
+	case Gzip:
+		return "gzip"
+	case Zip:
+		return "zip"
+	}
+	return "plain"
+}
+
+var (
+	ErrNoMatchingFile = errors.New("no matching file in zip archive")
+
+	gzipMagic     = []byte{0x1f, 0x8b}
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+)
+
+// Detect returns the Format of data beginning with header. At least four bytes are needed to detect zip archives.
+func Detect(header []byte) Format {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(header, zipMagic), bytes.HasPrefix(header, zipEmptyMagic):
+		return Zip
+	}
+	return Plain
+}
+
+// Open opens the file at where and returns a reader of its uncompressed contents.
+// Uncompressed files are returned as their *os.File.
+//
+// Zip archives are searched for a file whose base name case-insensitively matches one of names.
+// When names is empty the archive must contain exactly one file.
+func Open(where string, names []string) (io.ReadCloser, error) {
+	fd, err := os.Open(where)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 4)
+	n, err := io.ReadFull(fd, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		fd.Close()
+		return nil, fmt.Errorf("reading %s: %w", where, err)
+	}
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		fd.Close()
+		return nil, fmt.Errorf("seeking %s: %w", where, err)
+	}
+
+	switch Detect(header[:n]) {
+	case Gzip:
+		gz, err := gzip.NewReader(fd)
+		if err != nil {
+			fd.Close()
+			return nil, fmt.Errorf("gzip %s: %w", where, err)
+		}
+		return &readCloser{Reader: gz, closers: []io.Closer{gz, fd}}, nil
+
+	case Zip:
+		info, err := fd.Stat()
+		if err != nil {
+			fd.Close()
+			return nil, fmt.Errorf("stat %s: %w", where, err)
+		}
+		member, err := openZip(fd, info.Size(), names)
+		if err != nil {
+			fd.Close()
+			return nil, fmt.Errorf("zip %s: %w", where, err)
+		}
+		return &readCloser{Reader: member, closers: []io.Closer{member, fd}}, nil
+	}
+
+	return fd, nil
+}
+
+// NewReader returns a reader of the uncompressed contents of r.
+// Zip archives are buffered into memory and searched with names the same as Open.
+func NewReader(r io.Reader, names []string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(4)
+
+	switch Detect(header) {
+	case Gzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return gz, nil
+
+	case Zip:
+		bs, err := io.ReadAll(br)
+		if err != nil {
+			return nil, fmt.Errorf("reading zip: %w", err)
+		}
+		member, err := openZip(bytes.NewReader(bs), int64(len(bs)), names)
+		if err != nil {
+			return nil, fmt.Errorf("zip: %w", err)
+		}
+		return member, nil
+	}
+
+	return br, nil
+}
+
+func openZip(r io.ReaderAt, size int64, names []string) (io.ReadCloser, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*zip.File
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() {
+			files = append(files, f)
+		}
+	}
+
+	if len(names) == 0 {
+		if len(files) != 1 {
+			return nil, fmt.Errorf("%w: found %d files and no names to pick from", ErrNoMatchingFile, len(files))
+		}
+		return files[0].Open()
+	}
+	for _, f := range files {
+		if matchesName(path.Base(f.Name), names) {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("%w: looking for %s", ErrNoMatchingFile, strings.Join(names, ", "))
+}
+
+func matchesName(filename string, names []string) bool {
+	for idx := range names {
+		if strings.EqualFold(filename, names[idx]) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCloser closes each underlying reader once the decompressed reader is closed
+type readCloser struct {
+	io.Reader
+
+	closers []io.Closer
+}
+
+func (rc *readCloser) Close() error {
+	var errs []error
+	for _, c := range rc.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, contents string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(contents))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipBytes(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func writeFile(t *testing.T, name string, bs []byte) string {
+	t.Helper()
+
+	where := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(where, bs, 0600))
+	return where
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	t.Helper()
+
+	bs, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(bs)
+}
+
+func TestDetect(t *testing.T) {
+	require.Equal(t, Plain, Detect(nil))
+	require.Equal(t, Plain, Detect([]byte("011000015O")))
+	require.Equal(t, Plain, Detect([]byte("{}")))
+	require.Equal(t, Gzip, Detect(gzipBytes(t, "")))
+	require.Equal(t, Zip, Detect(zipBytes(t, map[string]string{"a.txt": "a"})))
+	require.Equal(t, Zip, Detect(zipBytes(t, nil)))
+
+	require.Equal(t, "gzip", Gzip.String())
+	require.Equal(t, "plain", Plain.String())
+}
+
+func TestOpen__plain(t *testing.T) {
+	where := writeFile(t, "FedACHdir.txt", []byte("plain"))
+
+	rc, err := Open(where, nil)
+	require.NoError(t, err)
+	defer rc.Close()
+
+	_, ok := rc.(*os.File)
+	require.True(t, ok)
+	require.Equal(t, "plain", readAll(t, rc))
+
+	// short and empty files
+	where = writeFile(t, "short.txt", []byte("a"))
+	rc, err = Open(where, nil)
+	require.NoError(t, err)
+	require.Equal(t, "a", readAll(t, rc))
+	require.NoError(t, rc.Close())
+
+	where = writeFile(t, "empty.txt", nil)
+	rc, err = Open(where, nil)
+	require.NoError(t, err)
+	require.Equal(t, "", readAll(t, rc))
+	require.NoError(t, rc.Close())
+}
+
+func TestOpen__gzip(t *testing.T) {
+	// The file extension is ignored
+	where := writeFile(t, "FedACHdir.txt", gzipBytes(t, "compressed"))
+
+	rc, err := Open(where, nil)
+	require.NoError(t, err)
+	require.Equal(t, "compressed", readAll(t, rc))
+	require.NoError(t, rc.Close())
+}
+
+func TestOpen__zip(t *testing.T) {
+	where := writeFile(t, "directories.zip", zipBytes(t, map[string]string{
+		"README.txt":       "readme",
+		"dirs/fpddir.txt":  "wire",
+		"FedACHdir.txt":    "ach",
+		"other/nested.txt": "nested",
+	}))
+
+	rc, err := Open(where, []string{"fedachdir.txt"})
+	require.NoError(t, err)
+	require.Equal(t, "ach", readAll(t, rc))
+	require.NoError(t, rc.Close())
+
+	rc, err = Open(where, []string{"fpddir.json", "fpddir.txt"})
+	require.NoError(t, err)
+	require.Equal(t, "wire", readAll(t, rc))
+	require.NoError(t, rc.Close())
+
+	_, err = Open(where, []string{"fedach.json"})
+	require.ErrorIs(t, err, ErrNoMatchingFile)
+
+	_, err = Open(where, nil)
+	require.ErrorIs(t, err, ErrNoMatchingFile)
+
+	// single file archives don't need names
+	where = writeFile(t, "single.zip", zipBytes(t, map[string]string{"anything.txt": "single"}))
+	rc, err = Open(where, nil)
+	require.NoError(t, err)
+	require.Equal(t, "single", readAll(t, rc))
+	require.NoError(t, rc.Close())
+}
+
+func TestOpen__errors(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.txt"), nil)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	where := writeFile(t, "broken.zip", []byte("PK\x03\x04garbage"))
+	_, err = Open(where, nil)
+	require.ErrorContains(t, err, "zip")
+}
+
+func TestNewReader(t *testing.T) {
+	r, err := NewReader(strings.NewReader("plain"), nil)
+	require.NoError(t, err)
+	require.Equal(t, "plain", readAll(t, r))
+
+	r, err = NewReader(bytes.NewReader(gzipBytes(t, "compressed")), nil)
+	require.NoError(t, err)
+	require.Equal(t, "compressed", readAll(t, r))
+
+	zipped := zipBytes(t, map[string]string{"fedach.json": "ach", "fedwire.json": "wire"})
+	r, err = NewReader(bytes.NewReader(zipped), []string{"fedwire.json"})
+	require.NoError(t, err)
+	require.Equal(t, "wire", readAll(t, r))
+
+	_, err = NewReader(bytes.NewReader(zipped), nil)
+	require.ErrorIs(t, err, ErrNoMatchingFile)
+}
+
+func TestOpen__data(t *testing.T) {
+	expected, err := os.ReadFile(filepath.Join("..", "..", "data", "fpddir.txt"))
+	require.NoError(t, err)
+
+	where := writeFile(t, "fpddir.txt.gz", gzipBytes(t, string(expected)))
+	rc, err := Open(where, nil)
+	require.NoError(t, err)
+	defer rc.Close()
+
+	require.Equal(t, string(expected), readAll(t, rc))
+}
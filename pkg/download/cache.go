@@ -0,0 +1,165 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotCached is returned when the cache directory holds no valid download of a list
+var ErrNotCached = errors.New("no cached download")
+
+const (
+	// cacheCopies is how many downloads of each list are kept in the cache directory
+	cacheCopies = 3
+
+	// cacheTimeFormat names cached files so they sort by download time
+	cacheTimeFormat = "20060102T150405.000000000Z"
+)
+
+// CachedList describes a download saved in the cache directory. Each download is kept in a
+// .download file next to a .json file holding this description.
+type CachedList struct {
+	ListName     string     `json:"listName"`
+	DownloadedAt time.Time  `json:"downloadedAt"`
+	SHA256       string     `json:"sha256"`
+	Size         int64      `json:"size"`
+	Validators   Validators `json:"validators"`
+}
+
+func (c *Client) cachePath(listName string, downloadedAt time.Time, ext string) string {
+	return filepath.Join(c.cacheDir, fmt.Sprintf("%s-%s%s", listName, downloadedAt.UTC().Format(cacheTimeFormat), ext))
+}
+
+// saveToCache writes a download into the cache directory and removes older copies of the list
+func (c *Client) saveToCache(listName string, data []byte, validators Validators) error {
+	if c.cacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.cacheDir, 0755); err != nil {
+		return fmt.Errorf("creating download cache: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	entry := CachedList{
+		ListName:     listName,
+		DownloadedAt: time.Now().UTC(),
+		SHA256:       hex.EncodeToString(sum[:]),
+		Size:         int64(len(data)),
+		Validators:   validators,
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding %s cache entry: %w", listName, err)
+	}
+
+	// The description is written last so partially written downloads are never read
+	if err := writeFileAtomic(c.cachePath(listName, entry.DownloadedAt, ".download"), data); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(c.cachePath(listName, entry.DownloadedAt, ".json"), meta); err != nil {
+		return err
+	}
+	return c.pruneCache(listName)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	fd, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer os.Remove(fd.Name())
+
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := fd.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", path, err)
+	}
+	if err := os.Rename(fd.Name(), path); err != nil {
+		return fmt.Errorf("renaming %s: %w", path, err)
+	}
+	return nil
+}
+
+// cachedEntries returns the description files of listName, newest first
+func (c *Client) cachedEntries(listName string) ([]string, error) {
+	if c.cacheDir == "" {
+		return nil, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(c.cacheDir, listName+"-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches, nil
+}
+
+func (c *Client) pruneCache(listName string) error {
+	entries, err := c.cachedEntries(listName)
+	if err != nil || len(entries) <= cacheCopies {
+		return err
+	}
+	var errs []error
+	for _, meta := range entries[cacheCopies:] {
+		for _, path := range []string{meta, strings.TrimSuffix(meta, ".json") + ".download"} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LatestCached returns the newest download of listName from the cache directory whose contents
+// match its recorded checksum. ErrNotCached is returned when there isn't one.
+func (c *Client) LatestCached(listName string) ([]byte, *CachedList, error) {
+	entries, err := c.cachedEntries(listName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading download cache: %w", err)
+	}
+	for _, meta := range entries {
+		data, entry, err := readCacheEntry(meta)
+		if err != nil {
+			c.logger.Warn().Logf("skipping cached download: %v", err)
+			continue
+		}
+		return data, entry, nil
+	}
+	return nil, nil, fmt.Errorf("%w of %s", ErrNotCached, listName)
+}
+
+func readCacheEntry(meta string) ([]byte, *CachedList, error) {
+	bs, err := os.ReadFile(meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry CachedList
+	if err := json.Unmarshal(bs, &entry); err != nil {
+		return nil, nil, fmt.Errorf("decoding %s: %w", meta, err)
+	}
+
+	path := strings.TrimSuffix(meta, ".json") + ".download"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(data)
+	if expected, _ := hex.DecodeString(entry.SHA256); int64(len(data)) != entry.Size || !bytes.Equal(expected, sum[:]) {
+		return nil, nil, fmt.Errorf("%s doesn't match its checksum", path)
+	}
+	return data, &entry, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient__cache(t *testing.T) {
+	file := readTestFile(t, "fedachdir.json")
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	var failing atomic.Bool
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(file)
+	}, ClientOpts{
+		CacheDir:    dir,
+		MaxAttempts: 1,
+	})
+
+	// Nothing is cached yet
+	_, _, err := client.LatestCached("fedach")
+	require.ErrorIs(t, err, ErrNotCached)
+
+	start := time.Now()
+	for i := 0; i < cacheCopies+2; i++ {
+		_, err := client.GetListContext(context.Background(), "fedach")
+		require.NoError(t, err)
+	}
+
+	// Older copies are removed
+	matches, err := filepath.Glob(filepath.Join(dir, "fedach-*"))
+	require.NoError(t, err)
+	require.Len(t, matches, 2*cacheCopies)
+
+	data, entry, err := client.LatestCached("fedach")
+	require.NoError(t, err)
+	require.Equal(t, file, data)
+	require.Equal(t, "fedach", entry.ListName)
+	require.Equal(t, int64(len(file)), entry.Size)
+	require.Equal(t, `"v1"`, entry.Validators.ETag)
+	require.WithinDuration(t, start, entry.DownloadedAt, time.Minute)
+
+	// Failed downloads aren't cached
+	failing.Store(true)
+	_, err = client.GetListContext(context.Background(), "fedach")
+	require.Error(t, err)
+	_, latest, err := client.LatestCached("fedach")
+	require.NoError(t, err)
+	require.Equal(t, entry.DownloadedAt, latest.DownloadedAt)
+
+	_, _, err = client.LatestCached("fedwire")
+	require.ErrorIs(t, err, ErrNotCached)
+}
+
+func TestClient__cacheCommit(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(readTestFile(t, "fpddir.txt"))
+	}, ClientOpts{
+		CacheDir: dir,
+	})
+
+	// Downloads aren't cached until they're committed
+	dl, err := client.FetchList(context.Background(), "fedwire")
+	require.NoError(t, err)
+	_, _, err = client.LatestCached("fedwire")
+	require.ErrorIs(t, err, ErrNotCached)
+	require.Empty(t, client.Validators("fedwire"))
+
+	require.NoError(t, dl.Commit())
+	_, entry, err := client.LatestCached("fedwire")
+	require.NoError(t, err)
+	require.Equal(t, `"v1"`, entry.Validators.ETag)
+	require.Equal(t, `"v1"`, client.Validators("fedwire").ETag)
+}
+
+func TestClient__cacheCorrupted(t *testing.T) {
+	file := readTestFile(t, "fpddir.txt")
+	dir := t.TempDir()
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(file)
+	}, ClientOpts{
+		CacheDir: dir,
+	})
+	for i := 0; i < 2; i++ {
+		_, err := client.GetListContext(context.Background(), "fedwire")
+		require.NoError(t, err)
+	}
+	_, newest, err := client.LatestCached("fedwire")
+	require.NoError(t, err)
+
+	// The newest copy is truncated, so the previous one is read
+	path := filepath.Join(dir, "fedwire-"+newest.DownloadedAt.UTC().Format(cacheTimeFormat)+".download")
+	require.NoError(t, os.WriteFile(path, file[:100], 0600))
+
+	data, entry, err := client.LatestCached("fedwire")
+	require.NoError(t, err)
+	require.Equal(t, file, data)
+	require.True(t, entry.DownloadedAt.Before(newest.DownloadedAt))
+}
+
+func TestClient__cacheDisabled(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(readTestFile(t, "fpddir.txt"))
+	}, ClientOpts{})
+
+	_, err := client.GetListContext(context.Background(), "fedwire")
+	require.NoError(t, err)
+
+	_, _, err = client.LatestCached("fedwire")
+	require.ErrorIs(t, err, ErrNotCached)
+}
@@ -6,13 +6,21 @@ package download
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
 	"time"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/base/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const DefaultFRBDownloadURLTemplate = "https://frbservices.org/EPaymentsDirectory/directories/%s?format=json"
@@ -21,6 +29,19 @@ var (
 	ErrMissingConfigValue   = errors.New("missing config value")
 	ErrMissingRoutingNumber = errors.New("missing routing number")
 	ErrMissingDownloadCD    = errors.New("missing download code")
+
+	// ErrNotModified is returned when a list hasn't changed since it was last downloaded
+	ErrNotModified = errors.New("list not modified")
+	// ErrBodyTooLarge is returned when a download is larger than the client's maximum size
+	ErrBodyTooLarge = errors.New("download is too large")
+)
+
+const (
+	DefaultMaxAttempts  = 4
+	DefaultRetryBackoff = time.Second
+	DefaultMaxBodySize  = 100 * 1024 * 1024
+
+	maxRetryBackoff = 30 * time.Second
 )
 
 type Client struct {
@@ -30,11 +51,34 @@ type Client struct {
 	downloadCode  string // X_FRB_EPAYMENTS_DIRECTORY_DOWNLOAD_CD
 	downloadURL   string // defaults to "https://frbservices.org/EPaymentsDirectory/directories/%s?format=json" where %s is the list name
 
+	maxAttempts  int
+	retryBackoff time.Duration // doubled after each attempt
+	maxBodySize  int64
+
+	// cacheDir holds recent downloads of each list, see LatestCached
+	cacheDir string
+
+	mu         sync.Mutex
+	validators map[string]Validators // keyed by list name
+
+	logger log.Logger
 }
 
 type ClientOpts struct {
 	HTTPClient                               *http.Client
 	RoutingNumber, DownloadCode, DownloadURL string
+
+	// MaxAttempts is how many times a download is tried, defaults to DefaultMaxAttempts
+	MaxAttempts int
+	// RetryBackoff is the wait before the first retry, defaults to DefaultRetryBackoff
+	RetryBackoff time.Duration
+	// MaxBodySize is the largest download accepted in bytes, defaults to DefaultMaxBodySize
+	MaxBodySize int64
+
+	// CacheDir is where successful downloads are saved, caching is disabled when empty
+	CacheDir string
+
+	Logger log.Logger
 }
 
 func NewClient(opts *ClientOpts) (*Client, error) {
@@ -65,23 +109,118 @@ func NewClient(opts *ClientOpts) (*Client, error) {
 		downloadurltemp = DefaultFRBDownloadURLTemplate
 	}
 
-	return &Client{
+	client := &Client{
 		httpClient:    opts.HTTPClient,
 		routingNumber: routingNum,
 		downloadCode:  downloadcd,
 		downloadURL:   downloadurltemp,
-	}, nil
+		maxAttempts:   opts.MaxAttempts,
+		retryBackoff:  opts.RetryBackoff,
+		maxBodySize:   opts.MaxBodySize,
+		cacheDir:      opts.CacheDir,
+		logger:        opts.Logger,
+	}
+	if client.maxAttempts <= 0 {
+		client.maxAttempts = DefaultMaxAttempts
+	}
+	if client.retryBackoff <= 0 {
+		client.retryBackoff = DefaultRetryBackoff
+	}
+	if client.maxBodySize <= 0 {
+		client.maxBodySize = DefaultMaxBodySize
+	}
+	if client.logger == nil {
+		client.logger = log.NewNopLogger()
+	}
+	return client, nil
 }
 
 // GetList downloads an FRB list and saves it into an io.Reader.
 // Example listName values: fedach, fedwire
 func (c *Client) GetList(listName string) (io.Reader, error) {
+	return c.GetListContext(context.Background(), listName)
+}
+
+// GetListContext downloads an FRB list, as FetchList, and commits it to the cache directory.
+// ErrNotModified is returned when the list hasn't changed since its previous download.
+func (c *Client) GetListContext(ctx context.Context, listName string) (io.Reader, error) {
+	dl, err := c.FetchList(ctx, listName)
+	if err != nil {
+		return nil, err
+	}
+	if err := dl.Commit(); err != nil {
+		c.logger.Warn().Logf("problem caching %s download: %v", listName, err)
+	}
+	return dl, nil
+}
+
+// FetchList downloads an FRB list, retrying server errors and timeouts with exponential backoff.
+// Requests are conditional on the ETag and Last-Modified of the list's previously committed download,
+// and ErrNotModified is returned when it hasn't changed. Payloads which aren't directory files, or
+// are larger than the client's limit, are rejected.
+//
+// The download isn't cached, or used for conditional requests, until it's committed.
+func (c *Client) FetchList(ctx context.Context, listName string) (*Download, error) {
 	where, err := url.Parse(fmt.Sprintf(c.downloadURL, listName))
 	if err != nil {
 		return nil, fmt.Errorf("url: %v", err)
 	}
 
-	req, err := http.NewRequest("GET", where.String(), nil)
+	ctx, span := telemetry.StartSpan(ctx, "fed.download", trace.WithAttributes(
+		attribute.String("fed.list", listName),
+		attribute.String("url.full", where.Redacted()),
+	))
+	defer span.End()
+
+	backoff := c.retryBackoff
+	for attempt := 1; ; attempt++ {
+		out, err := c.download(ctx, where.String(), listName)
+		if err == nil || attempt >= c.maxAttempts || !retryable(ctx, err) {
+			span.SetAttributes(attribute.Int("fed.attempts", attempt))
+			if err != nil && !errors.Is(err, ErrNotModified) {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return out, err
+		}
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("fed.attempt", attempt),
+			attribute.String("error", err.Error()),
+		))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("%w (after attempt %d: %v)", ctx.Err(), attempt, err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// Download is a list returned by FetchList
+type Download struct {
+	io.Reader
+
+	client     *Client
+	listName   string
+	data       []byte
+	validators Validators
+}
+
+// Commit saves the download in the cache directory and sends its ETag and Last-Modified in later
+// conditional requests. Callers commit a download once they've accepted the list, so rejected
+// lists are downloaded again instead of being read from the cache.
+func (d *Download) Commit() error {
+	d.client.SetValidators(d.listName, d.validators)
+	return d.client.saveToCache(d.listName, d.data, d.validators)
+}
+
+func (c *Client) download(ctx context.Context, where, listName string) (*Download, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", where, nil)
 	if err != nil {
 		return nil, fmt.Errorf("building %s url: %v", listName, err)
 	}
@@ -90,27 +229,112 @@ func (c *Client) GetList(listName string) (io.Reader, error) {
 		req.Header.Set("X_FRB_EPAYMENTS_DIRECTORY_ORG_ID", c.routingNumber)
 		req.Header.Set("X_FRB_EPAYMENTS_DIRECTORY_DOWNLOAD_CD", c.downloadCode)
 	}
+	validators := c.Validators(listName)
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
 
 	// perform our request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("http get: %v", err)
-	}
-	if resp != nil && resp.Body != nil {
-		defer resp.Body.Close()
+		return nil, fmt.Errorf("http get: %w", err)
 	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
 	// Quit if we fail to download
 	if resp.StatusCode >= 299 {
-		return nil, fmt.Errorf("unexpected http status: %d", resp.StatusCode)
+		return nil, &statusError{code: resp.StatusCode}
+	}
+	if resp.ContentLength > c.maxBodySize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, resp.ContentLength)
 	}
 
 	var out bytes.Buffer
-	if n, err := io.Copy(&out, resp.Body); n == 0 || err != nil {
-		return nil, fmt.Errorf("copying n=%d: %v", n, err)
+	if _, err := io.Copy(&out, io.LimitReader(resp.Body, c.maxBodySize+1)); err != nil {
+		return nil, fmt.Errorf("copying n=%d: %w", out.Len(), err)
+	}
+	if int64(out.Len()) > c.maxBodySize {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrBodyTooLarge, c.maxBodySize)
+	}
+	if err := validateContentType(resp.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+	if err := ValidatePayload(out.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return &Download{
+		Reader:   bytes.NewReader(out.Bytes()),
+		client:   c,
+		listName: listName,
+		data:     out.Bytes(),
+		validators: Validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
+
+// Validators identify a downloaded version of a list for conditional requests
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// Validators returns the ETag and Last-Modified of the most recent download of listName
+func (c *Client) Validators(listName string) Validators {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.validators[listName]
+}
+
+// SetValidators sets the values sent in conditional requests for listName, such as those from
+// a copy of the list which was saved earlier.
+func (c *Client) SetValidators(listName string, v Validators) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.validators == nil {
+		c.validators = make(map[string]Validators)
+	}
+	c.validators[listName] = v
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected http status: %d", e.code)
+}
+
+// retryable returns true for server errors, rate limiting, network timeouts, temporary network
+// errors and truncated bodies. Other request errors, such as a malformed URL or an invalid
+// certificate, fail the same way each attempt.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	var te interface {
+		Timeout() bool
+		Temporary() bool
 	}
-	if out.Len() > 0 {
-		return &out, nil
+	if errors.As(err, &te) {
+		return te.Timeout() || te.Temporary()
 	}
-	return nil, nil
+	return false
 }
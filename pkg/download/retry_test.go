@@ -0,0 +1,245 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ClientOpts) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv("FRB_DOWNLOAD_URL_TEMPLATE", server.URL+"/%s")
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = time.Millisecond
+	}
+	client, err := NewClient(&opts)
+	require.NoError(t, err)
+	return client
+}
+
+func readTestFile(t *testing.T, name string) []byte {
+	t.Helper()
+
+	bs, err := os.ReadFile(filepath.Join("..", "..", "data", name))
+	require.NoError(t, err)
+	return bs
+}
+
+func TestClient__retries(t *testing.T) {
+	file := readTestFile(t, "fedachdir.json")
+
+	var attempts atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write(file)
+	}, ClientOpts{})
+
+	r, err := client.GetListContext(context.Background(), "fedach")
+	require.NoError(t, err)
+	bs, _ := io.ReadAll(r)
+	require.Equal(t, file, bs)
+	require.Equal(t, int32(3), attempts.Load())
+
+	// Attempts are limited
+	attempts.Store(-10)
+	_, err = client.GetListContext(context.Background(), "fedach")
+	require.ErrorContains(t, err, "unexpected http status: 502")
+	require.Equal(t, int32(-10+DefaultMaxAttempts), attempts.Load())
+}
+
+func TestClient__noRetry(t *testing.T) {
+	var attempts atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	}, ClientOpts{})
+
+	_, err := client.GetListContext(context.Background(), "fedach")
+	require.ErrorContains(t, err, "unexpected http status: 403")
+	require.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClient__timeouts(t *testing.T) {
+	file := readTestFile(t, "fpddir.json")
+
+	var attempts atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write(file)
+	}, ClientOpts{
+		HTTPClient: &http.Client{Timeout: 50 * time.Millisecond},
+	})
+
+	_, err := client.GetListContext(context.Background(), "fedwire")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), attempts.Load())
+}
+
+func TestClient__noRetryRequestErrors(t *testing.T) {
+	t.Setenv("FRB_DOWNLOAD_URL_TEMPLATE", "unsupported://example.com/%s")
+
+	client, err := NewClient(&ClientOpts{RetryBackoff: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A retry would wait for the backoff until ctx expires
+	_, err = client.GetListContext(ctx, "fedach")
+	require.ErrorContains(t, err, "unsupported protocol scheme")
+	require.NoError(t, ctx.Err())
+}
+
+func TestRetryable(t *testing.T) {
+	ctx := context.Background()
+
+	require.True(t, retryable(ctx, &statusError{code: http.StatusBadGateway}))
+	require.True(t, retryable(ctx, &statusError{code: http.StatusTooManyRequests}))
+	require.False(t, retryable(ctx, &statusError{code: http.StatusNotFound}))
+	require.True(t, retryable(ctx, fmt.Errorf("copying n=10: %w", io.ErrUnexpectedEOF)))
+
+	timeout := &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}
+	require.True(t, retryable(ctx, fmt.Errorf("http get: %w", timeout)))
+
+	badCert := &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("x509: certificate signed by unknown authority")}
+	require.False(t, retryable(ctx, fmt.Errorf("http get: %w", badCert)))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.False(t, retryable(canceled, &statusError{code: http.StatusBadGateway}))
+}
+
+func TestClient__context(t *testing.T) {
+	var attempts atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, ClientOpts{
+		RetryBackoff: time.Hour,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetListContext(ctx, "fedach")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "unexpected http status: 503")
+	require.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClient__conditional(t *testing.T) {
+	file := readTestFile(t, "fedachdir.json")
+	lastModified := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write(file)
+	}, ClientOpts{})
+
+	ctx := context.Background()
+	_, err := client.GetListContext(ctx, "fedach")
+	require.NoError(t, err)
+	require.Equal(t, Validators{ETag: `"v1"`, LastModified: lastModified}, client.Validators("fedach"))
+	require.Empty(t, client.Validators("fedwire"))
+
+	_, err = client.GetListContext(ctx, "fedach")
+	require.ErrorIs(t, err, ErrNotModified)
+
+	// Validators from a saved copy
+	client.SetValidators("fedwire", Validators{ETag: `"v1"`, LastModified: lastModified})
+	_, err = client.GetListContext(ctx, "fedwire")
+	require.ErrorIs(t, err, ErrNotModified)
+}
+
+func TestClient__maxBodySize(t *testing.T) {
+	file := readTestFile(t, "FedACHdir.txt")
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fedwire" {
+			w.Header().Set("Content-Length", "1000000")
+		}
+		w.Write(file[:1024])
+	}, ClientOpts{
+		MaxBodySize: 1000,
+	})
+
+	_, err := client.GetListContext(context.Background(), "fedach")
+	require.ErrorIs(t, err, ErrBodyTooLarge)
+
+	_, err = client.GetListContext(context.Background(), "fedwire")
+	require.ErrorIs(t, err, ErrBodyTooLarge)
+}
+
+func TestClient__unexpectedPayload(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fedach":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<!DOCTYPE html><html><body>Please log in</body></html>"))
+		case "/fedwire":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"fedwireParticipants": {"response": {"code": 202}, "fedwireParticipants": []}}`))
+		}
+	}, ClientOpts{})
+
+	_, err := client.GetListContext(context.Background(), "fedach")
+	require.ErrorIs(t, err, ErrUnexpectedPayload)
+
+	_, err = client.GetListContext(context.Background(), "fedwire")
+	require.ErrorIs(t, err, ErrUnexpectedPayload)
+	require.ErrorContains(t, err, "FRB response code 202")
+}
+
+func TestValidatePayload(t *testing.T) {
+	for _, name := range []string{"FedACHdir.txt", "fedachdir.json", "fpddir.txt", "fpddir.json"} {
+		require.NoError(t, ValidatePayload(readTestFile(t, name)), name)
+	}
+	require.NoError(t, ValidatePayload([]byte("\xef\xbb\xbf  011000015O0110000151122415000000000FEDERAL RESERVE BANK")))
+	require.NoError(t, ValidatePayload([]byte("PK\x03\x04 zip archive")))
+	require.NoError(t, ValidatePayload([]byte("\x1f\x8b gzip")))
+
+	invalid := map[string]string{
+		"":                                      "empty",
+		"\n\t ":                                 "empty",
+		"<html><body>Maintenance</body></html>": "HTML or XML document",
+		`<?xml version="1.0"?><Error></Error>`:  "HTML or XML document",
+		`{ }`:                                   "JSON without an FRB response",
+		`{"fedACHParticipants": {"response": {"code": 404}}}`: "FRB response code 404",
+		`{"fedACHParticipants": `:                             "invalid JSON",
+		"Service Unavailable":                                 "unknown format",
+	}
+	for payload, msg := range invalid {
+		err := ValidatePayload([]byte(payload))
+		require.True(t, errors.Is(err, ErrUnexpectedPayload), payload)
+		require.ErrorContains(t, err, msg, payload)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/moov-io/fed/pkg/archive"
+)
+
+// ErrUnexpectedPayload is returned when a download isn't a directory file, such as a login page
+// or an FRB error response.
+var ErrUnexpectedPayload = errors.New("unexpected download payload")
+
+// successResponseCodes are the FRB response codes of directory files. The FRB's JSON files report
+// 100 while documentation refers to 200.
+var successResponseCodes = map[int]bool{
+	100: true,
+	200: true,
+}
+
+// ValidatePayload returns ErrUnexpectedPayload unless data looks like a FedACH or Fedwire directory
+// file: FRB JSON with a successful response code, plaintext records or a compressed file.
+func ValidatePayload(data []byte) error {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // UTF-8 byte order mark
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return fmt.Errorf("%w: empty", ErrUnexpectedPayload)
+	}
+	if archive.Detect(data) != archive.Plain {
+		return nil // checked once decompressed
+	}
+
+	switch trimmed[0] {
+	case '<':
+		return fmt.Errorf("%w: HTML or XML document", ErrUnexpectedPayload)
+	case '{':
+		return validateJSONPayload(trimmed)
+	}
+
+	// Plaintext records start with a routing number
+	if len(trimmed) < 9 || !isDigits(trimmed[:9]) {
+		return fmt.Errorf("%w: unknown format starting %q", ErrUnexpectedPayload, preview(trimmed))
+	}
+	return nil
+}
+
+// validateJSONPayload checks the response code of the object wrapping participants, e.g.
+// {"fedACHParticipants": {"response": {"code": 100}, "fedACHParticipants": [...]}}
+func validateJSONPayload(data []byte) error {
+	var wrapper map[string]struct {
+		Response *struct {
+			Code int `json:"code"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return fmt.Errorf("%w: invalid JSON: %v", ErrUnexpectedPayload, err)
+	}
+	for _, list := range wrapper {
+		if list.Response == nil {
+			continue
+		}
+		if !successResponseCodes[list.Response.Code] {
+			return fmt.Errorf("%w: FRB response code %d", ErrUnexpectedPayload, list.Response.Code)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: JSON without an FRB response", ErrUnexpectedPayload)
+}
+
+// validateContentType rejects web pages, which are served by login and error pages
+func validateContentType(value string) error {
+	if value == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err == nil && strings.EqualFold(mediaType, "text/html") {
+		return fmt.Errorf("%w: %s response", ErrUnexpectedPayload, mediaType)
+	}
+	return nil
+}
+
+func isDigits(bs []byte) bool {
+	for _, b := range bs {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func preview(bs []byte) string {
+	if len(bs) > 20 {
+		bs = bs[:20]
+	}
+	return string(bs)
+}
@@ -0,0 +1,467 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package synthetic generates FedACH and Fedwire directories of any size for load and integration
+// testing. Records have valid ABA check digits and plausible names, addresses and phone numbers but
+// describe no real institution, except the twelve Federal Reserve Banks which service the others.
+//
+// Directories are deterministic for a seed and can include branch offices, redirects to new routing
+// numbers and deliberately defective records.
+package synthetic
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moov-io/fed"
+)
+
+// MaxRecords is the largest directory Generate creates
+const MaxRecords = 2_000_000
+
+// Defect is a deliberate problem introduced into generated records
+type Defect string
+
+const (
+	// DefectCheckDigit gives a record a routing number with an invalid check digit
+	DefectCheckDigit Defect = "check-digit"
+	// DefectDuplicate gives a record the routing number of another record
+	DefectDuplicate Defect = "duplicate"
+	// DefectDanglingRedirect points a FedACH redirect record to a routing number which isn't in the directory
+	DefectDanglingRedirect Defect = "dangling-redirect"
+	// DefectLongName gives a record a name longer than its field, which breaks the fixed-width line length
+	DefectLongName Defect = "long-name"
+	// DefectUnknownState gives a record a state code which doesn't exist
+	DefectUnknownState Defect = "unknown-state"
+	// DefectInvalidDate gives a record a change date which can't be parsed
+	DefectInvalidDate Defect = "invalid-date"
+)
+
+// Defects are every kind of Defect
+var Defects = []Defect{
+	DefectCheckDigit, DefectDuplicate, DefectDanglingRedirect, DefectLongName, DefectUnknownState, DefectInvalidDate,
+}
+
+// ParseDefects reads a comma separated list of defects, "all" is every defect
+func ParseDefects(value string) ([]Defect, error) {
+	var out []Defect
+	for _, v := range strings.Split(value, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		switch {
+		case v == "":
+			continue
+		case v == "all":
+			return slices.Clone(Defects), nil
+		case slices.Contains(Defects, Defect(v)):
+			out = append(out, Defect(v))
+		default:
+			return nil, fmt.Errorf("unknown defect %q", v)
+		}
+	}
+	return out, nil
+}
+
+// Options configure a generated directory. The zero value of each rate uses its default.
+type Options struct {
+	// Seed makes directories reproducible, the same options and seed generate the same records
+	Seed uint64
+
+	// ACHRecords and WireRecords are the number of records in each list
+	ACHRecords  int
+	WireRecords int
+
+	// BranchRate is the fraction of FedACH records which are branch offices, defaults to 1%
+	BranchRate float64
+	// RedirectRate is the fraction of FedACH records sending items to a new routing number, defaults to 9%
+	RedirectRate float64
+
+	// DefectRate is the fraction of records given one of Defects, or every kind when Defects is empty
+	DefectRate float64
+	Defects    []Defect
+
+	// Now is the latest change date of records, defaults to the current time
+	Now time.Time
+}
+
+// Directory is a generated FedACH and Fedwire directory
+type Directory struct {
+	ACHParticipants  []*fed.ACHParticipant
+	WIREParticipants []*fed.WIREParticipant
+}
+
+// Generate creates a directory described by opts
+func Generate(opts Options) (*Directory, error) {
+	if opts.ACHRecords < 0 || opts.WireRecords < 0 {
+		return nil, errors.New("negative record count")
+	}
+	if opts.ACHRecords > MaxRecords || opts.WireRecords > MaxRecords {
+		return nil, fmt.Errorf("at most %d records can be generated", MaxRecords)
+	}
+	for _, rate := range []float64{opts.BranchRate, opts.RedirectRate, opts.DefectRate} {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("rate %v isn't between 0 and 1", rate)
+		}
+	}
+	if opts.BranchRate == 0 {
+		opts.BranchRate = 0.01
+	}
+	if opts.RedirectRate == 0 {
+		opts.RedirectRate = 0.09
+	}
+	if len(opts.Defects) == 0 {
+		opts.Defects = Defects
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	g := &generator{
+		opts:     opts,
+		rand:     rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)),
+		assigned: make(map[string]bool),
+	}
+	dir := &Directory{
+		ACHParticipants: g.achParticipants(),
+	}
+	dir.WIREParticipants = g.wireParticipants(dir.ACHParticipants)
+
+	g.addACHDefects(dir.ACHParticipants)
+	g.addWireDefects(dir.WIREParticipants)
+
+	for _, p := range dir.ACHParticipants {
+		p.RevisedAt = revisedAt(p.Revised)
+	}
+	for _, p := range dir.WIREParticipants {
+		p.RevisedAt = revisedAt(p.Date)
+	}
+
+	sortByRoutingNumber(dir.ACHParticipants, func(p *fed.ACHParticipant) string { return p.RoutingNumber })
+	sortByRoutingNumber(dir.WIREParticipants, func(p *fed.WIREParticipant) string { return p.RoutingNumber })
+	return dir, nil
+}
+
+func revisedAt(value string) *time.Time {
+	t, err := fed.ParseRevisionDate(value)
+	if err != nil || t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func sortByRoutingNumber[T any](ps []T, routingNumber func(T) string) {
+	slices.SortStableFunc(ps, func(a, b T) int {
+		return strings.Compare(routingNumber(a), routingNumber(b))
+	})
+}
+
+type generator struct {
+	opts Options
+	rand *rand.Rand
+
+	// assigned holds every routing number given to an institution
+	assigned map[string]bool
+}
+
+// institution is shared by an institution's FedACH and Fedwire records
+type institution struct {
+	routingNumber string
+	name          string
+	city          city
+	creditUnion   bool
+}
+
+func (g *generator) pick(options []string) string {
+	return options[g.rand.IntN(len(options))]
+}
+
+func (g *generator) chance(rate float64) bool {
+	return g.rand.Float64() < rate
+}
+
+func (g *generator) digits(n int) string {
+	var buf strings.Builder
+	for range n {
+		buf.WriteByte(byte('0' + g.rand.IntN(10)))
+	}
+	return buf.String()
+}
+
+// routingNumber returns an unassigned routing number in the city's Federal Reserve district.
+// Thrift institutions and credit unions use the district plus 20.
+func (g *generator) routingNumber(c city, thrift bool) string {
+	district := c.district
+	if thrift {
+		district += 20
+	}
+	for {
+		prefix := fmt.Sprintf("%02d%s", district, g.digits(6))
+		rtn := fmt.Sprintf("%s%d", prefix, fed.RoutingNumberCheckDigit(prefix))
+		if !g.assigned[rtn] {
+			g.assigned[rtn] = true
+			return rtn
+		}
+	}
+}
+
+func (g *generator) institution() institution {
+	c := cities[g.rand.IntN(len(cities))]
+	creditUnion := g.chance(0.35)
+
+	var name string
+	switch first := g.pick(namePrefixes); {
+	case creditUnion && g.chance(0.5):
+		name = g.pick(employers) + " " + g.pick(creditUnionSuffixes)
+	case creditUnion:
+		name = first + " " + g.pick(creditUnionSuffixes)
+	case g.chance(0.3):
+		name = c.name + " " + g.pick(bankSuffixes)
+	default:
+		name = first + " " + g.pick(bankSuffixes)
+	}
+	return institution{
+		routingNumber: g.routingNumber(c, creditUnion || g.chance(0.1)),
+		name:          truncate(name, 36),
+		city:          c,
+		creditUnion:   creditUnion,
+	}
+}
+
+// changeDate returns a date within ten years of opts.Now
+func (g *generator) changeDate() time.Time {
+	days := g.rand.IntN(10 * 365)
+	return g.opts.Now.AddDate(0, 0, -days)
+}
+
+func (g *generator) address() string {
+	if g.chance(0.15) {
+		return fmt.Sprintf("P O BOX %d", 1+g.rand.IntN(9999))
+	}
+	return fmt.Sprintf("%d %s %s", 1+g.rand.IntN(9999), g.pick(streets), g.pick(streetSuffixes))
+}
+
+func (g *generator) achParticipant(inst institution, office fed.OfficeCode) *fed.ACHParticipant {
+	revised := g.changeDate().Format("010206")
+	zipExt := "0000"
+	if g.chance(0.6) {
+		zipExt = g.digits(4)
+	}
+	p := &fed.ACHParticipant{
+		RoutingNumber:      inst.routingNumber,
+		OfficeCode:         office,
+		ServicingFRBNumber: federalReserveBanks[inst.city.district-1].routingNumber,
+		RecordTypeCode:     fed.RecordTypeCustomerRoutingNumber,
+		Revised:            revised,
+		NewRoutingNumber:   "000000000",
+		CustomerName:       inst.name,
+		ACHLocation: fed.ACHLocation{
+			Address:             g.address(),
+			City:                inst.city.name,
+			State:               inst.city.state,
+			PostalCode:          inst.city.zipPrefix + g.digits(2),
+			PostalCodeExtension: zipExt,
+		},
+		PhoneNumber: inst.city.areaCode + fmt.Sprintf("%d", 2+g.rand.IntN(8)) + g.digits(6),
+		StatusCode:  fed.StatusReceivesGovComm,
+		ViewCode:    fed.ViewCurrent,
+	}
+	p.CleanName = fed.Normalize(p.CustomerName)
+	return p
+}
+
+func (g *generator) achParticipants() []*fed.ACHParticipant {
+	out := make([]*fed.ACHParticipant, 0, g.opts.ACHRecords)
+
+	// The Federal Reserve Banks service every other record
+	for _, frb := range federalReserveBanks {
+		if len(out) == g.opts.ACHRecords {
+			return out
+		}
+		g.assigned[frb.routingNumber] = true
+		p := g.achParticipant(institution{routingNumber: frb.routingNumber, name: frb.name, city: frb.city}, fed.MainOffice)
+		p.RecordTypeCode = fed.RecordTypeFederalReserveBank
+		out = append(out, p)
+	}
+
+	var mains []institution
+	for len(out) < g.opts.ACHRecords {
+		// Branches share a name with a main office in the same district
+		if len(mains) > 0 && g.chance(g.opts.BranchRate) {
+			main := mains[g.rand.IntN(len(mains))]
+			branch := institution{name: main.name, city: main.city, creditUnion: main.creditUnion}
+			branch.routingNumber = g.routingNumber(branch.city, branch.creditUnion)
+			out = append(out, g.achParticipant(branch, fed.BranchOffice))
+			continue
+		}
+
+		inst := g.institution()
+		p := g.achParticipant(inst, fed.MainOffice)
+
+		// Redirected institutions merged into, or were renumbered as, an earlier one
+		if len(mains) > 0 && g.chance(g.opts.RedirectRate) {
+			p.RecordTypeCode = fed.RecordTypeNewRoutingNumber
+			p.NewRoutingNumber = mains[g.rand.IntN(len(mains))].routingNumber
+		} else {
+			mains = append(mains, inst)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// wireParticipants are mostly institutions from the FedACH directory, like the FRB's files
+func (g *generator) wireParticipants(ach []*fed.ACHParticipant) []*fed.WIREParticipant {
+	var shared []institution
+	for _, p := range ach {
+		if p.OfficeCode == fed.MainOffice && p.RecordTypeCode != fed.RecordTypeNewRoutingNumber {
+			c := city{name: p.City, state: p.State}
+			shared = append(shared, institution{routingNumber: p.RoutingNumber, name: p.CustomerName, city: c})
+		}
+	}
+	g.rand.Shuffle(len(shared), func(i, j int) {
+		shared[i], shared[j] = shared[j], shared[i]
+	})
+
+	out := make([]*fed.WIREParticipant, 0, g.opts.WireRecords)
+	for len(out) < g.opts.WireRecords {
+		var inst institution
+		if len(shared) > 0 && g.chance(0.9) {
+			inst, shared = shared[0], shared[1:]
+		} else {
+			inst = g.institution()
+		}
+
+		p := &fed.WIREParticipant{
+			RoutingNumber:   inst.routingNumber,
+			TelegraphicName: telegraphicName(inst.name, inst.city.name),
+			CustomerName:    inst.name,
+			WIRELocation: fed.WIRELocation{
+				City:  inst.city.name,
+				State: inst.city.state,
+			},
+			FundsTransferStatus:               fed.FundsTransferEligible,
+			FundsSettlementOnlyStatus:         fed.NotSettlementOnly,
+			BookEntrySecuritiesTransferStatus: fed.SecuritiesTransferEligible,
+		}
+		if g.chance(0.09) {
+			p.FundsTransferStatus = fed.FundsTransferIneligible
+		}
+		if g.chance(0.1) {
+			p.FundsSettlementOnlyStatus = fed.SettlementOnly
+		}
+		if g.chance(0.37) {
+			p.BookEntrySecuritiesTransferStatus = fed.SecuritiesTransferIneligible
+		}
+		if !g.chance(0.16) {
+			p.Date = g.changeDate().Format("20060102")
+		}
+		p.CleanName = fed.Normalize(p.CustomerName)
+		out = append(out, p)
+	}
+	return out
+}
+
+// telegraphicName abbreviates an institution's name and city into 18 characters, e.g. FIRST NB MAPLETON
+func telegraphicName(name, city string) string {
+	for _, abbr := range abbreviations {
+		name = strings.ReplaceAll(name, abbr[0], abbr[1])
+	}
+	if words := strings.Fields(city); len(words) > 0 && len(name)+len(words[0]) < 18 {
+		name += " " + words[0]
+	}
+	return truncate(name, 18)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return strings.TrimSpace(s[:n])
+	}
+	return s
+}
+
+// defect returns the defect to give a record, or an empty defect
+func (g *generator) defect(applicable func(Defect) bool) Defect {
+	if g.opts.DefectRate == 0 || !g.chance(g.opts.DefectRate) {
+		return ""
+	}
+	var kinds []Defect
+	for _, d := range g.opts.Defects {
+		if applicable(d) {
+			kinds = append(kinds, d)
+		}
+	}
+	if len(kinds) == 0 {
+		return ""
+	}
+	return kinds[g.rand.IntN(len(kinds))]
+}
+
+func (g *generator) invalidCheckDigit(rtn string) string {
+	digit := int(rtn[8]-'0') + 1 + g.rand.IntN(9)
+	return fmt.Sprintf("%s%d", rtn[:8], digit%10)
+}
+
+// unassignedRoutingNumber returns a valid routing number no institution was given
+func (g *generator) unassignedRoutingNumber() string {
+	c := cities[g.rand.IntN(len(cities))]
+	rtn := g.routingNumber(c, false)
+	delete(g.assigned, rtn)
+	return rtn
+}
+
+func (g *generator) longName(name string) string {
+	return name + " " + strings.Repeat(g.pick(namePrefixes)+" ", 3) + "HOLDING COMPANY"
+}
+
+func (g *generator) addACHDefects(ps []*fed.ACHParticipant) {
+	for i, p := range ps {
+		d := g.defect(func(Defect) bool { return true })
+		switch d {
+		case DefectCheckDigit:
+			p.RoutingNumber = g.invalidCheckDigit(p.RoutingNumber)
+		case DefectDuplicate:
+			if other := ps[g.rand.IntN(len(ps))]; other != p {
+				p.RoutingNumber = other.RoutingNumber
+			} else if i > 0 {
+				p.RoutingNumber = ps[i-1].RoutingNumber
+			}
+		case DefectDanglingRedirect:
+			p.RecordTypeCode = fed.RecordTypeNewRoutingNumber
+			p.NewRoutingNumber = g.unassignedRoutingNumber()
+		case DefectLongName:
+			p.CustomerName = g.longName(p.CustomerName)
+			p.CleanName = fed.Normalize(p.CustomerName)
+		case DefectUnknownState:
+			p.State = "ZZ"
+		case DefectInvalidDate:
+			p.Revised = "139999"
+		}
+	}
+}
+
+func (g *generator) addWireDefects(ps []*fed.WIREParticipant) {
+	for i, p := range ps {
+		d := g.defect(func(d Defect) bool { return d != DefectDanglingRedirect })
+		switch d {
+		case DefectCheckDigit:
+			p.RoutingNumber = g.invalidCheckDigit(p.RoutingNumber)
+		case DefectDuplicate:
+			if other := ps[g.rand.IntN(len(ps))]; other != p {
+				p.RoutingNumber = other.RoutingNumber
+			} else if i > 0 {
+				p.RoutingNumber = ps[i-1].RoutingNumber
+			}
+		case DefectLongName:
+			p.CustomerName = g.longName(p.CustomerName)
+			p.CleanName = fed.Normalize(p.CustomerName)
+		case DefectUnknownState:
+			p.State = "ZZ"
+		case DefectInvalidDate:
+			p.Date = "20241399"
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package synthetic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/fed"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+func TestGenerate(t *testing.T) {
+	dir, err := Generate(Options{Seed: 1, ACHRecords: 5000, WireRecords: 2000, Now: testNow})
+	require.NoError(t, err)
+	require.Len(t, dir.ACHParticipants, 5000)
+	require.Len(t, dir.WIREParticipants, 2000)
+
+	seen := make(map[string]bool)
+	var branches, redirects, frbs int
+	for _, p := range dir.ACHParticipants {
+		require.NoError(t, fed.ValidateRoutingNumber(p.RoutingNumber), p.RoutingNumber)
+		require.False(t, seen[p.RoutingNumber], p.RoutingNumber)
+		seen[p.RoutingNumber] = true
+
+		require.NotEmpty(t, p.CustomerName)
+		require.Len(t, p.PhoneNumber, 10)
+		require.NotNil(t, p.RevisedAt)
+		require.False(t, p.RevisedAt.After(testNow))
+
+		switch {
+		case p.OfficeCode == fed.BranchOffice:
+			branches++
+		case p.RecordTypeCode == fed.RecordTypeNewRoutingNumber:
+			redirects++
+		case p.RecordTypeCode == fed.RecordTypeFederalReserveBank:
+			frbs++
+		}
+	}
+	require.Equal(t, 12, frbs)
+	require.InDelta(t, 50, branches, 30)
+	require.InDelta(t, 450, redirects, 100)
+
+	// Redirects point to institutions in the directory
+	for _, p := range dir.ACHParticipants {
+		if p.IsRedirect() {
+			require.True(t, seen[p.NewRoutingNumber], p.NewRoutingNumber)
+		}
+	}
+
+	// Most Fedwire participants are FedACH participants too
+	var shared int
+	for _, p := range dir.WIREParticipants {
+		require.NoError(t, fed.ValidateRoutingNumber(p.RoutingNumber), p.RoutingNumber)
+		require.LessOrEqual(t, len(p.TelegraphicName), 18)
+		if seen[p.RoutingNumber] {
+			shared++
+		}
+	}
+	require.Greater(t, shared, 1500)
+
+	// The same seed generates the same directory
+	again, err := Generate(Options{Seed: 1, ACHRecords: 5000, WireRecords: 2000, Now: testNow})
+	require.NoError(t, err)
+	require.Equal(t, dir, again)
+
+	other, err := Generate(Options{Seed: 2, ACHRecords: 5000, WireRecords: 2000, Now: testNow})
+	require.NoError(t, err)
+	require.NotEqual(t, dir.ACHParticipants[100].RoutingNumber, other.ACHParticipants[100].RoutingNumber)
+}
+
+func TestGenerate__options(t *testing.T) {
+	for _, opts := range []Options{
+		{ACHRecords: -1},
+		{WireRecords: MaxRecords + 1},
+		{BranchRate: 1.5},
+		{DefectRate: -0.1},
+	} {
+		_, err := Generate(opts)
+		require.Error(t, err, opts)
+	}
+
+	dir, err := Generate(Options{ACHRecords: 5})
+	require.NoError(t, err)
+	require.Len(t, dir.ACHParticipants, 5)
+	require.Empty(t, dir.WIREParticipants)
+}
+
+func TestDirectory__write(t *testing.T) {
+	dir, err := Generate(Options{Seed: 3, ACHRecords: 1000, WireRecords: 500, Now: testNow})
+	require.NoError(t, err)
+
+	for _, format := range []Format{FormatText, FormatJSON} {
+		var buf bytes.Buffer
+		require.NoError(t, dir.WriteACH(&buf, format))
+		if format == FormatText {
+			for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+				require.Len(t, line, fed.ACHLineLength)
+			}
+		}
+		ach := fed.NewACHDictionary()
+		require.NoError(t, ach.Read(&buf), format)
+		require.Len(t, ach.ACHParticipants, 1000)
+
+		for i, p := range ach.ACHParticipants {
+			expected := dir.ACHParticipants[i]
+			require.Equal(t, expected.RoutingNumber, p.RoutingNumber)
+			require.Equal(t, expected.CustomerName, p.CustomerName)
+			require.Equal(t, expected.ACHLocation, p.ACHLocation)
+			require.Equal(t, expected.PhoneNumber, p.PhoneNumber)
+			require.Equal(t, expected.RevisedAt, p.RevisedAt)
+		}
+
+		buf.Reset()
+		require.NoError(t, dir.WriteWire(&buf, format))
+		if format == FormatText {
+			for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+				require.Len(t, line, fed.WIRELineLength)
+			}
+		}
+		wire := fed.NewWIREDictionary()
+		require.NoError(t, wire.Read(&buf), format)
+		require.Len(t, wire.WIREParticipants, 500)
+
+		for i, p := range wire.WIREParticipants {
+			expected := dir.WIREParticipants[i]
+			require.Equal(t, expected.RoutingNumber, p.RoutingNumber)
+			require.Equal(t, expected.TelegraphicName, p.TelegraphicName)
+			require.Equal(t, expected.WIRELocation, p.WIRELocation)
+			require.Equal(t, expected.FundsSettlementOnlyStatus, p.FundsSettlementOnlyStatus)
+			require.Equal(t, expected.Date, strings.TrimSpace(p.Date))
+		}
+	}
+
+	require.ErrorContains(t, dir.WriteACH(&bytes.Buffer{}, "xml"), `unknown format "xml"`)
+}
+
+func TestGenerate__defects(t *testing.T) {
+	dir, err := Generate(Options{Seed: 4, ACHRecords: 2000, WireRecords: 1000, DefectRate: 0.2, Now: testNow})
+	require.NoError(t, err)
+
+	found := make(map[Defect]int)
+	seen := make(map[string]bool)
+	for _, p := range dir.ACHParticipants {
+		if fed.ValidateRoutingNumber(p.RoutingNumber) != nil {
+			found[DefectCheckDigit]++
+		}
+		if seen[p.RoutingNumber] {
+			found[DefectDuplicate]++
+		}
+		seen[p.RoutingNumber] = true
+		if len(p.CustomerName) > 36 {
+			found[DefectLongName]++
+		}
+		if p.State == "ZZ" {
+			found[DefectUnknownState]++
+		}
+		if p.RevisedAt == nil {
+			found[DefectInvalidDate]++
+		}
+	}
+	for _, p := range dir.ACHParticipants {
+		if p.IsRedirect() && !seen[p.NewRoutingNumber] {
+			found[DefectDanglingRedirect]++
+		}
+	}
+	for _, d := range Defects {
+		require.InDelta(t, 2000*0.2/float64(len(Defects)), found[d], 40, d)
+	}
+
+	// Long names break the fixed-width format
+	var buf bytes.Buffer
+	require.NoError(t, dir.WriteACH(&buf, FormatText))
+	require.Error(t, fed.NewACHDictionary().Read(&buf))
+
+	// Only the chosen defects are introduced
+	dir, err = Generate(Options{Seed: 4, WireRecords: 1000, DefectRate: 0.2, Defects: []Defect{DefectUnknownState}, Now: testNow})
+	require.NoError(t, err)
+	var unknownStates int
+	for _, p := range dir.WIREParticipants {
+		require.NoError(t, fed.ValidateRoutingNumber(p.RoutingNumber))
+		if p.State == "ZZ" {
+			unknownStates++
+		}
+	}
+	require.InDelta(t, 200, unknownStates, 50)
+}
+
+func TestParseDefects(t *testing.T) {
+	defects, err := ParseDefects("check-digit, Duplicate,")
+	require.NoError(t, err)
+	require.Equal(t, []Defect{DefectCheckDigit, DefectDuplicate}, defects)
+
+	defects, err = ParseDefects("all")
+	require.NoError(t, err)
+	require.Equal(t, Defects, defects)
+
+	_, err = ParseDefects("typo")
+	require.ErrorContains(t, err, `unknown defect "typo"`)
+}
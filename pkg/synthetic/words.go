@@ -0,0 +1,143 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package synthetic
+
+// city is where generated institutions are located
+type city struct {
+	name      string
+	state     string
+	zipPrefix string // first three digits of the city's zip codes
+	areaCode  string
+	district  int // Federal Reserve district, 1 (Boston) to 12 (San Francisco)
+}
+
+var cities = []city{
+	{"BOSTON", "MA", "021", "617", 1},
+	{"WORCESTER", "MA", "016", "508", 1},
+	{"HARTFORD", "CT", "061", "860", 1},
+	{"PORTLAND", "ME", "041", "207", 1},
+	{"BURLINGTON", "VT", "054", "802", 1},
+	{"NEW YORK", "NY", "100", "212", 2},
+	{"BUFFALO", "NY", "142", "716", 2},
+	{"NEWARK", "NJ", "071", "973", 2},
+	{"PHILADELPHIA", "PA", "191", "215", 3},
+	{"WILMINGTON", "DE", "198", "302", 3},
+	{"ALLENTOWN", "PA", "181", "610", 3},
+	{"CLEVELAND", "OH", "441", "216", 4},
+	{"PITTSBURGH", "PA", "152", "412", 4},
+	{"CINCINNATI", "OH", "452", "513", 4},
+	{"LEXINGTON", "KY", "405", "859", 4},
+	{"RICHMOND", "VA", "232", "804", 5},
+	{"BALTIMORE", "MD", "212", "410", 5},
+	{"CHARLOTTE", "NC", "282", "704", 5},
+	{"COLUMBIA", "SC", "292", "803", 5},
+	{"CHARLESTON", "WV", "253", "304", 5},
+	{"ATLANTA", "GA", "303", "404", 6},
+	{"BIRMINGHAM", "AL", "352", "205", 6},
+	{"NASHVILLE", "TN", "372", "615", 6},
+	{"JACKSONVILLE", "FL", "322", "904", 6},
+	{"NEW ORLEANS", "LA", "701", "504", 6},
+	{"JACKSON", "MS", "392", "601", 6},
+	{"CHICAGO", "IL", "606", "312", 7},
+	{"DETROIT", "MI", "482", "313", 7},
+	{"INDIANAPOLIS", "IN", "462", "317", 7},
+	{"MILWAUKEE", "WI", "532", "414", 7},
+	{"DES MOINES", "IA", "503", "515", 7},
+	{"CEDAR RAPIDS", "IA", "524", "319", 7},
+	{"ST. LOUIS", "MO", "631", "314", 8},
+	{"LITTLE ROCK", "AR", "722", "501", 8},
+	{"LOUISVILLE", "KY", "402", "502", 8},
+	{"MEMPHIS", "TN", "381", "901", 8},
+	{"MINNEAPOLIS", "MN", "554", "612", 9},
+	{"SIOUX FALLS", "SD", "571", "605", 9},
+	{"FARGO", "ND", "581", "701", 9},
+	{"BILLINGS", "MT", "591", "406", 9},
+	{"KANSAS CITY", "MO", "641", "816", 10},
+	{"OMAHA", "NE", "681", "402", 10},
+	{"DENVER", "CO", "802", "303", 10},
+	{"OKLAHOMA CITY", "OK", "731", "405", 10},
+	{"WICHITA", "KS", "672", "316", 10},
+	{"CHEYENNE", "WY", "820", "307", 10},
+	{"DALLAS", "TX", "752", "214", 11},
+	{"HOUSTON", "TX", "770", "713", 11},
+	{"SAN ANTONIO", "TX", "782", "210", 11},
+	{"ALBUQUERQUE", "NM", "871", "505", 11},
+	{"SAN FRANCISCO", "CA", "941", "415", 12},
+	{"LOS ANGELES", "CA", "900", "213", 12},
+	{"SEATTLE", "WA", "981", "206", 12},
+	{"PORTLAND", "OR", "972", "503", 12},
+	{"PHOENIX", "AZ", "850", "602", 12},
+	{"SALT LAKE CITY", "UT", "841", "801", 12},
+	{"BOISE", "ID", "837", "208", 12},
+	{"FAIRBANKS", "AK", "997", "907", 12},
+	{"HONOLULU", "HI", "968", "808", 12},
+}
+
+// federalReserveBanks are the main offices which service institutions in each district
+var federalReserveBanks = []struct {
+	routingNumber string
+	name          string
+	city          city
+}{
+	{"011000015", "FEDERAL RESERVE BANK", cities[0]},
+	{"021001208", "FEDERAL RESERVE BANK", cities[5]},
+	{"031000040", "FEDERAL RESERVE BANK", cities[8]},
+	{"041000014", "FEDERAL RESERVE BANK", cities[11]},
+	{"051000033", "FEDERAL RESERVE BANK", cities[15]},
+	{"061000146", "FEDERAL RESERVE BANK", cities[20]},
+	{"071000301", "FEDERAL RESERVE BANK", cities[26]},
+	{"081000045", "FEDERAL RESERVE BANK", cities[32]},
+	{"091000080", "FEDERAL RESERVE BANK", cities[36]},
+	{"101000048", "FEDERAL RESERVE BANK", cities[40]},
+	{"111000038", "FEDERAL RESERVE BANK", cities[46]},
+	{"121000374", "FEDERAL RESERVE BANK", cities[50]},
+}
+
+var namePrefixes = []string{
+	"FIRST", "FIRST NATIONAL", "CITIZENS", "PEOPLES", "FARMERS", "FARMERS & MERCHANTS", "COMMUNITY",
+	"SECURITY", "HOME", "AMERICAN", "UNITED", "HERITAGE", "PIONEER", "LIBERTY", "GUARANTY", "UNION",
+	"VALLEY", "PRAIRIE", "RIVERSIDE", "LAKESIDE", "SUMMIT", "FRONTIER", "GRANITE", "CORNERSTONE",
+	"KEYSTONE", "COUNTY", "STATE", "MUTUAL", "PROVIDENT", "COMMERCE", "MERCHANTS", "FIDELITY",
+}
+
+var bankSuffixes = []string{
+	"BANK", "STATE BANK", "NATIONAL BANK", "SAVINGS BANK", "BANK & TRUST", "TRUST COMPANY",
+	"BANK, N.A.", "BANK AND TRUST CO", "SAVINGS & LOAN", "FEDERAL SAVINGS BANK",
+}
+
+var creditUnionSuffixes = []string{
+	"CREDIT UNION", "FEDERAL CREDIT UNION", "FCU", "COMMUNITY CREDIT UNION", "EMPLOYEES CREDIT UNION",
+}
+
+var employers = []string{
+	"TEACHERS", "EDUCATORS", "POSTAL", "TELCO", "RAILWAY", "HOSPITAL", "MUNICIPAL", "FIREFIGHTERS",
+	"POLICE", "NAVY", "AIR FORCE", "ARMY", "STATE EMPLOYEES", "UNIVERSITY", "REFINERY", "UTILITY",
+}
+
+var streets = []string{
+	"MAIN", "BROADWAY", "MARKET", "CENTER", "CHURCH", "ELM", "OAK", "MAPLE", "WASHINGTON", "LINCOLN",
+	"JEFFERSON", "PARK", "LAKE", "HILL", "RIVER", "COMMERCE", "CAPITOL", "HIGHLAND", "2ND", "5TH",
+}
+
+var streetSuffixes = []string{
+	"ST", "AVE", "BLVD", "RD", "DR", "PKWY", "WAY", "ST N.E.", "AVE SOUTH", "PLAZA",
+}
+
+// abbreviations shorten names into telegraphic names, in order
+var abbreviations = [][2]string{
+	{"FEDERAL CREDIT UNION", "FCU"},
+	{"CREDIT UNION", "CU"},
+	{"NATIONAL BANK", "NB"},
+	{"STATE BANK", "SB"},
+	{"SAVINGS BANK", "SVGS BK"},
+	{"SAVINGS & LOAN", "S&L"},
+	{"BANK & TRUST", "B&T"},
+	{"BANK AND TRUST CO", "B&T"},
+	{"TRUST COMPANY", "TR CO"},
+	{"COMMUNITY", "CMNTY"},
+	{"EMPLOYEES", "EMP"},
+	{"FIRST NATIONAL", "FIRST NATL"},
+	{", N.A.", ""},
+}
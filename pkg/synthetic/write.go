@@ -0,0 +1,184 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package synthetic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/moov-io/fed"
+)
+
+// Format is how directories are written
+type Format string
+
+const (
+	// FormatText is the FRB's fixed-width plaintext format, e.g. FedACHdir.txt
+	FormatText Format = "text"
+	// FormatJSON is the FRB's JSON format, e.g. fedachdir.json
+	FormatJSON Format = "json"
+)
+
+// Filenames returns the names the FRB uses for FedACH and Fedwire directories in format
+func (f Format) Filenames() (ach, wire string) {
+	if f == FormatJSON {
+		return "fedachdir.json", "fpddir.json"
+	}
+	return "FedACHdir.txt", "fpddir.txt"
+}
+
+// WriteACH writes the FedACH directory in format
+func (d *Directory) WriteACH(w io.Writer, format Format) error {
+	switch format {
+	case FormatText:
+		bw := bufio.NewWriter(w)
+		for _, p := range d.ACHParticipants {
+			bw.WriteString(achLine(p))
+			bw.WriteByte('\n')
+		}
+		return bw.Flush()
+
+	case FormatJSON:
+		participants := make([]jsonACHParticipant, len(d.ACHParticipants))
+		for i, p := range d.ACHParticipants {
+			participants[i] = newJSONACHParticipant(p)
+		}
+		var out struct {
+			FedACHParticipants struct {
+				Response           jsonResponse         `json:"response"`
+				FedACHParticipants []jsonACHParticipant `json:"fedACHParticipants"`
+			} `json:"fedACHParticipants"`
+		}
+		out.FedACHParticipants.Response.Code = 100
+		out.FedACHParticipants.FedACHParticipants = participants
+		return writeJSON(w, out)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// WriteWire writes the Fedwire directory in format
+func (d *Directory) WriteWire(w io.Writer, format Format) error {
+	switch format {
+	case FormatText:
+		bw := bufio.NewWriter(w)
+		for _, p := range d.WIREParticipants {
+			bw.WriteString(wireLine(p))
+			bw.WriteByte('\n')
+		}
+		return bw.Flush()
+
+	case FormatJSON:
+		participants := make([]jsonWIREParticipant, len(d.WIREParticipants))
+		for i, p := range d.WIREParticipants {
+			participants[i] = jsonWIREParticipant{
+				RoutingNumber:             p.RoutingNumber,
+				TelegraphicName:           p.TelegraphicName,
+				CustomerName:              p.CustomerName,
+				CustomerState:             p.State,
+				CustomerCity:              p.City,
+				FundsEligibility:          string(p.FundsTransferStatus),
+				FundsSettlementOnlyStatus: string(p.FundsSettlementOnlyStatus),
+				SecuritiesEligibility:     string(p.BookEntrySecuritiesTransferStatus),
+				ChangeDate:                p.Date,
+			}
+		}
+		var out struct {
+			FedwireParticipants struct {
+				Response            jsonResponse          `json:"response"`
+				FedwireParticipants []jsonWIREParticipant `json:"fedwireParticipants"`
+			} `json:"fedwireParticipants"`
+		}
+		out.FedwireParticipants.Response.Code = 100
+		out.FedwireParticipants.FedwireParticipants = participants
+		return writeJSON(w, out)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// achLine formats a FedACH record, fields longer than their width aren't truncated
+func achLine(p *fed.ACHParticipant) string {
+	phone := p.PhoneNumber + strings.Repeat(" ", max(0, 10-len(p.PhoneNumber)))
+	return fmt.Sprintf("%-9s%-1s%-9s%-1s%-6s%-9s%-36s%-36s%-20s%-2s%-5s%-4s%s%-1s%-1s%5s",
+		p.RoutingNumber, p.OfficeCode, p.ServicingFRBNumber, p.RecordTypeCode, p.Revised, p.NewRoutingNumber,
+		p.CustomerName, p.Address, p.City, p.State, p.PostalCode, p.PostalCodeExtension, phone,
+		p.StatusCode, p.ViewCode, "")
+}
+
+// wireLine formats a Fedwire record, fields longer than their width aren't truncated
+func wireLine(p *fed.WIREParticipant) string {
+	return fmt.Sprintf("%-9s%-18s%-36s%-2s%-25s%-1s%-1s%-1s%-8s",
+		p.RoutingNumber, p.TelegraphicName, p.CustomerName, p.State, p.City, p.FundsTransferStatus,
+		p.FundsSettlementOnlyStatus, p.BookEntrySecuritiesTransferStatus, p.Date)
+}
+
+type jsonResponse struct {
+	Code int `json:"code"`
+}
+
+type jsonACHParticipant struct {
+	RoutingNumber         string `json:"routingNumber"`
+	OfficeCode            string `json:"officeCode"`
+	ServicingFRBNumber    string `json:"servicingFRBNumber"`
+	RecordTypeCode        string `json:"recordTypeCode"`
+	ChangeDate            string `json:"changeDate"`
+	NewRoutingNumber      string `json:"newRoutingNumber"`
+	CustomerName          string `json:"customerName"`
+	CustomerAddress       string `json:"customerAddress"`
+	CustomerCity          string `json:"customerCity"`
+	CustomerState         string `json:"customerState"`
+	CustomerZip           string `json:"customerZip"`
+	CustomerZipExt        string `json:"customerZipExt"`
+	CustomerAreaCode      string `json:"customerAreaCode"`
+	CustomerPhonePrefix   string `json:"customerPhonePrefix"`
+	CustomerPhoneSuffix   string `json:"customerPhoneSuffix"`
+	InstitutionStatusCode string `json:"institutionStatusCode"`
+	DataViewCode          string `json:"dataViewCode"`
+}
+
+func newJSONACHParticipant(p *fed.ACHParticipant) jsonACHParticipant {
+	out := jsonACHParticipant{
+		RoutingNumber:         p.RoutingNumber,
+		OfficeCode:            string(p.OfficeCode),
+		ServicingFRBNumber:    p.ServicingFRBNumber,
+		RecordTypeCode:        string(p.RecordTypeCode),
+		ChangeDate:            p.Revised,
+		NewRoutingNumber:      p.NewRoutingNumber,
+		CustomerName:          p.CustomerName,
+		CustomerAddress:       p.Address,
+		CustomerCity:          p.City,
+		CustomerState:         p.State,
+		CustomerZip:           p.PostalCode,
+		CustomerZipExt:        p.PostalCodeExtension,
+		InstitutionStatusCode: string(p.StatusCode),
+		DataViewCode:          string(p.ViewCode),
+	}
+	if len(p.PhoneNumber) == 10 {
+		out.CustomerAreaCode = p.PhoneNumber[:3]
+		out.CustomerPhonePrefix = p.PhoneNumber[3:6]
+		out.CustomerPhoneSuffix = p.PhoneNumber[6:]
+	}
+	return out
+}
+
+type jsonWIREParticipant struct {
+	RoutingNumber             string `json:"routingNumber"`
+	TelegraphicName           string `json:"telegraphicName"`
+	CustomerName              string `json:"customerName"`
+	CustomerState             string `json:"customerState"`
+	CustomerCity              string `json:"customerCity"`
+	FundsEligibility          string `json:"fundsEligibility"`
+	FundsSettlementOnlyStatus string `json:"fundsSettlementOnlyStatus"`
+	SecuritiesEligibility     string `json:"securitiesEligibility"`
+	ChangeDate                string `json:"changeDate"`
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"container/heap"
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/moov-io/base/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SearchWorkers is the number of goroutines used to score participants in fuzzy searches. Values
+// below 2 score every participant on the calling goroutine. Set it before searching.
+var SearchWorkers = runtime.GOMAXPROCS(0)
+
+// minParticipantsPerWorker is the smallest shard worth handing to another goroutine
+const minParticipantsPerWorker = 1024
+
+// SearchStats describes the work done by a fuzzy search
+type SearchStats struct {
+	// Candidates is the number of participants which matched the search before its limit was applied.
+	// A search cut short by its context only counts the participants scored before it stopped.
+	Candidates int
+}
+
+// scorer returns the score of a participant and if it matched the search at all
+type scorer[T any] func(p T) (float64, bool)
+
+// scoreParticipants scores participants and returns the limit highest scoring matches in descending order,
+// along with how many participants matched. Matches with equal scores keep their order from participants,
+// as a stable sort would.
+//
+// Participants are sharded across SearchWorkers goroutines which each keep their own top-K heap that are
+// then merged. If ctx is done the best matches found so far are returned along with ctx.Err().
+func scoreParticipants[T any](ctx context.Context, participants []T, limit int, score scorer[T]) ([]T, SearchStats, error) {
+	if limit <= 0 {
+		return []T{}, SearchStats{}, ctx.Err()
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "fed.scoreParticipants", trace.WithAttributes(
+		attribute.Int("fed.participants", len(participants)),
+		attribute.Int("fed.limit", limit),
+	))
+	defer span.End()
+
+	workers := SearchWorkers
+	if n := (len(participants) + minParticipantsPerWorker - 1) / minParticipantsPerWorker; n < workers {
+		workers = n
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	var matches atomic.Int64
+	var stopped atomic.Bool
+	shards := make([]*topK[T], workers)
+	scoreShard := func(shard int) {
+		start := shard * len(participants) / workers
+		end := (shard + 1) * len(participants) / workers
+
+		top := newTopK[T](limit)
+		var matched int64
+		for i := start; i < end; i++ {
+			if (i-start)%contextCheckInterval == 0 && ctx.Err() != nil {
+				stopped.Store(true)
+				break
+			}
+			if s, ok := score(participants[i]); ok {
+				matched++
+				top.offer(scoredParticipant[T]{participant: participants[i], score: s, index: i})
+			}
+		}
+		shards[shard] = top
+		matches.Add(matched)
+	}
+
+	if workers == 1 {
+		scoreShard(0)
+	} else {
+		var wg sync.WaitGroup
+		for shard := 0; shard < workers; shard++ {
+			wg.Add(1)
+			go func(shard int) {
+				defer wg.Done()
+				scoreShard(shard)
+			}(shard)
+		}
+		wg.Wait()
+	}
+
+	merged := shards[0]
+	for _, top := range shards[1:] {
+		for _, p := range top.items {
+			merged.offer(p)
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("fed.workers", workers),
+		attribute.Int64("fed.candidates", matches.Load()),
+	)
+
+	var err error
+	if stopped.Load() {
+		err = ctx.Err()
+		span.RecordError(err)
+	}
+	return merged.sorted(), SearchStats{Candidates: int(matches.Load())}, err
+}
+
+type scoredParticipant[T any] struct {
+	participant T
+	score       float64
+	index       int // position in the searched participants, used to break ties
+}
+
+// worse returns true if a should be ordered after b in search results
+func (a scoredParticipant[T]) worse(b scoredParticipant[T]) bool {
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	return a.index > b.index
+}
+
+// topK holds the k best scored participants as a min-heap, so the worst of them is replaced first.
+type topK[T any] struct {
+	k     int
+	items []scoredParticipant[T]
+}
+
+func newTopK[T any](k int) *topK[T] {
+	return &topK[T]{k: k}
+}
+
+func (h *topK[T]) offer(p scoredParticipant[T]) {
+	if len(h.items) < h.k {
+		heap.Push(h, p)
+		return
+	}
+	if h.items[0].worse(p) {
+		h.items[0] = p
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted returns the participants ordered from best to worst
+func (h *topK[T]) sorted() []T {
+	sort.Slice(h.items, func(i, j int) bool { return h.items[j].worse(h.items[i]) })
+
+	out := make([]T, len(h.items))
+	for i := range h.items {
+		out[i] = h.items[i].participant
+	}
+	return out
+}
+
+func (h *topK[T]) Len() int           { return len(h.items) }
+func (h *topK[T]) Less(i, j int) bool { return h.items[i].worse(h.items[j]) }
+func (h *topK[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *topK[T]) Push(x any) {
+	h.items = append(h.items, x.(scoredParticipant[T]))
+}
+
+func (h *topK[T]) Pop() any {
+	n := len(h.items)
+	p := h.items[n-1]
+	h.items = h.items[:n-1]
+	return p
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/fed/pkg/strcmp"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setSearchWorkers(tb testing.TB, n int) {
+	tb.Helper()
+
+	prev := SearchWorkers
+	SearchWorkers = n
+	tb.Cleanup(func() { SearchWorkers = prev })
+}
+
+// sequentialACHNameSearch scores every participant and stable sorts them, as searches did before sharding
+func sequentialACHNameSearch(dict *ACHDictionary, s string, limit int) []*ACHParticipant {
+	type result struct {
+		*ACHParticipant
+		highestMatch float64
+	}
+	s = strings.ToLower(s)
+
+	var out []result
+	for _, achP := range dict.ACHParticipants {
+		jaroScore := strcmp.JaroWinkler(strings.ToLower(achP.CleanName), s)
+		levenScore := strcmp.Levenshtein(strings.ToLower(achP.CleanName), s)
+		if jaroScore > ACHJaroWinklerSimilarity || levenScore > ACHLevenshteinSimilarity {
+			out = append(out, result{achP, math.Max(jaroScore, levenScore)})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].highestMatch > out[j].highestMatch })
+
+	found := make([]*ACHParticipant, 0)
+	for i := 0; i < limit && i < len(out); i++ {
+		found = append(found, out[i].ACHParticipant)
+	}
+	return found
+}
+
+func TestScoreParticipants__MatchesSequential(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	for _, workers := range []int{1, 2, 3, 8} {
+		setSearchWorkers(t, workers)
+
+		for _, name := range []string{"FARMERS", "FIRST NATIONAL", "CHASE", "BANK"} {
+			for _, limit := range []int{1, 10, 100, len(dict.ACHParticipants)} {
+				expected := sequentialACHNameSearch(dict, name, limit)
+				found := dict.FinancialInstitutionSearch(name, limit)
+				require.Equal(t, expected, found, "workers=%d name=%s limit=%d", workers, name, limit)
+			}
+		}
+	}
+}
+
+func TestScoreParticipants__RoutingNumberMatchesSequential(t *testing.T) {
+	_, dict := loadTestWireFiles(t)
+
+	setSearchWorkers(t, 1)
+	expected, err := dict.RoutingNumberSearch("0912", 50)
+	require.NoError(t, err)
+
+	setSearchWorkers(t, 6)
+	found, err := dict.RoutingNumberSearch("0912", 50)
+	require.NoError(t, err)
+	require.Equal(t, expected, found)
+}
+
+func TestScoreParticipants__Ties(t *testing.T) {
+	setSearchWorkers(t, 4)
+
+	// Every other participant ties, so results must keep their input order
+	participants := make([]int, 5000)
+	for i := range participants {
+		participants[i] = i
+	}
+	found, _, err := scoreParticipants(context.Background(), participants, 5, func(p int) (float64, bool) {
+		return float64(p % 2), true
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 3, 5, 7, 9}, found)
+
+	found, _, err = scoreParticipants(context.Background(), participants, 0, func(p int) (float64, bool) {
+		return 1, true
+	})
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
+
+func TestScoreParticipants__Cancelled(t *testing.T) {
+	setSearchWorkers(t, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	participants := make([]int, 5000)
+	found, _, err := scoreParticipants(ctx, participants, 5, func(p int) (float64, bool) {
+		return 1, true
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Empty(t, found)
+}
+
+func TestScoreParticipants__Candidates(t *testing.T) {
+	setSearchWorkers(t, 4)
+
+	participants := make([]int, 5000)
+	for i := range participants {
+		participants[i] = i
+	}
+	found, stats, err := scoreParticipants(context.Background(), participants, 5, func(p int) (float64, bool) {
+		return float64(p), p%10 == 0
+	})
+	require.NoError(t, err)
+	require.Len(t, found, 5)
+	require.Equal(t, 500, stats.Candidates)
+
+	// Every match is counted, not only those returned
+	_, dict := loadTestACHFiles(t)
+	achP, stats, err := dict.FinancialInstitutionSearchContext(context.Background(), "FARMERS", 1)
+	require.NoError(t, err)
+	require.Len(t, achP, 1)
+	require.Greater(t, stats.Candidates, 1)
+
+	achP, stats, err = dict.RoutingNumberSearchContext(context.Background(), "044112187", 10)
+	require.NoError(t, err)
+	require.Len(t, achP, 1)
+	require.Equal(t, 1, stats.Candidates)
+}
+
+func BenchmarkACHFinancialInstitutionSearch(b *testing.B) {
+	_, dict := loadTestACHFiles(b)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			setSearchWorkers(b, workers)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				dict.FinancialInstitutionSearch("FIRST NATIONAL", 100)
+			}
+		})
+	}
+}
+
+func BenchmarkWIREFinancialInstitutionSearch(b *testing.B) {
+	_, dict := loadTestWireFiles(b)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			setSearchWorkers(b, workers)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				dict.FinancialInstitutionSearch("FIRST NATIONAL", 100)
+			}
+		})
+	}
+}
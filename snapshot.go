@@ -0,0 +1,233 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var (
+	// ErrSnapshotFormat is returned when data isn't a snapshot of the expected dictionary
+	ErrSnapshotFormat = errors.New("not a dictionary snapshot")
+	// ErrSnapshotVersion is returned when a snapshot was written in an unsupported format version
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrSnapshotChecksum is returned when a snapshot's contents don't match its checksum
+	ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+)
+
+const (
+	snapshotMagic = "FEDSNAP\x00"
+
+	// snapshotVersion is incremented whenever the snapshot payload changes
+	snapshotVersion uint32 = 1
+
+	// snapshot headers are the magic, version and payload length
+	snapshotHeaderLength = len(snapshotMagic) + 4 + 8
+)
+
+// SourceChecksum returns the checksum of directory data as reported by a dictionary's SourceChecksum
+// method once the data is read. Comparing them shows if a snapshot was created from the same data.
+func SourceChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshot is the payload of a snapshot file
+type snapshot[T any] struct {
+	Kind           string
+	SourceChecksum string
+	CreatedAt      time.Time
+
+	Participants []T
+
+	// Indexes hold positions in Participants
+	RoutingNumberIndex map[string]int
+	CustomerNameIndex  map[string][]int
+}
+
+// writeSnapshot writes payload in the snapshot format: a header holding the magic, format version
+// and payload length, the gob encoded payload and the payload's SHA-256.
+func writeSnapshot(w io.Writer, payload any) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(payload); err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+
+	header := make([]byte, 0, snapshotHeaderLength)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint32(header, snapshotVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(buf.Len()))
+
+	for _, part := range [][]byte{header, buf.Bytes(), sum[:]} {
+		if _, err := w.Write(part); err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// readSnapshot verifies a snapshot written by writeSnapshot and decodes its payload
+func readSnapshot(r io.Reader, payload any) error {
+	header := make([]byte, snapshotHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%w: reading header: %v", ErrSnapshotFormat, err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrSnapshotFormat
+	}
+	header = header[len(snapshotMagic):]
+	if version := binary.BigEndian.Uint32(header); version != snapshotVersion {
+		return fmt.Errorf("%w: found %d and expected %d", ErrSnapshotVersion, version, snapshotVersion)
+	}
+	length := binary.BigEndian.Uint64(header[4:])
+	if length > math.MaxInt64-sha256.Size {
+		return fmt.Errorf("%w: invalid payload length %d", ErrSnapshotFormat, length)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, int64(length)+sha256.Size))
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+	if len(body) < sha256.Size || uint64(len(body)-sha256.Size) != length {
+		return fmt.Errorf("%w: snapshot is truncated", ErrSnapshotChecksum)
+	}
+	data, sum := body[:length], body[length:]
+	if expected := sha256.Sum256(data); !bytes.Equal(expected[:], sum) {
+		return ErrSnapshotChecksum
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(payload); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+	return nil
+}
+
+// saveSnapshot writes a snapshot of kind holding participants and their indexes
+func saveSnapshot[T any](w io.Writer, kind, source string, participants []*T, byRoutingNumber map[string]*T, byName map[string][]*T) error {
+	snap, err := newSnapshot(kind, source, participants, byRoutingNumber, byName)
+	if err != nil {
+		return err
+	}
+	return writeSnapshot(w, snap)
+}
+
+// internKeys interns the values of a participant decoded from a snapshot, as Read does while parsing,
+// and returns the routing number and customer name it's indexed by.
+type internKeys[T any] func(p *T) (routingNumber, customerName string)
+
+// loadSnapshot reads a snapshot of kind written by saveSnapshot. The participants, indexes and source
+// checksum are only replaced once the whole snapshot is verified and restored.
+func loadSnapshot[T any](r io.Reader, kind string, intern internKeys[T], participants *[]*T, byRoutingNumber *map[string]*T, byName *map[string][]*T, source *string) error {
+	var snap snapshot[T]
+	if err := readSnapshot(r, &snap); err != nil {
+		return err
+	}
+	ps, rn, names, err := snap.restore(kind, intern)
+	if err != nil {
+		return err
+	}
+	*participants, *byRoutingNumber, *byName, *source = ps, rn, names, snap.SourceChecksum
+	return nil
+}
+
+// newSnapshot copies participants and replaces the pointers in their indexes with positions
+func newSnapshot[T any](kind, source string, participants []*T, byRoutingNumber map[string]*T, byName map[string][]*T) (*snapshot[T], error) {
+	out := &snapshot[T]{
+		Kind:               kind,
+		SourceChecksum:     source,
+		CreatedAt:          time.Now().UTC(),
+		Participants:       make([]T, len(participants)),
+		RoutingNumberIndex: make(map[string]int, len(byRoutingNumber)),
+		CustomerNameIndex:  make(map[string][]int, len(byName)),
+	}
+	positions := make(map[*T]int, len(participants))
+	for i, p := range participants {
+		out.Participants[i] = *p
+		positions[p] = i
+	}
+
+	for key, p := range byRoutingNumber {
+		idx, ok := positions[p]
+		if !ok {
+			return nil, fmt.Errorf("routing number index holds unknown participant %s", key)
+		}
+		out.RoutingNumberIndex[key] = idx
+	}
+	for key, ps := range byName {
+		indexes := make([]int, len(ps))
+		for i := range ps {
+			idx, ok := positions[ps[i]]
+			if !ok {
+				return nil, fmt.Errorf("customer name index holds unknown participant %s", key)
+			}
+			indexes[i] = idx
+		}
+		out.CustomerNameIndex[key] = indexes
+	}
+	return out, nil
+}
+
+// restore returns the snapshot's participants and indexes, all pointing into one slice of participants.
+// Decoded values are interned, and the index keys share memory with the participants they point to.
+func (s *snapshot[T]) restore(kind string, intern internKeys[T]) ([]*T, map[string]*T, map[string][]*T, error) {
+	if s.Kind != kind {
+		return nil, nil, nil, fmt.Errorf("%w: found %s snapshot and expected %s", ErrSnapshotFormat, s.Kind, kind)
+	}
+
+	participants := make([]*T, len(s.Participants))
+	routingNumbers, names := make([]string, len(s.Participants)), make([]string, len(s.Participants))
+	for i := range s.Participants {
+		participants[i] = &s.Participants[i]
+		routingNumbers[i], names[i] = intern(participants[i])
+	}
+	lookup := func(idx int) (*T, error) {
+		if idx < 0 || idx >= len(participants) {
+			return nil, fmt.Errorf("%w: index position %d out of range", ErrSnapshotFormat, idx)
+		}
+		return participants[idx], nil
+	}
+
+	byRoutingNumber := make(map[string]*T, len(s.RoutingNumberIndex))
+	for key, idx := range s.RoutingNumberIndex {
+		p, err := lookup(idx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if routingNumbers[idx] != key {
+			return nil, nil, nil, fmt.Errorf("%w: routing number index %s holds participant %s", ErrSnapshotFormat, key, routingNumbers[idx])
+		}
+		byRoutingNumber[routingNumbers[idx]] = p
+	}
+
+	// Every entry shares one backing array, as with createIndex*CustomerName
+	backing := make([]*T, 0, len(participants))
+	byName := make(map[string][]*T, len(s.CustomerNameIndex))
+	for key, indexes := range s.CustomerNameIndex {
+		start := len(backing)
+		for _, idx := range indexes {
+			p, err := lookup(idx)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if names[idx] != key {
+				return nil, nil, nil, fmt.Errorf("%w: customer name index %q holds participant named %q", ErrSnapshotFormat, key, names[idx])
+			}
+			key = names[idx]
+			backing = append(backing, p)
+		}
+		byName[key] = backing[start:len(backing):len(backing)]
+	}
+	return participants, byRoutingNumber, byName, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestACHDictionary_Snapshot(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	bs, err := os.ReadFile(filepath.Join("data", "FedACHdir.txt"))
+	require.NoError(t, err)
+	require.Equal(t, SourceChecksum(bs), dict.SourceChecksum())
+
+	var buf bytes.Buffer
+	require.NoError(t, dict.WriteSnapshot(&buf))
+
+	loaded := NewACHDictionary()
+	require.NoError(t, loaded.LoadSnapshot(bytes.NewReader(buf.Bytes())))
+	require.Equal(t, dict.SourceChecksum(), loaded.SourceChecksum())
+
+	expected, err := json.Marshal(dict.ACHParticipants)
+	require.NoError(t, err)
+	found, err := json.Marshal(loaded.ACHParticipants)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(found))
+
+	require.Len(t, loaded.IndexACHRoutingNumber, len(dict.IndexACHRoutingNumber))
+	require.Len(t, loaded.IndexACHCustomerName, len(dict.IndexACHCustomerName))
+	for name, ps := range dict.IndexACHCustomerName {
+		require.Len(t, loaded.IndexACHCustomerName[name], len(ps), name)
+	}
+
+	p := loaded.RoutingNumberSearchSingle("044112187")
+	require.NotNil(t, p)
+	require.Contains(t, loaded.ACHParticipants, p)
+	require.Equal(t, dict.FinancialInstitutionSearch("FARMERS", 10), loaded.FinancialInstitutionSearch("FARMERS", 10))
+}
+
+func TestWIREDictionary_Snapshot(t *testing.T) {
+	_, plainDict := loadTestWireFiles(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, plainDict.WriteSnapshot(&buf))
+
+	loaded := NewWIREDictionary()
+	require.NoError(t, loaded.LoadSnapshot(&buf))
+	require.Equal(t, plainDict.SourceChecksum(), loaded.SourceChecksum())
+	require.Equal(t, plainDict.FinancialInstitutionSearch("MIDWEST", 10), loaded.FinancialInstitutionSearch("MIDWEST", 10))
+
+	p := loaded.RoutingNumberSearchSingle("091905114")
+	require.NotNil(t, p)
+	require.Contains(t, loaded.FinancialInstitutionSearchSingle(p.CustomerName), p)
+
+	// ACH dictionaries can't load Wire snapshots
+	buf.Reset()
+	require.NoError(t, plainDict.WriteSnapshot(&buf))
+	require.ErrorIs(t, NewACHDictionary().LoadSnapshot(&buf), ErrSnapshotFormat)
+}
+
+func TestSnapshot__Invalid(t *testing.T) {
+	_, dict := loadTestWireFiles(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, dict.WriteSnapshot(&buf))
+	good := buf.Bytes()
+
+	load := func(data []byte) error {
+		loaded := NewWIREDictionary()
+		err := loaded.LoadSnapshot(bytes.NewReader(data))
+		if err != nil {
+			require.Empty(t, loaded.WIREParticipants)
+		}
+		return err
+	}
+	require.NoError(t, load(good))
+
+	// Corrupted payload
+	corrupt := bytes.Clone(good)
+	corrupt[len(corrupt)/2] ^= 0xff
+	require.ErrorIs(t, load(corrupt), ErrSnapshotChecksum)
+
+	// Truncated file
+	require.ErrorIs(t, load(good[:len(good)-10]), ErrSnapshotChecksum)
+	require.ErrorIs(t, load(good[:5]), ErrSnapshotFormat)
+
+	// Another version
+	versioned := bytes.Clone(good)
+	binary.BigEndian.PutUint32(versioned[len(snapshotMagic):], snapshotVersion+1)
+	require.ErrorIs(t, load(versioned), ErrSnapshotVersion)
+
+	// Forged payload lengths which overflow when the checksum is added
+	for _, length := range []uint64{math.MaxUint64, math.MaxUint64 - sha256.Size + 1, math.MaxInt64} {
+		forged := bytes.Clone(good)
+		binary.BigEndian.PutUint64(forged[len(snapshotMagic)+4:], length)
+		require.Error(t, load(forged), "length %d", length)
+	}
+
+	// Not a snapshot
+	bs, err := os.ReadFile(filepath.Join("data", "fpddir.txt"))
+	require.NoError(t, err)
+	require.ErrorIs(t, load(bs), ErrSnapshotFormat)
+}
+
+func BenchmarkACHDictionary_LoadSnapshot(b *testing.B) {
+	bs, err := os.ReadFile(filepath.Join("data", "FedACHdir.txt"))
+	require.NoError(b, err)
+
+	dict := NewACHDictionary()
+	require.NoError(b, dict.Read(bytes.NewReader(bs)))
+
+	var snap bytes.Buffer
+	require.NoError(b, dict.WriteSnapshot(&snap))
+
+	b.Run("Read", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, NewACHDictionary().Read(bytes.NewReader(bs)))
+		}
+	})
+	b.Run("LoadSnapshot", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, NewACHDictionary().LoadSnapshot(bytes.NewReader(snap.Bytes())))
+		}
+	})
+}
+
+func TestSnapshot__Intern(t *testing.T) {
+	_, dict := loadTestACHFiles(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, dict.WriteSnapshot(&buf))
+	loaded := NewACHDictionary()
+	require.NoError(t, loaded.LoadSnapshot(&buf))
+
+	// Participants in the same city share one copy of its name, as they do after Read
+	seen := make(map[string]*byte)
+	for _, p := range loaded.ACHParticipants {
+		if ptr, ok := seen[p.City]; ok {
+			require.Equal(t, ptr, unsafe.StringData(p.City), p.City)
+		} else {
+			seen[p.City] = unsafe.StringData(p.City)
+		}
+	}
+
+	// Index keys share memory with the participants
+	for key, p := range loaded.IndexACHRoutingNumber {
+		require.Equal(t, unsafe.StringData(p.RoutingNumber), unsafe.StringData(key))
+	}
+	for key, ps := range loaded.IndexACHCustomerName {
+		require.Equal(t, unsafe.StringData(ps[0].CustomerName), unsafe.StringData(key))
+	}
+
+	// Indexes which don't match their participants are rejected
+	snap, err := newSnapshot("ach", "", dict.ACHParticipants, dict.IndexACHRoutingNumber, dict.IndexACHCustomerName)
+	require.NoError(t, err)
+	snap.RoutingNumberIndex["044112187"] = snap.RoutingNumberIndex["011000015"]
+	_, _, _, err = snap.restore("ach", (*ACHParticipant).internFields)
+	require.ErrorIs(t, err, ErrSnapshotFormat)
+}
+
+func TestSnapshot__Memory(t *testing.T) {
+	bs, err := os.ReadFile(filepath.Join("data", "FedACHdir.txt"))
+	require.NoError(t, err)
+
+	var snap bytes.Buffer
+	dict := NewACHDictionary()
+	require.NoError(t, dict.Read(bytes.NewReader(bs)))
+	require.NoError(t, dict.WriteSnapshot(&snap))
+
+	// retained returns the heap held by the dictionary load creates
+	retained := func(load func(dict *ACHDictionary) error) int64 {
+		start := heapInUse()
+		dict := NewACHDictionary()
+		require.NoError(t, load(dict))
+		used := heapInUse() - start
+		runtime.KeepAlive(dict)
+		return used
+	}
+	readHeap := retained(func(dict *ACHDictionary) error {
+		return dict.Read(bytes.NewReader(bs))
+	})
+	loadedHeap := retained(func(dict *ACHDictionary) error {
+		return dict.LoadSnapshot(bytes.NewReader(snap.Bytes()))
+	})
+
+	// A snapshot's participants are held in one allocation, so they shouldn't need more than parsed ones
+	t.Logf("read: %d bytes, snapshot: %d bytes", readHeap, loadedHeap)
+	require.LessOrEqual(t, loadedHeap, readHeap)
+}
@@ -7,6 +7,7 @@ package fed
 import (
 	"errors"
 	"regexp"
+	"unicode/utf8"
 )
 
 var (
@@ -25,3 +26,50 @@ func (v *validator) isNumeric(s string) error {
 	}
 	return nil
 }
+
+// validateRoutingNumberSearch checks the prefix used to search by routing number. Each call returns a new error.
+func (v *validator) validateRoutingNumberSearch(s string) error {
+	if utf8.RuneCountInString(s) < MinimumRoutingNumberDigits {
+		// The first 2 digits (characters) are required
+		return NewRecordWrongLengthErr(MinimumRoutingNumberDigits, len(s))
+	}
+	if utf8.RuneCountInString(s) > MaximumRoutingNumberDigits {
+		// Routing Number cannot be greater than 9 digits (characters)
+		return NewRecordWrongLengthErr(MaximumRoutingNumberDigits, len(s))
+	}
+	if err := v.isNumeric(s); err != nil {
+		return ErrRoutingNumberNumeric
+	}
+	return nil
+}
+
+// ValidateRoutingNumber checks that s is a 9 digit ABA routing number with a valid check digit.
+//
+// The check digit is the last digit of the routing number and is computed with the weights 3, 7, 1
+// repeated across the first eight digits. See https://en.wikipedia.org/wiki/ABA_routing_transit_number
+func ValidateRoutingNumber(s string) error {
+	if len(s) != MaximumRoutingNumberDigits {
+		return NewRecordWrongLengthErr(MaximumRoutingNumberDigits, len(s))
+	}
+	if numericRegex.MatchString(s) {
+		return ErrRoutingNumberNumeric
+	}
+	if RoutingNumberCheckDigit(s[:8]) != int(s[8]-'0') {
+		return ErrRoutingNumberCheckDigit
+	}
+	return nil
+}
+
+// RoutingNumberCheckDigit computes the ABA check digit for the first eight digits of a routing number.
+// It returns -1 if s is not eight ASCII digits.
+func RoutingNumberCheckDigit(s string) int {
+	if len(s) != 8 || numericRegex.MatchString(s) {
+		return -1
+	}
+	weights := [8]int{3, 7, 1, 3, 7, 1, 3, 7}
+	sum := 0
+	for i := range weights {
+		sum += int(s[i]-'0') * weights[i]
+	}
+	return (10 - (sum % 10)) % 10
+}
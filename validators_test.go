@@ -0,0 +1,38 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRoutingNumber(t *testing.T) {
+	require.NoError(t, ValidateRoutingNumber("011000015"))
+	require.NoError(t, ValidateRoutingNumber("273976369"))
+	require.NoError(t, ValidateRoutingNumber("322271627"))
+
+	require.ErrorIs(t, ValidateRoutingNumber("011000016"), ErrRoutingNumberCheckDigit)
+	require.ErrorIs(t, ValidateRoutingNumber("01100001a"), ErrRoutingNumberNumeric)
+
+	var lengthErr RecordWrongLengthErr
+	require.ErrorAs(t, ValidateRoutingNumber("0110000"), &lengthErr)
+	require.Equal(t, 9, lengthErr.LengthRequired)
+}
+
+func TestRoutingNumberCheckDigit(t *testing.T) {
+	require.Equal(t, 5, RoutingNumberCheckDigit("01100001"))
+	require.Equal(t, 9, RoutingNumberCheckDigit("27397636"))
+	require.Equal(t, -1, RoutingNumberCheckDigit("2739763"))
+	require.Equal(t, -1, RoutingNumberCheckDigit("2739763a"))
+}
+
+func TestValidateRoutingNumber__Directory(t *testing.T) {
+	_, plainDict := loadTestACHFiles(t)
+	for _, p := range plainDict.ACHParticipants {
+		require.NoError(t, ValidateRoutingNumber(p.RoutingNumber), p.RoutingNumber)
+	}
+}